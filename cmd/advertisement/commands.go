@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	reshardImagesCommand = "reshard-images"
+	gcImagesCommand      = "gc-images"
+)
+
+var errUnknownCommand = errors.New("unknown command")
+
+// runCommand runs a maintenance command instead of the server, e.g. `advertisement gc-images -dry-run`.
+func runCommand(ctx context.Context, args []string, services *service.Service, log logger.Logger) error {
+	switch args[0] {
+	case reshardImagesCommand:
+		moved, err := services.ReshardImages(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("images resharded", zap.Int("moved", moved))
+		return nil
+	case gcImagesCommand:
+		flags := flag.NewFlagSet(gcImagesCommand, flag.ContinueOnError)
+		dryRun := flags.Bool("dry-run", false, "only report orphaned image files")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		result, err := services.CollectOrphanedImages(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+		log.Info("orphaned images collected",
+			zap.Bool("dry_run", *dryRun),
+			zap.Int("checked", result.Checked),
+			zap.Int("orphaned", result.Orphaned),
+			zap.Int("removed", result.Removed),
+		)
+		return nil
+	default:
+		return errUnknownCommand
+	}
+}
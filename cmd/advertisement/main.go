@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"github.com/romandnk/advertisement/configs"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/mailer"
 	"github.com/romandnk/advertisement/internal/server/http"
 	"github.com/romandnk/advertisement/internal/service"
 	"github.com/romandnk/advertisement/internal/storage/postgres"
+	"github.com/romandnk/advertisement/internal/worker"
 	"go.uber.org/zap"
 	"net"
+	nethttp "net/http"
+	"os"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -43,13 +50,70 @@ func main() {
 
 	storage := postgres.NewPostgresStorage(db)
 
-	services := service.NewService(storage, log, config.SecretKey, config.PathToImages)
+	mail, err := mailer.NewMailer(config.Mailer, log)
+	if err != nil {
+		log.Error("error initialising mailer", zap.String("error", err.Error()))
+		return
+	}
+
+	log.Log.Info("using mailer", zap.String("type", config.Mailer.Type))
+
+	blobStore, err := blob.NewBlobStore(ctx, config.BlobStore)
+	if err != nil {
+		log.Error("error initialising blob store", zap.String("error", err.Error()))
+		return
+	}
+
+	log.Log.Info("using blob store", zap.String("type", config.BlobStore.Type))
+
+	services := service.NewService(storage, blobStore, mail, log, config)
+
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, os.Args[1:], services, log); err != nil {
+			log.Error("error running command", zap.String("command", os.Args[1]), zap.String("error", err.Error()))
+		}
+		return
+	}
 
 	handler := http.NewHandler(services, log, config.SecretKey)
 
 	server := http.NewServer(config.Server.Host, config.Server.Port,
 		config.Server.ReadTimeout, config.Server.WriteTimeout, handler.InitRoutes())
 
+	workers := []*worker.Periodic{
+		worker.NewPeriodic("advert expiry", log, config.AdvertExpiry.Interval, services.ArchiveExpiredAdverts),
+	}
+	for i := 0; i < config.ImageJobs.Workers; i++ {
+		workers = append(workers, worker.NewPeriodic("image job", log, config.ImageJobs.Interval, services.ProcessImageJobs))
+	}
+	if config.ImageGC.Interval > 0 {
+		workers = append(workers, worker.NewPeriodic("image gc", log, config.ImageGC.Interval,
+			func(ctx context.Context) (int, error) {
+				result, err := services.CollectOrphanedImages(ctx, false)
+				return result.Removed, err
+			}))
+	}
+	if config.UploadPurge.Interval > 0 {
+		workers = append(workers, worker.NewPeriodic("upload purge", log, config.UploadPurge.Interval,
+			func(ctx context.Context) (int, error) {
+				images, imagesErr := services.PurgeUnattachedImages(ctx)
+				uploads, uploadsErr := services.PurgeStaleUploads(ctx)
+				return images + uploads, errors.Join(imagesErr, uploadsErr)
+			}))
+	}
+	if config.TokenPurge.Interval > 0 {
+		workers = append(workers, worker.NewPeriodic("token purge", log, config.TokenPurge.Interval, services.PurgeExpiredTokens))
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker.Periodic) {
+			defer wg.Done()
+			w.Run(ctx)
+		}(w)
+	}
+
 	go func() {
 		<-ctx.Done()
 
@@ -66,8 +130,11 @@ func main() {
 
 	log.Info("app is starting...")
 
-	if err := server.Start(); err != nil {
+	if err := server.Start(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
 		log.Error("error starting server", zap.String("error", err.Error()))
-		return
 	}
+
+	// the workers may still be processing a batch, wait for them before closing the db
+	cancel()
+	wg.Wait()
 }
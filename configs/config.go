@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/subosito/gotenv"
 	"go.uber.org/zap/zapcore"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -39,14 +40,62 @@ var (
 	ErrSecretKeyEmpty                = errors.New("secret key: empty")
 	ErrSecretKeyTooSmall             = errors.New("secret key: min length is 6")
 	ErrSecretKeyTooBig               = errors.New("secret key: max length is 12")
+	ErrMailerInvalidType             = errors.New("mailer: invalid type (smtp, file, log)")
+	ErrMailerEmptyHost               = errors.New("mailer: empty smtp host")
+	ErrMailerInvalidPort             = errors.New("mailer: invalid smtp port (from 0 to 65535)")
+	ErrMailerEmptyFrom               = errors.New("mailer: empty from address")
+	ErrMailerEmptyPath               = errors.New("mailer: empty file path")
+	ErrPublicURLInvalid              = errors.New("public url: must be an absolute http(s) url")
+	ErrAdvertExpiryParseTTL          = errors.New("advert expiry: ttl must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrAdvertExpiryParseInterval     = errors.New("advert expiry: interval must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrAdvertExpiryTTL               = errors.New("advert expiry: ttl must be positive")
+	ErrAdvertExpiryInterval          = errors.New("advert expiry: interval must be positive")
+	ErrAdvertExpiryBatchSize         = errors.New("advert expiry: batch size must be positive")
+	ErrBlobStoreInvalidType          = errors.New("blob store: invalid type (fs, s3)")
+	ErrBlobStoreEmptyEndpoint        = errors.New("blob store: empty s3 endpoint")
+	ErrBlobStoreEmptyBucket          = errors.New("blob store: empty s3 bucket")
+	ErrBlobStoreEmptyCredentials     = errors.New("blob store: empty s3 access key or secret key")
+	ErrImageFormatsEmpty             = errors.New("image formats: empty list")
+	ErrImageFormatInvalid            = errors.New("image formats: invalid format (jpeg, png, gif, webp)")
+	ErrImageMaxPixels                = errors.New("image max pixels: must be positive")
+	ErrImageGCParseInterval          = errors.New("image gc: interval must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrImageGCParseMinAge            = errors.New("image gc: min age must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrImageGCInterval               = errors.New("image gc: interval must not be negative")
+	ErrImageGCMinAge                 = errors.New("image gc: min age must be positive")
+	ErrImageDuplicatesMaxDistance    = errors.New("image duplicates: max distance must be from 0 to 15")
+	ErrImageJobsParseInterval        = errors.New("image jobs: interval must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrImageJobsParseLease           = errors.New("image jobs: lease must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrImageJobsWorkers              = errors.New("image jobs: workers must be positive")
+	ErrImageJobsInterval             = errors.New("image jobs: interval must be positive")
+	ErrImageJobsLease                = errors.New("image jobs: lease must be positive")
+	ErrImageJobsMaxAttempts          = errors.New("image jobs: max attempts must be positive")
+	ErrUploadMaxSize                 = errors.New("upload max size: must be positive")
+	ErrUploadPurgeParseTTL           = errors.New("upload purge: ttl must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrUploadPurgeParseInterval      = errors.New("upload purge: interval must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrUploadPurgeTTL                = errors.New("upload purge: ttl must be positive")
+	ErrUploadPurgeInterval           = errors.New("upload purge: interval must not be negative")
+	ErrTokenPurgeParseInterval       = errors.New("token purge: interval must be represented as 1h2m3s (hours, minutes, seconds)")
+	ErrTokenPurgeInterval            = errors.New("token purge: interval must not be negative")
+	ErrTokenPurgeBatchSize           = errors.New("token purge: batch size must be positive")
 )
 
 type Config struct {
-	Postgres     PostgresConf
-	Server       ServerConf
-	ZapLogger    ZapLoggerConf
-	PathToImages string
-	SecretKey    string
+	Postgres        PostgresConf
+	Server          ServerConf
+	ZapLogger       ZapLoggerConf
+	Mailer          MailerConf
+	AdvertExpiry    AdvertExpiryConf
+	BlobStore       BlobStoreConf
+	ImageFormats    []string
+	ImageMaxPixels  int
+	ImageGC         ImageGCConf
+	ImageDuplicates ImageDuplicatesConf
+	ImageJobs       ImageJobsConf
+	UploadMaxSize   int64
+	UploadPurge     UploadPurgeConf
+	TokenPurge      TokenPurgeConf
+	SecretKey       string
+	PublicURL       string
 }
 
 type PostgresConf struct {
@@ -69,6 +118,77 @@ type ServerConf struct {
 	WriteTimeout time.Duration
 }
 
+// MailerConf chooses how emails are delivered: smtp sends them,
+// file appends them to Path and log writes them to the application log.
+type MailerConf struct {
+	Type     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Path     string
+}
+
+// BlobStoreConf chooses where images are kept: fs stores them under Path on the local disk,
+// s3 in the Bucket of an S3-compatible storage.
+type BlobStoreConf struct {
+	Type      string
+	Path      string
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// AdvertExpiryConf sets how long an advert stays published and how often
+// the background worker archives expired adverts, BatchSize adverts at a time.
+type AdvertExpiryConf struct {
+	TTL       time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ImageGCConf sets how often the background job removes orphaned image files, 0 disables the job.
+// Files younger than MinAge are never removed, their images may still be in an open transaction.
+type ImageGCConf struct {
+	Interval time.Duration
+	MinAge   time.Duration
+}
+
+// ImageDuplicatesConf sets how many bits of perceptual hashes may differ for images to be
+// considered the same picture, at most 15, and whether uploads of pictures of banned users are rejected.
+type ImageDuplicatesConf struct {
+	MaxDistance int
+	BlockBanned bool
+}
+
+// ImageJobsConf sets how many workers process uploaded images and how often they look for new jobs.
+// A job not finished within Lease, e.g. because the instance died, is taken by another worker,
+// after MaxAttempts failed attempts the image is marked as failed.
+type ImageJobsConf struct {
+	Workers     int
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+}
+
+// UploadPurgeConf sets how long images uploaded before their advert and unfinished chunked uploads
+// are kept without being attached and how often they are purged, 0 disables the purge.
+type UploadPurgeConf struct {
+	TTL      time.Duration
+	Interval time.Duration
+}
+
+// TokenPurgeConf sets how often expired entries of the revoked access tokens list are deleted,
+// BatchSize entries at a time, 0 disables the purge.
+type TokenPurgeConf struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
 type ZapLoggerConf struct {
 	Level           zapcore.Level
 	Encoding        string
@@ -115,8 +235,76 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
-	pathToImages := viper.GetString("path_to_images")
-	if err := validatePathToImages(pathToImages); err != nil {
+	mailer := newMailerConf()
+	if err := validateMailerConf(mailer); err != nil {
+		return nil, err
+	}
+
+	advertExpiry, err := newAdvertExpiryConf()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAdvertExpiryConf(advertExpiry); err != nil {
+		return nil, err
+	}
+
+	imageFormats := viper.GetStringSlice("image_formats")
+	if err := validateImageFormats(imageFormats); err != nil {
+		return nil, err
+	}
+
+	imageMaxPixels := viper.GetInt("image_max_pixels")
+	if err := validateImageMaxPixels(imageMaxPixels); err != nil {
+		return nil, err
+	}
+
+	imageGC, err := newImageGCConf()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImageGCConf(imageGC); err != nil {
+		return nil, err
+	}
+
+	imageDuplicates := ImageDuplicatesConf{
+		MaxDistance: viper.GetInt("image_duplicates.max_distance"),
+		BlockBanned: viper.GetBool("image_duplicates.block_banned"),
+	}
+	if err := validateImageDuplicatesConf(imageDuplicates); err != nil {
+		return nil, err
+	}
+
+	imageJobs, err := newImageJobsConf()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImageJobsConf(imageJobs); err != nil {
+		return nil, err
+	}
+
+	uploadMaxSize := viper.GetInt64("upload_max_size")
+	if err := validateUploadMaxSize(uploadMaxSize); err != nil {
+		return nil, err
+	}
+
+	uploadPurge, err := newUploadPurgeConf()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUploadPurgeConf(uploadPurge); err != nil {
+		return nil, err
+	}
+
+	tokenPurge, err := newTokenPurgeConf()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTokenPurgeConf(tokenPurge); err != nil {
+		return nil, err
+	}
+
+	blobStore := newBlobStoreConf()
+	if err := validateBlobStoreConf(blobStore); err != nil {
 		return nil, err
 	}
 
@@ -125,12 +313,28 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	publicURL := strings.TrimRight(viper.GetString("public_url"), "/")
+	if err := validatePublicURL(publicURL); err != nil {
+		return nil, err
+	}
+
 	config := Config{
-		Postgres:     postgres,
-		Server:       server,
-		ZapLogger:    zapLogger,
-		PathToImages: pathToImages,
-		SecretKey:    secret,
+		Postgres:        postgres,
+		Server:          server,
+		ZapLogger:       zapLogger,
+		Mailer:          mailer,
+		AdvertExpiry:    advertExpiry,
+		BlobStore:       blobStore,
+		ImageFormats:    imageFormats,
+		ImageMaxPixels:  imageMaxPixels,
+		ImageGC:         imageGC,
+		ImageDuplicates: imageDuplicates,
+		ImageJobs:       imageJobs,
+		UploadMaxSize:   uploadMaxSize,
+		UploadPurge:     uploadPurge,
+		TokenPurge:      tokenPurge,
+		SecretKey:       secret,
+		PublicURL:       publicURL,
 	}
 
 	return &config, nil
@@ -311,6 +515,274 @@ func validateZapLoggerConf(cfg ZapLoggerConf) error {
 	return nil
 }
 
+func newMailerConf() MailerConf {
+	return MailerConf{
+		Type:     viper.GetString("mailer.type"),
+		Host:     viper.GetString("mailer.host"),
+		Port:     viper.GetInt("mailer.port"),
+		Username: viper.GetString("MAILER_USERNAME"),
+		Password: viper.GetString("MAILER_PASSWORD"),
+		From:     viper.GetString("mailer.from"),
+		Path:     viper.GetString("mailer.path"),
+	}
+}
+
+func validateMailerConf(cfg MailerConf) error {
+	switch cfg.Type {
+	case "smtp":
+		if cfg.Host == "" {
+			return ErrMailerEmptyHost
+		}
+		if cfg.Port < 0 || cfg.Port > 65535 {
+			return ErrMailerInvalidPort
+		}
+		if cfg.From == "" {
+			return ErrMailerEmptyFrom
+		}
+	case "file":
+		if cfg.Path == "" {
+			return ErrMailerEmptyPath
+		}
+	case "log":
+	default:
+		return ErrMailerInvalidType
+	}
+
+	return nil
+}
+
+func newAdvertExpiryConf() (AdvertExpiryConf, error) {
+	ttl, err := time.ParseDuration(viper.GetString("advert_expiry.ttl"))
+	if err != nil {
+		return AdvertExpiryConf{}, ErrAdvertExpiryParseTTL
+	}
+
+	interval, err := time.ParseDuration(viper.GetString("advert_expiry.interval"))
+	if err != nil {
+		return AdvertExpiryConf{}, ErrAdvertExpiryParseInterval
+	}
+
+	return AdvertExpiryConf{
+		TTL:       ttl,
+		Interval:  interval,
+		BatchSize: viper.GetInt("advert_expiry.batch_size"),
+	}, nil
+}
+
+func validateAdvertExpiryConf(cfg AdvertExpiryConf) error {
+	if cfg.TTL <= 0 {
+		return ErrAdvertExpiryTTL
+	}
+	if cfg.Interval <= 0 {
+		return ErrAdvertExpiryInterval
+	}
+	if cfg.BatchSize <= 0 {
+		return ErrAdvertExpiryBatchSize
+	}
+
+	return nil
+}
+
+func newImageGCConf() (ImageGCConf, error) {
+	interval, err := time.ParseDuration(viper.GetString("image_gc.interval"))
+	if err != nil {
+		return ImageGCConf{}, ErrImageGCParseInterval
+	}
+
+	minAge, err := time.ParseDuration(viper.GetString("image_gc.min_age"))
+	if err != nil {
+		return ImageGCConf{}, ErrImageGCParseMinAge
+	}
+
+	return ImageGCConf{
+		Interval: interval,
+		MinAge:   minAge,
+	}, nil
+}
+
+func validateImageGCConf(cfg ImageGCConf) error {
+	if cfg.Interval < 0 {
+		return ErrImageGCInterval
+	}
+	if cfg.MinAge <= 0 {
+		return ErrImageGCMinAge
+	}
+
+	return nil
+}
+
+func validateImageDuplicatesConf(cfg ImageDuplicatesConf) error {
+	// similar hashes are looked up by 16-bit bands differing in at most MaxDistance/4 bits,
+	// beyond 3 bits there are too many band values to look up
+	if cfg.MaxDistance < 0 || cfg.MaxDistance > 15 {
+		return ErrImageDuplicatesMaxDistance
+	}
+	return nil
+}
+
+func newImageJobsConf() (ImageJobsConf, error) {
+	interval, err := time.ParseDuration(viper.GetString("image_jobs.interval"))
+	if err != nil {
+		return ImageJobsConf{}, ErrImageJobsParseInterval
+	}
+
+	lease, err := time.ParseDuration(viper.GetString("image_jobs.lease"))
+	if err != nil {
+		return ImageJobsConf{}, ErrImageJobsParseLease
+	}
+
+	return ImageJobsConf{
+		Workers:     viper.GetInt("image_jobs.workers"),
+		Interval:    interval,
+		Lease:       lease,
+		MaxAttempts: viper.GetInt("image_jobs.max_attempts"),
+	}, nil
+}
+
+func validateImageJobsConf(cfg ImageJobsConf) error {
+	if cfg.Workers <= 0 {
+		return ErrImageJobsWorkers
+	}
+	if cfg.Interval <= 0 {
+		return ErrImageJobsInterval
+	}
+	if cfg.Lease <= 0 {
+		return ErrImageJobsLease
+	}
+	if cfg.MaxAttempts <= 0 {
+		return ErrImageJobsMaxAttempts
+	}
+
+	return nil
+}
+
+func validatePublicURL(publicURL string) error {
+	parsedURL, err := url.Parse(publicURL)
+	if err != nil || parsedURL.Host == "" || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return ErrPublicURLInvalid
+	}
+	return nil
+}
+
+func newBlobStoreConf() BlobStoreConf {
+	return BlobStoreConf{
+		Type:      viper.GetString("blob_store.type"),
+		Path:      viper.GetString("path_to_images"),
+		Endpoint:  viper.GetString("blob_store.endpoint"),
+		Region:    viper.GetString("blob_store.region"),
+		Bucket:    viper.GetString("blob_store.bucket"),
+		AccessKey: viper.GetString("BLOB_STORE_ACCESS_KEY"),
+		SecretKey: viper.GetString("BLOB_STORE_SECRET_KEY"),
+		UseSSL:    viper.GetBool("blob_store.use_ssl"),
+	}
+}
+
+func validateBlobStoreConf(cfg BlobStoreConf) error {
+	switch cfg.Type {
+	case "fs":
+		return validatePathToImages(cfg.Path)
+	case "s3":
+		if cfg.Endpoint == "" {
+			return ErrBlobStoreEmptyEndpoint
+		}
+		if cfg.Bucket == "" {
+			return ErrBlobStoreEmptyBucket
+		}
+		if cfg.AccessKey == "" || cfg.SecretKey == "" {
+			return ErrBlobStoreEmptyCredentials
+		}
+	default:
+		return ErrBlobStoreInvalidType
+	}
+
+	return nil
+}
+
+func validateImageFormats(formats []string) error {
+	if len(formats) == 0 {
+		return ErrImageFormatsEmpty
+	}
+	known := map[string]struct{}{
+		"jpeg": {},
+		"png":  {},
+		"gif":  {},
+		"webp": {},
+	}
+	for _, format := range formats {
+		if _, ok := known[format]; !ok {
+			return ErrImageFormatInvalid
+		}
+	}
+
+	return nil
+}
+
+func validateImageMaxPixels(maxPixels int) error {
+	if maxPixels <= 0 {
+		return ErrImageMaxPixels
+	}
+	return nil
+}
+
+func validateUploadMaxSize(maxSize int64) error {
+	if maxSize <= 0 {
+		return ErrUploadMaxSize
+	}
+	return nil
+}
+
+func newUploadPurgeConf() (UploadPurgeConf, error) {
+	ttl, err := time.ParseDuration(viper.GetString("upload_purge.ttl"))
+	if err != nil {
+		return UploadPurgeConf{}, ErrUploadPurgeParseTTL
+	}
+
+	interval, err := time.ParseDuration(viper.GetString("upload_purge.interval"))
+	if err != nil {
+		return UploadPurgeConf{}, ErrUploadPurgeParseInterval
+	}
+
+	return UploadPurgeConf{
+		TTL:      ttl,
+		Interval: interval,
+	}, nil
+}
+
+func validateUploadPurgeConf(cfg UploadPurgeConf) error {
+	if cfg.TTL <= 0 {
+		return ErrUploadPurgeTTL
+	}
+	if cfg.Interval < 0 {
+		return ErrUploadPurgeInterval
+	}
+
+	return nil
+}
+
+func newTokenPurgeConf() (TokenPurgeConf, error) {
+	interval, err := time.ParseDuration(viper.GetString("token_purge.interval"))
+	if err != nil {
+		return TokenPurgeConf{}, ErrTokenPurgeParseInterval
+	}
+
+	return TokenPurgeConf{
+		Interval:  interval,
+		BatchSize: viper.GetInt("token_purge.batch_size"),
+	}, nil
+}
+
+func validateTokenPurgeConf(cfg TokenPurgeConf) error {
+	if cfg.Interval < 0 {
+		return ErrTokenPurgeInterval
+	}
+	// the batch size is used only when the purge is enabled
+	if cfg.Interval > 0 && cfg.BatchSize <= 0 {
+		return ErrTokenPurgeBatchSize
+	}
+
+	return nil
+}
+
 func validatePathToImages(path string) error {
 	info, err := os.Stat(path)
 
@@ -0,0 +1,52 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"github.com/romandnk/advertisement/configs"
+	"io"
+	"time"
+)
+
+var (
+	ErrUnknownBlobStoreType = errors.New("unknown blob store type")
+	ErrNotFound             = errors.New("blob not found")
+)
+
+const (
+	TypeFileSystem = "fs"
+	TypeS3         = "s3"
+)
+
+// Info describes a stored blob without its content.
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore keeps binary objects by key. Keys use "/" as a separator whatever the backend is.
+// Get, Stream and Stat of a missing key return ErrNotFound, deleting a missing key is not an error.
+// Stream returns a seekable reader so that ranges can be served without loading the whole object.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Stream(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	Delete(ctx context.Context, key string) error
+	// Walk calls fn for every stored blob in no particular order and stops at the first error fn returns.
+	Walk(ctx context.Context, fn func(info Info) error) error
+}
+
+// NewBlobStore returns the implementation chosen in the config.
+func NewBlobStore(ctx context.Context, cfg configs.BlobStoreConf) (BlobStore, error) {
+	switch cfg.Type {
+	case TypeFileSystem:
+		return NewFileSystemStore(cfg.Path), nil
+	case TypeS3:
+		return NewS3Store(ctx, cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey, cfg.UseSSL)
+	default:
+		return nil, ErrUnknownBlobStoreType
+	}
+}
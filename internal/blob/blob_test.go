@@ -0,0 +1,277 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBucket = "images"
+
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	data := []byte("png image")
+
+	err := store.Put(ctx, "ab/cd/id.png", bytes.NewReader(data), int64(len(data)), "image/png")
+	require.NoError(t, err)
+
+	got, err := store.Get(ctx, "ab/cd/id.png")
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	info, err := store.Stat(ctx, "ab/cd/id.png")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), info.Size)
+	require.Equal(t, "image/png", info.ContentType)
+	require.False(t, info.ModTime.IsZero())
+
+	reader, info, err := store.Stream(ctx, "ab/cd/id.png")
+	require.NoError(t, err)
+	streamed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, data, streamed)
+	require.Equal(t, int64(len(data)), info.Size)
+
+	_, err = reader.Seek(4, io.SeekStart)
+	require.NoError(t, err)
+	streamed, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, data[4:], streamed)
+
+	err = store.Put(ctx, "ef/gh/other.jpg", bytes.NewReader(data), int64(len(data)), "image/jpeg")
+	require.NoError(t, err)
+
+	var keys []string
+	err = store.Walk(ctx, func(info Info) error {
+		keys = append(keys, info.Key)
+		require.Equal(t, int64(len(data)), info.Size)
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"ab/cd/id.png", "ef/gh/other.jpg"}, keys)
+
+	errStop := errors.New("stop")
+	err = store.Walk(ctx, func(info Info) error {
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+
+	err = store.Delete(ctx, "ef/gh/other.jpg")
+	require.NoError(t, err)
+
+	err = store.Delete(ctx, "ab/cd/id.png")
+	require.NoError(t, err)
+
+	_, err = store.Get(ctx, "ab/cd/id.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = store.Stat(ctx, "ab/cd/id.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, _, err = store.Stream(ctx, "ab/cd/id.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Delete(ctx, "ab/cd/id.png")
+	require.NoError(t, err)
+}
+
+func TestFileSystemStore(t *testing.T) {
+	testBlobStore(t, NewFileSystemStore(t.TempDir()))
+}
+
+func TestFileSystemStoreWalkSkipsHiddenFiles(t *testing.T) {
+	root := t.TempDir()
+	store := NewFileSystemStore(root)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitkeep"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".tmp-123"), []byte("partly written"), 0o644))
+
+	err := store.Walk(context.Background(), func(info Info) error {
+		t.Fatalf("unexpected blob %s", info.Key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = NewFileSystemStore(filepath.Join(root, "missing")).Walk(context.Background(), func(info Info) error {
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestFileSystemStoreKeyOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	store := NewFileSystemStore(root)
+
+	require.True(t, strings.HasPrefix(store.path("../../etc/passwd"), root))
+}
+
+func TestS3Store(t *testing.T) {
+	srv := httptest.NewTLSServer(newFakeS3())
+	defer srv.Close()
+
+	store, err := newS3Store(context.Background(), srv.Listener.Addr().String(), testBucket, &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: srv.Client().Transport,
+	})
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+func TestS3StoreBucketNotExist(t *testing.T) {
+	srv := httptest.NewTLSServer(newFakeS3())
+	defer srv.Close()
+
+	_, err := newS3Store(context.Background(), srv.Listener.Addr().String(), "unknown", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: srv.Client().Transport,
+	})
+	require.ErrorIs(t, err, ErrBucketNotExist)
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// fakeS3 is a MinIO stand-in that keeps objects of one bucket in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]fakeObject)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != testBucket {
+		writeFakeS3Error(w, r, "NoSuchBucket")
+		return
+	}
+
+	if key == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			f.writeList(w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			writeFakeS3Error(w, r, "NoSuchKey")
+			return
+		}
+		data, status := object.data, http.StatusOK
+		if start, ok := parseFakeRange(r.Header.Get("Range")); ok && start < len(data) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+			data, status = data[start:], http.StatusPartialContent
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type fakeListResult struct {
+	XMLName     xml.Name          `xml:"ListBucketResult"`
+	Name        string            `xml:"Name"`
+	KeyCount    int               `xml:"KeyCount"`
+	MaxKeys     int               `xml:"MaxKeys"`
+	IsTruncated bool              `xml:"IsTruncated"`
+	Contents    []fakeListContent `xml:"Contents"`
+}
+
+type fakeListContent struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+}
+
+// writeList answers ListObjectsV2 with all objects on one page.
+func (f *fakeS3) writeList(w http.ResponseWriter) {
+	result := fakeListResult{Name: testBucket, MaxKeys: 1000}
+	for key, object := range f.objects {
+		result.Contents = append(result.Contents, fakeListContent{
+			Key:          key,
+			LastModified: object.modTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         `"etag"`,
+			Size:         len(object.data),
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+// parseFakeRange supports the open "bytes=N-" ranges minio uses after a seek.
+func parseFakeRange(header string) (int, bool) {
+	value, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.Atoi(strings.TrimSuffix(value, "-"))
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+func writeFakeS3Error(w http.ResponseWriter, r *http.Request, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>` + code + `</Code></Error>`))
+	}
+}
@@ -0,0 +1,147 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileSystemStore keeps blobs as files under the root directory.
+type FileSystemStore struct {
+	root string
+}
+
+func NewFileSystemStore(root string) *FileSystemStore {
+	return &FileSystemStore{root: root}
+}
+
+// Put writes into a temporary file first, so readers never see a partly written blob.
+func (s *FileSystemStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name := s.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
+
+func (s *FileSystemStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, convertFileSystemError(err)
+	}
+	return data, nil
+}
+
+func (s *FileSystemStore) Stream(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, Info{}, convertFileSystemError(err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, Info{}, err
+	}
+
+	return file, newFileInfo(key, stat), nil
+}
+
+func (s *FileSystemStore) Stat(ctx context.Context, key string) (Info, error) {
+	stat, err := os.Stat(s.path(key))
+	if err != nil {
+		return Info{}, convertFileSystemError(err)
+	}
+	return newFileInfo(key, stat), nil
+}
+
+func (s *FileSystemStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Walk skips hidden files: temporary files of unfinished Put calls and files like .gitkeep.
+func (s *FileSystemStore) Walk(ctx context.Context, fn func(info Info) error) error {
+	return filepath.WalkDir(s.root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// nothing has been stored yet
+			if name == s.root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			// removed while walking
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+
+		return fn(newFileInfo(filepath.ToSlash(rel), stat))
+	})
+}
+
+func (s *FileSystemStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// newFileInfo guesses the content type by the extension, files do not keep it.
+func newFileInfo(key string, stat fs.FileInfo) Info {
+	return Info{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     stat.ModTime(),
+	}
+}
+
+func convertFileSystemError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
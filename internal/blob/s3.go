@@ -0,0 +1,127 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"net/http"
+)
+
+var ErrBucketNotExist = errors.New("bucket does not exist")
+
+// S3Store keeps blobs in a bucket of any S3-compatible storage: AWS S3, MinIO, Ceph and so on.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Store(ctx context.Context, endpoint, region, bucket, accessKey, secretKey string, useSSL bool) (*S3Store, error) {
+	return newS3Store(ctx, endpoint, bucket, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+}
+
+func newS3Store(ctx context.Context, endpoint, bucket string, options *minio.Options) (*S3Store, error) {
+	client, err := minio.New(endpoint, options)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrBucketNotExist
+	}
+
+	return &S3Store{
+		client: client,
+		bucket: bucket,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	object, _, err := s.Stream(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, convertS3Error(err)
+	}
+
+	return data, nil
+}
+
+func (s *S3Store) Stream(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Info{}, convertS3Error(err)
+	}
+
+	// GetObject is lazy, Stat makes the request and reports a missing key
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, Info{}, convertS3Error(err)
+	}
+
+	return object, newObjectInfo(stat), nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	stat, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return Info{}, convertS3Error(err)
+	}
+	return newObjectInfo(stat), nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Store) Walk(ctx context.Context, fn func(info Info) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	// stops the listing goroutine when fn fails
+	defer cancel()
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(newObjectInfo(object)); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+func newObjectInfo(stat minio.ObjectInfo) Info {
+	return Info{
+		Key:         stat.Key,
+		Size:        stat.Size,
+		ContentType: stat.ContentType,
+		ModTime:     stat.LastModified,
+	}
+}
+
+func convertS3Error(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer appends emails to a file instead of sending them, it is meant for local development and tests.
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{
+		path: path,
+		from: from,
+	}
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(formatMessage(m.from, message, time.Now()), "\r\n"...))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
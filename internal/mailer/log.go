@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+	"github.com/romandnk/advertisement/internal/logger"
+	"go.uber.org/zap"
+)
+
+// LogMailer writes emails to the application log instead of sending them.
+type LogMailer struct {
+	logger logger.Logger
+}
+
+func NewLogMailer(logger logger.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(_ context.Context, message Message) error {
+	m.logger.Info("email",
+		zap.String("to", message.To),
+		zap.String("subject", message.Subject),
+		zap.String("body", message.Body),
+	)
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"github.com/romandnk/advertisement/configs"
+	"github.com/romandnk/advertisement/internal/logger"
+)
+
+var ErrUnknownMailerType = errors.New("unknown mailer type")
+
+const (
+	TypeSMTP = "smtp"
+	TypeFile = "file"
+	TypeLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer returns the implementation chosen in the config.
+func NewMailer(cfg configs.MailerConf, logger logger.Logger) (Mailer, error) {
+	switch cfg.Type {
+	case TypeSMTP:
+		return NewSMTPMailer(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From), nil
+	case TypeFile:
+		return NewFileMailer(cfg.Path, cfg.From), nil
+	case TypeLog:
+		return NewLogMailer(logger), nil
+	default:
+		return nil, ErrUnknownMailerType
+	}
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMessage(t *testing.T) {
+	message := Message{
+		To:      "test@vk.com",
+		Subject: "Тест",
+		Body:    "body",
+	}
+
+	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	expected := "From: noreply@vk.com\r\n" +
+		"To: test@vk.com\r\n" +
+		"Subject: =?utf-8?q?=D0=A2=D0=B5=D1=81=D1=82?=\r\n" +
+		"Date: Sun, 02 Jan 2000 00:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: 8bit\r\n" +
+		"\r\n" +
+		"body\r\n"
+
+	require.Equal(t, expected, string(formatMessage("noreply@vk.com", message, date)))
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+
+	mailer := NewFileMailer(path, "noreply@vk.com")
+
+	err := mailer.Send(context.Background(), Message{To: "first@vk.com", Subject: "first", Body: "first body"})
+	require.NoError(t, err)
+	err = mailer.Send(context.Background(), Message{To: "second@vk.com", Subject: "second", Body: "second body"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, strings.Count(string(data), "From: noreply@vk.com"))
+	require.Contains(t, string(data), "To: first@vk.com")
+	require.Contains(t, string(data), "second body")
+}
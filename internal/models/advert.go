@@ -5,14 +5,129 @@ import (
 	"time"
 )
 
+const (
+	AdvertSortByDate  = "date"
+	AdvertSortByPrice = "price"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+
+	AdvertStatusDraft         = "draft"
+	AdvertStatusPendingReview = "pending_review"
+	AdvertStatusPublished     = "published"
+	AdvertStatusRejected      = "rejected"
+	AdvertStatusArchived      = "archived"
+	AdvertStatusSold          = "sold"
+
+	AdvertStatusReasonExpired = "expired"
+)
+
 type Advert struct {
-	ID          string
-	Title       string
-	Description string
-	Price       decimal.Decimal
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID           string
+	Title        string
+	Description  string
+	Price        decimal.Decimal
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       string
+	CategoryID   string
+	Status       string
+	StatusReason string
+	ExpiresAt    time.Time
+	Deleted      bool
+	Images       []*Image
+	UploadIDs    []string // completed chunked uploads to add to Images
+	ImageIDs     []string // images uploaded beforehand by the owner of the advert
+	// FavouritesCount is filled for a single advert, Favourited only when the request has a user
+	FavouritesCount int
+	Favourited      *bool
+}
+
+// AdvertStatusChange moves an advert to the To status if its current status is one of From.
+// An empty UserID means the change is made by a moderator and ownership is not checked.
+// A nil ExpiresAt keeps the current expiry time.
+type AdvertStatusChange struct {
+	ID        string
+	UserID    string
+	From      []string
+	To        string
+	Reason    string
+	ExpiresAt *time.Time
+	UpdatedAt time.Time
+}
+
+// ExpiredAdvert is an advert archived by the expiry worker together with the email of its owner.
+type ExpiredAdvert struct {
+	ID        string
+	Title     string
+	UserID    string
+	UserEmail string
+}
+
+// DuplicateAdvert is another advert having an image similar to ImageID of the checked advert.
+// Distance is the number of differing bits of the perceptual hashes, 0 for the same picture.
+type DuplicateAdvert struct {
+	ID               string
+	Title            string
+	UserID           string
+	Status           string
+	ImageID          string
+	DuplicateImageID string
+	Distance         int
+}
+
+// AdvertUpdate is a partial edit of an advert, nil fields are left unchanged.
+type AdvertUpdate struct {
+	ID             string
+	UserID         string
+	Title          *string
+	Description    *string
+	Price          *decimal.Decimal
+	CategoryID     *string
+	UpdatedAt      time.Time
+	AddImages      []*Image
+	AddUploadIDs   []string
+	RemoveImageIDs []string
+	// ContentChanged sends a published or rejected advert back to moderation
+	ContentChanged bool
+}
+
+// AdvertListParams describes one page of the advert feed.
+// Cursor is the opaque token received from the client, After is its decoded form.
+type AdvertListParams struct {
+	Limit       int
+	SortBy      string
+	Order       string
+	MinPrice    *decimal.Decimal
+	MaxPrice    *decimal.Decimal
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
 	UserID      string
-	Deleted     bool
-	Images      []*Image
+	CategoryID  string
+	Status      string
+	Cursor      string
+	After       *AdvertCursor
+}
+
+// AdvertCursor is the position of the last advert of the previous page.
+type AdvertCursor struct {
+	Price     decimal.Decimal
+	CreatedAt time.Time
+	ID        string
+}
+
+type AdvertSearchParams struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// AdvertSearchResult is a found advert with its relevance and fragments
+// of the title and description where matched words are wrapped in <mark> tags.
+// The fragments are HTML: the text of the advert is escaped, <mark> is the only markup.
+type AdvertSearchResult struct {
+	Advert               Advert
+	Rank                 float32
+	TitleHighlight       string
+	DescriptionHighlight string
 }
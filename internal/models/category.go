@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Category struct {
+	ID        string
+	Name      string
+	ParentID  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Children  []*Category
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type Favourite struct {
+	UserID    string
+	AdvertID  string
+	CreatedAt time.Time
+}
+
+// FavouriteAdvert is an advert in the favourites of a user, FavouritedAt orders the list.
+type FavouriteAdvert struct {
+	Advert       Advert
+	FavouritedAt time.Time
+}
+
+// FavouriteListParams selects favourite adverts of UserID in the Statuses, the most recently added first.
+// After is the position of the last advert of the previous page, its CreatedAt is the time the advert was added.
+type FavouriteListParams struct {
+	UserID   string
+	Statuses []string
+	Limit    int
+	Cursor   string
+	After    *AdvertCursor
+}
@@ -1,11 +1,69 @@
 package models
 
-import "time"
+import (
+	"io"
+	"time"
+)
+
+// Image formats are named as image.Decode reports them.
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatGIF  = "gif"
+	ImageFormatWebP = "webp"
+)
+
+// An advert image is processed in the background after the upload, the advert is shown
+// only when all its images are ready. Avatars are processed right away and are always ready.
+const (
+	ImageStatusProcessing = "processing"
+	ImageStatusReady      = "ready"
+	ImageStatusFailed     = "failed"
+)
+
+const (
+	ImageSizeThumb    = "thumb"
+	ImageSizeMedium   = "medium"
+	ImageSizeOriginal = "original"
+)
 
 type Image struct {
-	ID        string
-	Data      []byte
-	AdvertID  string
-	CreatedAt time.Time
-	Deleted   bool
+	ID           string
+	Data         []byte
+	Format       string
+	ContentHash  string // hex sha256 of the original, empty for images uploaded before it was stored
+	PHash        int64  // perceptual hash of the original, similar pictures differ in few bits
+	AdvertID     string
+	UserID       string // owner of an image uploaded before its advert, empty for other images
+	Status       string
+	StatusReason string // why processing failed, shown to the owner
+	CreatedAt    time.Time
+	Deleted      bool
+}
+
+// ImageJob is a claimed job to process an uploaded image, Attempts counts this one too.
+type ImageJob struct {
+	ImageID  string
+	AdvertID string
+	Format   string
+	Attempts int
+}
+
+// ImageFile is a stored image opened for streaming, the caller closes Content.
+// Content is nil when the client already has the image, see NotModified.
+type ImageFile struct {
+	Format      string
+	Size        int64
+	ModTime     time.Time
+	ETag        string
+	NotModified bool
+	Content     io.ReadSeekCloser
+}
+
+// ImageGCResult counts stored image files checked by the garbage collector
+// and the orphaned ones among them: files of deleted images or of images never saved to the database.
+type ImageGCResult struct {
+	Checked  int
+	Orphaned int
+	Removed  int
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Thread is the conversation about an advert between its seller and one buyer.
+// Unread counts the messages the user the thread was loaded for has not read yet.
+type Thread struct {
+	ID          string
+	AdvertID    string
+	AdvertTitle string
+	SellerID    string
+	BuyerID     string
+	Unread      int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Message struct {
+	ID        string
+	ThreadID  string
+	SenderID  string
+	Body      string
+	CreatedAt time.Time
+}
+
+// UserBlock stops messages between UserID and BlockedID in both directions.
+type UserBlock struct {
+	UserID    string
+	BlockedID string
+	CreatedAt time.Time
+}
+
+// ThreadListParams selects threads of UserID, the most recently updated first.
+// After is the position of the last thread of the previous page, its CreatedAt is the time the thread was updated.
+type ThreadListParams struct {
+	UserID string
+	Limit  int
+	Cursor string
+	After  *AdvertCursor
+}
+
+// MessageListParams selects messages of the thread, the newest first.
+// After is the position of the last message of the previous page.
+type MessageListParams struct {
+	ThreadID string
+	Limit    int
+	Cursor   string
+	After    *AdvertCursor
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session is a signed in device. It keeps the hash of the only refresh token
+// which is currently valid for it.
+type Session struct {
+	ID               string
+	UserID           string
+	RefreshTokenHash string
+	UserAgent        string
+	CreatedAt        time.Time
+	LastUsedAt       time.Time
+	ExpiresAt        time.Time
+	Revoked          bool
+}
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// AccessToken holds the claims of a parsed access token.
+type AccessToken struct {
+	ID        string
+	UserID    string
+	Role      string
+	SessionID string
+	Version   int
+	ExpiresAt time.Time
+}
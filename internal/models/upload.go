@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Upload is a file uploaded in chunks, Offset is the number of bytes received so far.
+// The upload is complete when Offset reaches Length.
+type Upload struct {
+	ID        string
+	UserID    string
+	Length    int64
+	Offset    int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -2,11 +2,50 @@ package models
 
 import "time"
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+
+	UserTokenPurposeVerifyEmail   = "verify_email"
+	UserTokenPurposeResetPassword = "reset_password"
+)
+
 type User struct {
-	ID        string
-	Email     string
-	Password  string
+	ID            string
+	Email         string
+	EmailVerified bool
+	Password      string
+	Role          string
+	TokenVersion  int
+	DisplayName   string
+	Phone         string
+	City          string
+	AvatarID      string
+	Banned        bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Deleted       bool
+}
+
+// UserProfileUpdate is a partial edit of a profile, nil fields are left unchanged.
+// Avatar replaces the current avatar, RemoveAvatar drops it.
+type UserProfileUpdate struct {
+	ID           string
+	DisplayName  *string
+	Phone        *string
+	City         *string
+	Avatar       *Image
+	RemoveAvatar bool
+	UpdatedAt    time.Time
+}
+
+// UserToken is a single-use secret sent to the user by email, only its hash is stored.
+type UserToken struct {
+	Hash      string
+	UserID    string
+	Purpose   string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Deleted   bool
+	ExpiresAt time.Time
+	UsedAt    *time.Time
 }
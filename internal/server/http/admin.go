@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+var (
+	banUserAction     = "ban user"
+	unbanUserAction   = "unban user"
+	setUserRoleAction = "set user role"
+)
+
+func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.BanUser(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error banning user", err)
+		h.logError(resp.Message, banUserAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.UnbanUser(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error unbanning user", err)
+		h.logError(resp.Message, unbanUserAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Role string `json:"role"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, setUserRoleAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	err = h.service.SetUserRole(r.Context(), chi.URLParam(r, "id"), body.Role)
+	if err != nil {
+		resp := newResponse("", "error setting user role", err)
+		h.logError(resp.Message, setUserRoleAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,23 +1,30 @@
 package http
 
 import (
-	"fmt"
+	"encoding/json"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/shopspring/decimal"
-	"github.com/spf13/viper"
-	"image"
-	_ "image/jpeg"
-	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 var (
-	createAdvertAction  = "create advert"
-	deleteAdvertAction  = "delete advert"
-	getAdvertByIDAction = "get advert by id"
+	createAdvertAction    = "create advert"
+	updateAdvertAction    = "update advert"
+	deleteAdvertAction    = "delete advert"
+	deleteAnyAdvertAction = "delete advert by moderator"
+	getAdvertByIDAction   = "get advert by id"
+	listAdvertsAction     = "list adverts"
+	searchAdvertsAction   = "search adverts"
+	changeStatusAction    = "change advert status"
+	moderationQueueAction = "list moderation queue"
+	approveAdvertAction   = "approve advert"
+	rejectAdvertAction    = "reject advert"
+	renewAdvertAction     = "renew advert"
+	findDuplicatesAction  = "find duplicate adverts"
 )
 
 func (h *Handler) CreateAdvert(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +40,7 @@ func (h *Handler) CreateAdvert(w http.ResponseWriter, r *http.Request) {
 
 	title := r.FormValue("title")
 	description := r.FormValue("description")
+	categoryID := r.FormValue("category_id")
 	priceStr := r.FormValue("price")
 	price, err := decimal.NewFromString(priceStr)
 	if err != nil {
@@ -42,51 +50,21 @@ func (h *Handler) CreateAdvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imagesForm := r.MultipartForm.File["images"]
-
-	var images []*models.Image
-	for _, imageForm := range imagesForm {
-		var img models.Image
-
-		file, err := imageForm.Open()
-		if err != nil {
-			file.Close()
-			resp := newResponse("images", "error opening file: "+imageForm.Filename, err)
-			h.logError(resp.Message, createAdvertAction, resp.Error)
-			renderResponse(w, r, http.StatusBadRequest, resp)
-			return
-		}
-
-		_, _, err = image.Decode(file)
-		if err != nil {
-			file.Close()
-			resp := newResponse("images", "image cannot be decoded: "+imageForm.Filename, nil)
-			h.logError(resp.Message, createAdvertAction, resp.Error)
-			renderResponse(w, r, http.StatusBadRequest, resp)
-			return
-		}
-
-		file.Seek(0, 0)
-
-		imageData, err := io.ReadAll(file)
-		if err != nil {
-			file.Close()
-			resp := newResponse("images", "error reading file: "+imageForm.Filename, err)
-			h.logError(resp.Message, createAdvertAction, resp.Error)
-			renderResponse(w, r, http.StatusBadRequest, resp)
-			return
-		}
-
-		img.Data = imageData
-
-		images = append(images, &img)
-
-		file.Close()
+	images, message, err := readImages(r.MultipartForm.File["images"])
+	if message != "" {
+		resp := newResponse("images", message, err)
+		h.logError(resp.Message, createAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
 	}
 
 	advert.Title = title
 	advert.Description = description
 	advert.Price = price
+	advert.CategoryID = categoryID
+	if r.FormValue("draft") == "true" {
+		advert.Status = models.AdvertStatusDraft
+	}
 	userID := r.Context().Value("user_id")
 	switch userID.(type) {
 	case string:
@@ -98,6 +76,8 @@ func (h *Handler) CreateAdvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	advert.Images = images
+	advert.UploadIDs = r.MultipartForm.Value["upload_ids"]
+	advert.ImageIDs = r.MultipartForm.Value["image_ids"]
 
 	id, err := h.service.CreateAdvert(r.Context(), advert)
 	if err != nil {
@@ -111,6 +91,71 @@ func (h *Handler) CreateAdvert(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"id": id})
 }
 
+func (h *Handler) UpdateAdvert(w http.ResponseWriter, r *http.Request) {
+	update := models.AdvertUpdate{
+		ID: chi.URLParam(r, "id"),
+	}
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		resp := newResponse("", "error parsing form", err)
+		h.logError(resp.Message, updateAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	form := r.MultipartForm.Value
+
+	if _, ok := form["title"]; ok {
+		title := r.FormValue("title")
+		update.Title = &title
+	}
+
+	if _, ok := form["description"]; ok {
+		description := r.FormValue("description")
+		update.Description = &description
+	}
+
+	if _, ok := form["price"]; ok {
+		price, err := decimal.NewFromString(r.FormValue("price"))
+		if err != nil {
+			resp := newResponse("price", "must be a  number e.g. 123.45", err)
+			h.logError(resp.Message, updateAdvertAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		update.Price = &price
+	}
+
+	if _, ok := form["category_id"]; ok {
+		categoryID := r.FormValue("category_id")
+		update.CategoryID = &categoryID
+	}
+
+	update.RemoveImageIDs = form["remove_images"]
+
+	images, message, err := readImages(r.MultipartForm.File["images"])
+	if message != "" {
+		resp := newResponse("images", message, err)
+		h.logError(resp.Message, updateAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+	update.AddImages = images
+	update.AddUploadIDs = form["upload_ids"]
+
+	advert, err := h.service.UpdateAdvert(r.Context(), update)
+	if err != nil {
+		resp := newResponse("", "error updating advert", err)
+		h.logError(resp.Message, updateAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newAdvertResponse(advert))
+}
+
 func (h *Handler) DeleteAdvert(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -125,6 +170,20 @@ func (h *Handler) DeleteAdvert(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *Handler) DeleteAnyAdvert(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.DeleteAnyAdvert(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error deleting advert", err)
+		h.logError(resp.Message, deleteAnyAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func (h *Handler) GetAdvertByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -136,34 +195,358 @@ func (h *Handler) GetAdvertByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var imageURLs []string
-	host := viper.GetString("server.host")
-	port := viper.GetString("server.port")
-	for _, img := range advert.Images {
-		url := fmt.Sprintf("http://%s:%s/api/v1/images/%s", host, port, img.ID)
-		imageURLs = append(imageURLs, url)
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, advertDetailsResponse{
+		advertResponse:  newAdvertResponse(advert),
+		FavouritesCount: advert.FavouritesCount,
+		Favourited:      advert.Favourited,
+	})
+}
+
+func (h *Handler) ListAdverts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := models.AdvertListParams{
+		SortBy:     query.Get("sort"),
+		Order:      query.Get("order"),
+		UserID:     query.Get("user_id"),
+		CategoryID: query.Get("category_id"),
+		Cursor:     query.Get("cursor"),
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			resp := newResponse("limit", "must be an integer", err)
+			h.logError(resp.Message, listAdvertsAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		params.Limit = limit
+	}
+
+	for _, field := range []string{"min_price", "max_price"} {
+		priceStr := query.Get(field)
+		if priceStr == "" {
+			continue
+		}
+		price, err := decimal.NewFromString(priceStr)
+		if err != nil {
+			resp := newResponse(field, "must be a  number e.g. 123.45", err)
+			h.logError(resp.Message, listAdvertsAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		if field == "min_price" {
+			params.MinPrice = &price
+		} else {
+			params.MaxPrice = &price
+		}
+	}
+
+	for _, field := range []string{"created_from", "created_to"} {
+		dateStr := query.Get(field)
+		if dateStr == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, dateStr)
+		if err != nil {
+			resp := newResponse(field, "must be a date e.g. 2006-01-02T15:04:05Z", err)
+			h.logError(resp.Message, listAdvertsAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		if field == "created_from" {
+			params.CreatedFrom = &date
+		} else {
+			params.CreatedTo = &date
+		}
+	}
+
+	adverts, nextCursor, err := h.service.ListAdverts(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error listing adverts", err)
+		h.logError(resp.Message, listAdvertsAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	renderAdvertList(w, r, adverts, nextCursor)
+}
+
+func (h *Handler) SearchAdverts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := models.AdvertSearchParams{
+		Query: query.Get("q"),
+	}
+
+	for _, field := range []string{"limit", "offset"} {
+		valueStr := query.Get(field)
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil {
+			resp := newResponse(field, "must be an integer", err)
+			h.logError(resp.Message, searchAdvertsAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		if field == "limit" {
+			params.Limit = value
+		} else {
+			params.Offset = value
+		}
+	}
+
+	results, err := h.service.SearchAdverts(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error searching adverts", err)
+		h.logError(resp.Message, searchAdvertsAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	type searchResultResponse struct {
+		advertResponse
+		Rank                 float32 `json:"rank"`
+		TitleHighlight       string  `json:"title_highlight"`
+		DescriptionHighlight string  `json:"description_highlight"`
+	}
+
+	resultsResponse := make([]searchResultResponse, 0, len(results))
+	for _, result := range results {
+		resultsResponse = append(resultsResponse, searchResultResponse{
+			advertResponse:       newAdvertResponse(result.Advert),
+			Rank:                 result.Rank,
+			TitleHighlight:       result.TitleHighlight,
+			DescriptionHighlight: result.DescriptionHighlight,
+		})
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"results": resultsResponse})
+}
+
+func (h *Handler) ChangeAdvertStatus(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var body struct {
+		Status string `json:"status"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, changeStatusAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	err = h.service.ChangeAdvertStatus(r.Context(), id, body.Status)
+	if err != nil {
+		resp := newResponse("", "error changing advert status", err)
+		h.logError(resp.Message, changeStatusAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) RenewAdvert(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	advert, err := h.service.RenewAdvert(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error renewing advert", err)
+		h.logError(resp.Message, renewAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newAdvertResponse(advert))
+}
+
+func (h *Handler) ListModerationQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := models.AdvertListParams{
+		Cursor: query.Get("cursor"),
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			resp := newResponse("limit", "must be an integer", err)
+			h.logError(resp.Message, moderationQueueAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		params.Limit = limit
+	}
+
+	adverts, nextCursor, err := h.service.ListModerationQueue(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error listing moderation queue", err)
+		h.logError(resp.Message, moderationQueueAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	renderAdvertList(w, r, adverts, nextCursor)
+}
+
+func (h *Handler) ApproveAdvert(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.ApproveAdvert(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error approving advert", err)
+		h.logError(resp.Message, approveAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) RejectAdvert(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, rejectAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	err = h.service.RejectAdvert(r.Context(), id, body.Reason)
+	if err != nil {
+		resp := newResponse("", "error rejecting advert", err)
+		h.logError(resp.Message, rejectAdvertAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) FindDuplicateAdverts(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	duplicates, err := h.service.FindDuplicateAdverts(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error finding duplicate adverts", err)
+		h.logError(resp.Message, findDuplicatesAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	type duplicateResponse struct {
+		AdvertID         string `json:"advert_id"`
+		Title            string `json:"title"`
+		UserID           string `json:"user_id"`
+		Status           string `json:"status"`
+		ImageID          string `json:"image_id"`
+		DuplicateImageID string `json:"duplicate_image_id"`
+		Distance         int    `json:"distance"`
+	}
+
+	duplicatesResponse := make([]duplicateResponse, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		duplicatesResponse = append(duplicatesResponse, duplicateResponse{
+			AdvertID:         duplicate.ID,
+			Title:            duplicate.Title,
+			UserID:           duplicate.UserID,
+			Status:           duplicate.Status,
+			ImageID:          duplicate.ImageID,
+			DuplicateImageID: duplicate.DuplicateImageID,
+			Distance:         duplicate.Distance,
+		})
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"duplicates": duplicatesResponse})
+}
+
+func renderAdvertList(w http.ResponseWriter, r *http.Request, adverts []models.Advert, nextCursor string) {
+	advertsResponse := make([]advertResponse, 0, len(adverts))
+	for _, advert := range adverts {
+		advertsResponse = append(advertsResponse, newAdvertResponse(advert))
 	}
 
 	jsonResponse := struct {
-		ID          string          `json:"id""`
-		Title       string          `json:"title"`
-		Description string          `json:"description"`
-		Price       decimal.Decimal `json:"price"`
-		CreatedAt   time.Time       `json:"created_at"`
-		UpdatedAt   time.Time       `json:"updated_at"`
-		UserID      string          `json:"user_id"`
-		ImageURLs   []string        `json:"image_urls"`
+		Adverts    []advertResponse `json:"adverts"`
+		NextCursor string           `json:"next_cursor,omitempty"`
 	}{
-		ID:          advert.ID,
-		Title:       advert.Title,
-		Description: advert.Description,
-		Price:       advert.Price,
-		CreatedAt:   advert.CreatedAt,
-		UpdatedAt:   advert.UpdatedAt,
-		UserID:      advert.UserID,
-		ImageURLs:   imageURLs,
+		Adverts:    advertsResponse,
+		NextCursor: nextCursor,
 	}
 
-	w.WriteHeader(http.StatusOK)
+	render.Status(r, http.StatusOK)
 	render.JSON(w, r, jsonResponse)
 }
+
+type advertResponse struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	Price        decimal.Decimal `json:"price"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+	UserID       string          `json:"user_id"`
+	CategoryID   string          `json:"category_id,omitempty"`
+	Status       string          `json:"status"`
+	StatusReason string          `json:"status_reason,omitempty"`
+	ImageURLs    []string        `json:"image_urls"`
+	// ImageStatuses lists images which are still processing or have failed,
+	// such an advert is shown only to its owner and moderators
+	ImageStatuses map[string]string `json:"image_statuses,omitempty"`
+}
+
+// advertDetailsResponse is a single advert, Favourited is present only for requests with a token.
+type advertDetailsResponse struct {
+	advertResponse
+	FavouritesCount int   `json:"favourites_count"`
+	Favourited      *bool `json:"favourited,omitempty"`
+}
+
+func newAdvertResponse(advert models.Advert) advertResponse {
+	var imageURLs []string
+	var imageStatuses map[string]string
+	for _, img := range advert.Images {
+		imageURLs = append(imageURLs, newImageURL(img.ID))
+
+		if img.Status == models.ImageStatusProcessing || img.Status == models.ImageStatusFailed {
+			if imageStatuses == nil {
+				imageStatuses = make(map[string]string)
+			}
+			imageStatuses[img.ID] = img.Status
+		}
+	}
+
+	return advertResponse{
+		ID:            advert.ID,
+		Title:         advert.Title,
+		Description:   advert.Description,
+		Price:         advert.Price,
+		CreatedAt:     advert.CreatedAt,
+		UpdatedAt:     advert.UpdatedAt,
+		ExpiresAt:     advert.ExpiresAt,
+		UserID:        advert.UserID,
+		CategoryID:    advert.CategoryID,
+		Status:        advert.Status,
+		StatusReason:  advert.StatusReason,
+		ImageURLs:     imageURLs,
+		ImageStatuses: imageStatuses,
+	}
+}
@@ -400,6 +400,108 @@ func TestHandlerDeleteAdvertError(t *testing.T) {
 	}
 }
 
+func TestHandlerRejectAdvert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	expectedID := uuid.New().String()
+
+	services.EXPECT().RejectAdvert(gomock.Any(), expectedID, "prohibited goods").Return(nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Post("/api/v1/moderation/adverts/{id}/reject", handler.RejectAdvert)
+
+	w := httptest.NewRecorder()
+
+	body := bytes.NewBufferString(`{"reason":"prohibited goods"}`)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/api/v1/moderation/adverts/"+expectedID+"/reject", body)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandlerFindDuplicateAdverts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	expectedID := uuid.New().String()
+	duplicates := []models.DuplicateAdvert{{
+		ID:               uuid.New().String(),
+		Title:            "iphone",
+		UserID:           uuid.New().String(),
+		Status:           models.AdvertStatusPublished,
+		ImageID:          uuid.New().String(),
+		DuplicateImageID: uuid.New().String(),
+		Distance:         2,
+	}}
+
+	services.EXPECT().FindDuplicateAdverts(gomock.Any(), expectedID).Return(duplicates, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/moderation/adverts/{id}/duplicates", handler.FindDuplicateAdverts)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/api/v1/moderation/adverts/"+expectedID+"/duplicates", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Duplicates []map[string]interface{} `json:"duplicates"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Duplicates, 1)
+	require.Equal(t, duplicates[0].ID, body.Duplicates[0]["advert_id"])
+	require.Equal(t, duplicates[0].DuplicateImageID, body.Duplicates[0]["duplicate_image_id"])
+	require.Equal(t, float64(2), body.Duplicates[0]["distance"])
+}
+
+func TestHandlerChangeAdvertStatusError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	expectedID := uuid.New().String()
+	expectedErr := custom_error.CustomError{Field: "status", Message: "status can be changed to pending_review, draft, archived or sold"}
+
+	services.EXPECT().ChangeAdvertStatus(gomock.Any(), expectedID, models.AdvertStatusPublished).Return(expectedErr)
+	logger.EXPECT().Error("error changing advert status",
+		zap.String("action", changeStatusAction),
+		zap.String("error", expectedErr.Error()))
+
+	handler := NewHandler(services, logger, " ")
+
+	r := chi.NewRouter()
+	r.Put(urlAdverts+"/{id}/status", handler.ChangeAdvertStatus)
+
+	w := httptest.NewRecorder()
+
+	body := bytes.NewBufferString(`{"status":"published"}`)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, urlAdverts+"/"+expectedID+"/status", body)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
 func TestHandlerGetAdvertByID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -409,6 +511,7 @@ func TestHandlerGetAdvertByID(t *testing.T) {
 	expectedAdvertID := uuid.New().String()
 	expectedImageID := uuid.New().String()
 	tm := time.Date(2023, time.August, 11, 0, 35, 14, 340105741, time.UTC)
+	favourited := true
 
 	expectedAdvert := models.Advert{
 		ID:          expectedAdvertID,
@@ -417,7 +520,9 @@ func TestHandlerGetAdvertByID(t *testing.T) {
 		Price:       decimal.New(1200, 0),
 		CreatedAt:   tm,
 		UpdatedAt:   tm,
+		ExpiresAt:   tm.Add(30 * 24 * time.Hour),
 		UserID:      uuid.New().String(),
+		Status:      models.AdvertStatusPublished,
 		Deleted:     false,
 		Images: []*models.Image{
 			{
@@ -428,6 +533,8 @@ func TestHandlerGetAdvertByID(t *testing.T) {
 				Deleted:   false,
 			},
 		},
+		FavouritesCount: 4,
+		Favourited:      &favourited,
 	}
 
 	ctx := context.Background()
@@ -459,8 +566,348 @@ func TestHandlerGetAdvertByID(t *testing.T) {
 		"price":       "1200",
 		"created_at":  tm.Format(time.RFC3339Nano),
 		"updated_at":  tm.Format(time.RFC3339Nano),
+		"expires_at":  tm.Add(30 * 24 * time.Hour).Format(time.RFC3339Nano),
 		"user_id":     expectedAdvert.UserID,
+		"status":      models.AdvertStatusPublished,
 		"image_urls":  []interface{}{"http://:/api/v1/images/" + expectedImageID},
+		// json numbers are decoded as float64
+		"favourites_count": float64(4),
+		"favourited":       true,
+	}
+
+	require.Equal(t, expectedResponse, responseBody)
+}
+
+func TestHandlerListAdverts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	tm := time.Date(2023, time.August, 11, 0, 35, 14, 340105741, time.UTC)
+	minPrice := decimal.New(100, 0)
+	expectedAdvert := models.Advert{
+		ID:          uuid.New().String(),
+		Title:       "test",
+		Description: "test",
+		Price:       decimal.New(1200, 0),
+		CreatedAt:   tm,
+		UpdatedAt:   tm,
+		ExpiresAt:   tm.Add(30 * 24 * time.Hour),
+		UserID:      uuid.New().String(),
+		Status:      models.AdvertStatusPublished,
+	}
+
+	expectedParams := models.AdvertListParams{
+		Limit:    10,
+		SortBy:   models.AdvertSortByPrice,
+		Order:    models.SortOrderAsc,
+		MinPrice: &minPrice,
+		Cursor:   "cursor",
+	}
+
+	services.EXPECT().ListAdverts(gomock.Any(), expectedParams).Return([]models.Advert{expectedAdvert}, "next", nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get(urlAdverts, handler.ListAdverts)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		urlAdverts+"?limit=10&sort=price&order=asc&min_price=100&cursor=cursor", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	expectedResponse := map[string]interface{}{
+		"adverts": []interface{}{
+			map[string]interface{}{
+				"id":          expectedAdvert.ID,
+				"title":       "test",
+				"description": "test",
+				"price":       "1200",
+				"created_at":  tm.Format(time.RFC3339Nano),
+				"updated_at":  tm.Format(time.RFC3339Nano),
+				"expires_at":  tm.Add(30 * 24 * time.Hour).Format(time.RFC3339Nano),
+				"user_id":     expectedAdvert.UserID,
+				"status":      models.AdvertStatusPublished,
+				"image_urls":  nil,
+			},
+		},
+		"next_cursor": "next",
+	}
+
+	require.Equal(t, expectedResponse, responseBody)
+}
+
+func TestHandlerListAdvertsError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		message       string
+		expectedError string
+		responseBody  map[string]interface{}
+	}{
+		{
+			name:          "invalid limit",
+			query:         "?limit=ten",
+			message:       "must be an integer",
+			expectedError: `strconv.Atoi: parsing "ten": invalid syntax`,
+			responseBody: map[string]interface{}{
+				"field":   "limit",
+				"message": "must be an integer",
+				"error":   `strconv.Atoi: parsing "ten": invalid syntax`,
+			},
+		},
+		{
+			name:          "invalid max price",
+			query:         "?max_price=abc",
+			message:       "must be a  number e.g. 123.45",
+			expectedError: "can't convert abc to decimal",
+			responseBody: map[string]interface{}{
+				"field":   "max_price",
+				"message": "must be a  number e.g. 123.45",
+				"error":   "can't convert abc to decimal",
+			},
+		},
+		{
+			name:          "invalid created from",
+			query:         "?created_from=yesterday",
+			message:       "must be a date e.g. 2006-01-02T15:04:05Z",
+			expectedError: `parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+			responseBody: map[string]interface{}{
+				"field":   "created_from",
+				"message": "must be a date e.g. 2006-01-02T15:04:05Z",
+				"error":   `parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			logger.EXPECT().Error(tc.message,
+				zap.String("action", listAdvertsAction),
+				zap.String("error", tc.expectedError),
+			)
+
+			handler := NewHandler(services, logger, " ")
+
+			r := chi.NewRouter()
+			r.Get(urlAdverts, handler.ListAdverts)
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlAdverts+tc.query, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var responseBody map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.responseBody, responseBody)
+		})
+	}
+}
+
+func TestHandlerUpdateAdvert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	advertID := uuid.New().String()
+	removeImageID := uuid.New().String()
+	title := "new title"
+	price := decimal.New(1500, 0)
+	tm := time.Date(2023, time.August, 11, 0, 35, 14, 340105741, time.UTC)
+
+	expectedUpdate := models.AdvertUpdate{
+		ID:             advertID,
+		Title:          &title,
+		Price:          &price,
+		RemoveImageIDs: []string{removeImageID},
+	}
+
+	updatedAdvert := models.Advert{
+		ID:          advertID,
+		Title:       title,
+		Description: "test",
+		Price:       price,
+		CreatedAt:   tm,
+		UpdatedAt:   tm,
+		UserID:      "test_user",
+	}
+
+	services.EXPECT().UpdateAdvert(gomock.Any(), expectedUpdate).Return(updatedAdvert, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Patch(urlAdverts+"/{id}", handler.UpdateAdvert)
+
+	bodyBuf := &bytes.Buffer{}
+	bodyWriter := multipart.NewWriter(bodyBuf)
+	err := bodyWriter.WriteField("title", title)
+	require.NoError(t, err)
+	err = bodyWriter.WriteField("price", "1500")
+	require.NoError(t, err)
+	err = bodyWriter.WriteField("remove_images", removeImageID)
+	require.NoError(t, err)
+	err = bodyWriter.Close()
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), "user_id", "test_user")
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, urlAdverts+"/"+advertID, bodyBuf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", bodyWriter.FormDataContentType())
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, title, responseBody["title"])
+	require.Equal(t, "1500", responseBody["price"])
+}
+
+func TestHandlerUpdateAdvertErrorInvalidPrice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	logger.EXPECT().Error("must be a  number e.g. 123.45",
+		zap.String("action", updateAdvertAction),
+		zap.String("error", "can't convert abc to decimal"),
+	)
+
+	handler := NewHandler(services, logger, " ")
+
+	r := chi.NewRouter()
+	r.Patch(urlAdverts+"/{id}", handler.UpdateAdvert)
+
+	bodyBuf := &bytes.Buffer{}
+	bodyWriter := multipart.NewWriter(bodyBuf)
+	err := bodyWriter.WriteField("price", "abc")
+	require.NoError(t, err)
+	err = bodyWriter.Close()
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch, urlAdverts+"/"+uuid.New().String(), bodyBuf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", bodyWriter.FormDataContentType())
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"field":   "price",
+		"message": "must be a  number e.g. 123.45",
+		"error":   "can't convert abc to decimal",
+	}, responseBody)
+}
+
+func TestHandlerSearchAdverts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	tm := time.Date(2023, time.August, 11, 0, 35, 14, 340105741, time.UTC)
+	expectedResult := models.AdvertSearchResult{
+		Advert: models.Advert{
+			ID:          uuid.New().String(),
+			Title:       "Велосипед горный",
+			Description: "test",
+			Price:       decimal.New(1200, 0),
+			CreatedAt:   tm,
+			UpdatedAt:   tm,
+			ExpiresAt:   tm.Add(30 * 24 * time.Hour),
+			UserID:      uuid.New().String(),
+			Status:      models.AdvertStatusPublished,
+		},
+		Rank:                 0.5,
+		TitleHighlight:       "<mark>Велосипед</mark> горный",
+		DescriptionHighlight: "test",
+	}
+
+	expectedParams := models.AdvertSearchParams{
+		Query:  "велосипеды",
+		Limit:  5,
+		Offset: 10,
+	}
+
+	services.EXPECT().SearchAdverts(gomock.Any(), expectedParams).Return([]models.AdvertSearchResult{expectedResult}, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get(urlAdverts+"/search", handler.SearchAdverts)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		urlAdverts+"/search?q=%D0%B2%D0%B5%D0%BB%D0%BE%D1%81%D0%B8%D0%BF%D0%B5%D0%B4%D1%8B&limit=5&offset=10", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	expectedResponse := map[string]interface{}{
+		"results": []interface{}{
+			map[string]interface{}{
+				"id":                    expectedResult.Advert.ID,
+				"title":                 "Велосипед горный",
+				"description":           "test",
+				"price":                 "1200",
+				"created_at":            tm.Format(time.RFC3339Nano),
+				"updated_at":            tm.Format(time.RFC3339Nano),
+				"expires_at":            tm.Add(30 * 24 * time.Hour).Format(time.RFC3339Nano),
+				"user_id":               expectedResult.Advert.UserID,
+				"status":                models.AdvertStatusPublished,
+				"image_urls":            nil,
+				"rank":                  0.5,
+				"title_highlight":       "<mark>Велосипед</mark> горный",
+				"description_highlight": "test",
+			},
+		},
 	}
 
 	require.Equal(t, expectedResponse, responseBody)
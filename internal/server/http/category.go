@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/models"
+	"net/http"
+)
+
+var (
+	createCategoryAction = "create category"
+	getCategoryAction    = "get category by id"
+	listCategoriesAction = "list categories"
+	updateCategoryAction = "update category"
+	deleteCategoryAction = "delete category"
+)
+
+type bodyCategory struct {
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+}
+
+type categoryResponse struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	ParentID string             `json:"parent_id,omitempty"`
+	Children []categoryResponse `json:"children,omitempty"`
+}
+
+func newCategoryResponse(category *models.Category) categoryResponse {
+	resp := categoryResponse{
+		ID:       category.ID,
+		Name:     category.Name,
+		ParentID: category.ParentID,
+	}
+	for _, child := range category.Children {
+		resp.Children = append(resp.Children, newCategoryResponse(child))
+	}
+	return resp
+}
+
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var categoryFromBody bodyCategory
+
+	err := json.NewDecoder(r.Body).Decode(&categoryFromBody)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, createCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	category := models.Category{
+		Name:     categoryFromBody.Name,
+		ParentID: categoryFromBody.ParentID,
+	}
+
+	id, err := h.service.CreateCategory(r.Context(), category)
+	if err != nil {
+		resp := newResponse("", "error creating category", err)
+		h.logError(resp.Message, createCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]string{"id": id})
+}
+
+func (h *Handler) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	category, err := h.service.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error getting category by id", err)
+		h.logError(resp.Message, getCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newCategoryResponse(&category))
+}
+
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.ListCategories(r.Context())
+	if err != nil {
+		resp := newResponse("", "error listing categories", err)
+		h.logError(resp.Message, listCategoriesAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	categoriesResponse := make([]categoryResponse, 0, len(categories))
+	for _, category := range categories {
+		categoriesResponse = append(categoriesResponse, newCategoryResponse(category))
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"categories": categoriesResponse})
+}
+
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var categoryFromBody bodyCategory
+
+	err := json.NewDecoder(r.Body).Decode(&categoryFromBody)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, updateCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	category := models.Category{
+		ID:       chi.URLParam(r, "id"),
+		Name:     categoryFromBody.Name,
+		ParentID: categoryFromBody.ParentID,
+	}
+
+	err = h.service.UpdateCategory(r.Context(), category)
+	if err != nil {
+		resp := newResponse("", "error updating category", err)
+		h.logError(resp.Message, updateCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.DeleteCategory(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error deleting category", err)
+		h.logError(resp.Message, deleteCategoryAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	mock_service "github.com/romandnk/advertisement/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const urlCategories = "/api/v1/categories"
+
+func TestHandlerCreateCategory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	expectedCategory := models.Category{
+		Name:     "Велосипеды",
+		ParentID: uuid.New().String(),
+	}
+	expectedID := uuid.New().String()
+
+	services.EXPECT().CreateCategory(gomock.Any(), expectedCategory).Return(expectedID, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Post(urlCategories, handler.CreateCategory)
+
+	jsonBody, err := json.Marshal(map[string]string{
+		"name":      expectedCategory.Name,
+		"parent_id": expectedCategory.ParentID,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlCategories, bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, expectedID, responseBody["id"])
+}
+
+func TestHandlerListCategories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	child := &models.Category{ID: "child", Name: "Велосипеды", ParentID: "root"}
+	root := &models.Category{ID: "root", Name: "Транспорт", Children: []*models.Category{child}}
+
+	services.EXPECT().ListCategories(gomock.Any()).Return([]*models.Category{root}, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get(urlCategories, handler.ListCategories)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlCategories, nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	expectedResponse := map[string]interface{}{
+		"categories": []interface{}{
+			map[string]interface{}{
+				"id":   "root",
+				"name": "Транспорт",
+				"children": []interface{}{
+					map[string]interface{}{
+						"id":        "child",
+						"name":      "Велосипеды",
+						"parent_id": "root",
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, expectedResponse, responseBody)
+}
+
+func TestHandlerRequireRole(t *testing.T) {
+	testCases := []struct {
+		name string
+		role interface{}
+		code int
+	}{
+		{
+			name: "admin",
+			role: models.RoleAdmin,
+			code: http.StatusOK,
+		},
+		{
+			name: "user",
+			role: models.RoleUser,
+			code: http.StatusForbidden,
+		},
+		{
+			name: "no role",
+			role: nil,
+			code: http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := mock_logger.NewMockLogger(ctrl)
+			if tc.code == http.StatusForbidden {
+				role, _ := tc.role.(string)
+				logger.EXPECT().Error("forbidden",
+					zap.String("action", checkRoleAction),
+					zap.String("error", "role "+role+" is not allowed"),
+				)
+			}
+
+			handler := NewHandler(nil, logger, " ")
+
+			r := chi.NewRouter()
+			r.With(handler.requireRole(models.RoleAdmin)).Delete(urlCategories, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ctx := context.Background()
+			if tc.role != nil {
+				ctx = context.WithValue(ctx, "role", tc.role)
+			}
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, urlCategories, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/models"
+	"net/http"
+	"time"
+)
+
+var (
+	addFavouriteAction    = "add favourite"
+	removeFavouriteAction = "remove favourite"
+	listFavouritesAction  = "list favourites"
+)
+
+type favouriteAdvertResponse struct {
+	advertResponse
+	FavouritedAt time.Time `json:"favourited_at"`
+}
+
+func (h *Handler) AddFavourite(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.AddFavourite(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error adding favourite", err)
+		h.logError(resp.Message, addFavouriteAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) RemoveFavourite(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.RemoveFavourite(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error removing favourite", err)
+		h.logError(resp.Message, removeFavouriteAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) ListFavourites(w http.ResponseWriter, r *http.Request) {
+	params := models.FavouriteListParams{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r, listFavouritesAction)
+	if !ok {
+		return
+	}
+	params.Limit = limit
+
+	favourites, nextCursor, err := h.service.ListFavourites(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error listing favourites", err)
+		h.logError(resp.Message, listFavouritesAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	advertsResponse := make([]favouriteAdvertResponse, 0, len(favourites))
+	for _, favourite := range favourites {
+		advertsResponse = append(advertsResponse, favouriteAdvertResponse{
+			advertResponse: newAdvertResponse(favourite.Advert),
+			FavouritedAt:   favourite.FavouritedAt,
+		})
+	}
+
+	jsonResponse := struct {
+		Adverts    []favouriteAdvertResponse `json:"adverts"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}{
+		Adverts:    advertsResponse,
+		NextCursor: nextCursor,
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, jsonResponse)
+}
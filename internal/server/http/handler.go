@@ -3,6 +3,7 @@ package http
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/models"
 	"github.com/romandnk/advertisement/internal/service"
 	"go.uber.org/zap"
 )
@@ -32,20 +33,102 @@ func (h *Handler) InitRoutes() *chi.Mux {
 			r.Route("/users", func(r chi.Router) {
 				r.Post("/sign-up", h.SignUp)
 				r.Post("/sign-in", h.SignIn)
+				r.Post("/refresh", h.Refresh)
+				r.Get("/verify", h.VerifyEmail)
+				r.Post("/password/reset-request", h.RequestPasswordReset)
+				r.Post("/password/reset", h.ResetPassword)
+				r.Get("/{id}", h.GetPublicProfile)
+
+				r.Group(func(r chi.Router) {
+					r.Use(h.authorizationMiddleware)
+					r.Post("/logout", h.Logout)
+					r.Post("/logout-all", h.LogoutAll)
+					r.Post("/verify/resend", h.ResendVerificationEmail)
+					r.Get("/me", h.GetProfile)
+					r.Patch("/me", h.UpdateProfile)
+					r.Get("/sessions", h.ListSessions)
+					r.Delete("/sessions/{id}", h.RevokeSession)
+					r.Get("/me/favourites", h.ListFavourites)
+					r.Put("/{id}/block", h.BlockUser)
+					r.Delete("/{id}/block", h.UnblockUser)
+				})
 			})
 
 			r.Route("/adverts", func(r chi.Router) {
+				r.Get("/", h.ListAdverts)
+				r.Get("/search", h.SearchAdverts)
+
+				r.Group(func(r chi.Router) {
+					r.Use(h.optionalAuthorizationMiddleware)
+					r.Get("/{id}", h.GetAdvertByID)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(h.authorizationMiddleware)
+					r.Post("/", h.CreateAdvert)
+					r.Patch("/{id}", h.UpdateAdvert)
+					r.Delete("/{id}", h.DeleteAdvert)
+					r.Put("/{id}/status", h.ChangeAdvertStatus)
+					r.Post("/{id}/renew", h.RenewAdvert)
+					r.Put("/{id}/favourite", h.AddFavourite)
+					r.Delete("/{id}/favourite", h.RemoveFavourite)
+					r.Post("/{id}/messages", h.SendAdvertMessage)
+				})
+			})
+
+			r.Route("/categories", func(r chi.Router) {
+				r.Get("/", h.ListCategories)
+				r.Get("/{id}", h.GetCategoryByID)
+
+				r.Group(func(r chi.Router) {
+					r.Use(h.authorizationMiddleware)
+					r.Use(h.requireRole(models.RoleAdmin))
+					r.Post("/", h.CreateCategory)
+					r.Put("/{id}", h.UpdateCategory)
+					r.Delete("/{id}", h.DeleteCategory)
+				})
+			})
+
+			r.Route("/moderation", func(r chi.Router) {
 				r.Use(h.authorizationMiddleware)
-				r.Post("/", h.CreateAdvert)
-				r.Delete("/{id}", h.DeleteAdvert)
+				r.Use(h.requireRole(models.RoleModerator, models.RoleAdmin))
+				r.Get("/adverts", h.ListModerationQueue)
+				r.Post("/adverts/{id}/approve", h.ApproveAdvert)
+				r.Post("/adverts/{id}/reject", h.RejectAdvert)
+				r.Get("/adverts/{id}/duplicates", h.FindDuplicateAdverts)
+				r.Delete("/adverts/{id}", h.DeleteAnyAdvert)
 			})
 
-			r.Group(func(r chi.Router) {
-				r.Get("/adverts/{id}", h.GetAdvertByID)
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(h.authorizationMiddleware)
+				r.Use(h.requireRole(models.RoleAdmin))
+				r.Post("/users/{id}/ban", h.BanUser)
+				r.Delete("/users/{id}/ban", h.UnbanUser)
+				r.Put("/users/{id}/role", h.SetUserRole)
+			})
+
+			r.Route("/threads", func(r chi.Router) {
+				r.Use(h.authorizationMiddleware)
+				r.Get("/", h.ListThreads)
+				r.Get("/unread", h.CountUnreadMessages)
+				r.Get("/{id}/messages", h.ListMessages)
+				r.Post("/{id}/messages", h.SendThreadMessage)
+			})
+
+			r.Route("/uploads", func(r chi.Router) {
+				r.Use(h.authorizationMiddleware)
+				r.Post("/", h.CreateUpload)
+				r.Head("/{id}", h.GetUpload)
+				r.Patch("/{id}", h.WriteUploadChunk)
 			})
 
 			r.Route("/images", func(r chi.Router) {
 				r.Get("/{id}", h.GetImageByID)
+
+				r.Group(func(r chi.Router) {
+					r.Use(h.authorizationMiddleware)
+					r.Post("/", h.UploadImage)
+				})
 			})
 		})
 	})
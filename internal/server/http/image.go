@@ -1,17 +1,89 @@
 package http
 
 import (
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/spf13/viper"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
 	"net/http"
-	"strconv"
 )
 
-var getImageAction = "get image by id"
+var (
+	getImageAction    = "get image by id"
+	uploadImageAction = "upload image"
+)
+
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+type uploadImageResponse struct {
+	ID     string            `json:"id"`
+	Status string            `json:"status"`
+	URLs   map[string]string `json:"urls"`
+}
+
+// UploadImage stores one image of the caller before the advert is created,
+// the returned id is passed in image_ids of the new advert.
+func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		resp := newResponse("", "error parsing form", err)
+		h.logError(resp.Message, uploadImageAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	files := r.MultipartForm.File["image"]
+	if len(files) != 1 {
+		resp := newResponse("image", "exactly one image must be uploaded", nil)
+		h.logError(resp.Message, uploadImageAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	img, message, err := readImage(files[0])
+	if message != "" {
+		resp := newResponse("image", message, err)
+		h.logError(resp.Message, uploadImageAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	uploaded, err := h.service.UploadImage(r.Context(), img)
+	if err != nil {
+		resp := newResponse("", "error uploading image", err)
+		h.logError(resp.Message, uploadImageAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	// the urls answer once the image is processed, until then they report its status
+	imageURL := newImageURL(uploaded.ID)
+	urls := map[string]string{models.ImageSizeOriginal: imageURL}
+	for _, size := range []string{models.ImageSizeMedium, models.ImageSizeThumb} {
+		urls[size] = imageURL + "?size=" + size
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, uploadImageResponse{
+		ID:     uploaded.ID,
+		Status: uploaded.Status,
+		URLs:   urls,
+	})
+}
 
 func (h *Handler) GetImageByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	size := r.URL.Query().Get("size")
 
-	image, err := h.service.GetImageByID(r.Context(), id)
+	image, err := h.service.GetImageByID(r.Context(), id, size, r.Header.Get("If-None-Match"))
 	if err != nil {
 		resp := newResponse("", "error getting image by id", err)
 		h.logError(resp.Message, getImageAction, resp.Error)
@@ -19,13 +91,67 @@ func (h *Handler) GetImageByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("content-type", "image/jpeg")
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Length", strconv.Itoa(len(image.Data)))
-	if _, err := w.Write(image.Data); err != nil {
-		resp := newResponse("", "error displaying image", err)
-		h.logError(resp.Message, getImageAction, resp.Error)
-		renderResponse(w, r, http.StatusInternalServerError, resp)
+	// images never change once uploaded, a new upload always gets a new id
+	w.Header().Set("Cache-Control", imageCacheControl)
+	if image.ETag != "" {
+		w.Header().Set("ETag", image.ETag)
+	}
+
+	if image.NotModified {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
+
+	defer image.Content.Close()
+
+	// ServeContent handles Range and If-Modified-Since and streams the file without reading it into memory
+	w.Header().Set("Content-Type", "image/"+image.Format)
+	http.ServeContent(w, r, "", image.ModTime, image.Content)
+}
+
+func newImageURL(id string) string {
+	host := viper.GetString("server.host")
+	port := viper.GetString("server.port")
+	return fmt.Sprintf("http://%s:%s/api/v1/images/%s", host, port, id)
+}
+
+// readImages decodes uploaded files. On failure it returns a non-empty message
+// naming the broken file.
+func readImages(files []*multipart.FileHeader) ([]*models.Image, string, error) {
+	var images []*models.Image
+	for _, imageForm := range files {
+		img, message, err := readImage(imageForm)
+		if message != "" {
+			return nil, message, err
+		}
+		images = append(images, img)
+	}
+	return images, "", nil
+}
+
+func readImage(imageForm *multipart.FileHeader) (*models.Image, string, error) {
+	file, err := imageForm.Open()
+	if err != nil {
+		return nil, "error opening file: " + imageForm.Filename, err
+	}
+	defer file.Close()
+
+	// the format is detected by the content, not by the file name or the declared content type.
+	// Only the header is read here, the service checks the dimensions before decoding the pixels
+	_, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, "image cannot be decoded: " + imageForm.Filename, nil
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, "error reading file: " + imageForm.Filename, err
+	}
+
+	imageData, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "error reading file: " + imageForm.Filename, err
+	}
+
+	return &models.Image{Data: imageData, Format: format}, "", nil
 }
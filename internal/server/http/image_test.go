@@ -0,0 +1,203 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/models"
+	mock_service "github.com/romandnk/advertisement/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"image"
+	"image/gif"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadImagesFormat(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+
+	pngData := &bytes.Buffer{}
+	require.NoError(t, png.Encode(pngData, img))
+
+	gifData := &bytes.Buffer{}
+	require.NoError(t, gif.Encode(gifData, img, nil))
+
+	bodyBuf := &bytes.Buffer{}
+	bodyWriter := multipart.NewWriter(bodyBuf)
+	// the extension does not match the content on purpose
+	for name, data := range map[string][]byte{"first.jpg": pngData.Bytes(), "second.jpg": gifData.Bytes()} {
+		file, err := bodyWriter.CreateFormFile("images", name)
+		require.NoError(t, err)
+		_, err = file.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, bodyWriter.Close())
+
+	req := httptest.NewRequest(http.MethodPost, urlAdverts, bodyBuf)
+	req.Header.Set("Content-Type", bodyWriter.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(10<<20))
+
+	images, message, err := readImages(req.MultipartForm.File["images"])
+	require.NoError(t, err)
+	require.Empty(t, message)
+
+	formats := make([]string, 0, len(images))
+	for _, image := range images {
+		formats = append(formats, image.Format)
+	}
+	require.ElementsMatch(t, []string{models.ImageFormatPNG, models.ImageFormatGIF}, formats)
+}
+
+// testImageContent is an in-memory image file as the blob store would stream it.
+type testImageContent struct {
+	*bytes.Reader
+}
+
+func (testImageContent) Close() error {
+	return nil
+}
+
+func newTestImageFile(data []byte, format string, modTime time.Time) models.ImageFile {
+	return models.ImageFile{
+		Format:  format,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+		Content: testImageContent{bytes.NewReader(data)},
+	}
+}
+
+func TestHandlerGetImageByIDContentType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	id := uuid.New().String()
+	data := []byte("png thumbnail")
+
+	file := newTestImageFile(data, models.ImageFormatPNG, time.Now())
+	file.ETag = `"hash-thumb"`
+
+	services.EXPECT().GetImageByID(gomock.Any(), id, models.ImageSizeThumb, "").Return(file, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/images/{id}", handler.GetImageByID)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/images/"+id+"?size=thumb", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	require.Equal(t, `"hash-thumb"`, w.Header().Get("ETag"))
+	require.Equal(t, imageCacheControl, w.Header().Get("Cache-Control"))
+	require.Equal(t, data, w.Body.Bytes())
+}
+
+func TestHandlerGetImageByIDNotModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	services := mock_service.NewMockServices(ctrl)
+
+	id := uuid.New().String()
+
+	// the service does not open the file, Content stays nil
+	services.EXPECT().GetImageByID(gomock.Any(), id, "", `"hash"`).
+		Return(models.ImageFile{Format: models.ImageFormatJPEG, ETag: `"hash"`, NotModified: true}, nil)
+
+	handler := NewHandler(services, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/images/{id}", handler.GetImageByID)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/images/"+id, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", `"hash"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Equal(t, `"hash"`, w.Header().Get("ETag"))
+	require.Equal(t, imageCacheControl, w.Header().Get("Cache-Control"))
+	require.Empty(t, w.Body.Bytes())
+}
+
+func TestHandlerGetImageByIDConditional(t *testing.T) {
+	data := []byte("jpeg image content")
+	modTime := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:           "range",
+			header:         "Range",
+			value:          "bytes=5-9",
+			expectedStatus: http.StatusPartialContent,
+			expectedBody:   data[5:10],
+		},
+		{
+			name:           "not modified",
+			header:         "If-Modified-Since",
+			value:          modTime.Format(http.TimeFormat),
+			expectedStatus: http.StatusNotModified,
+			expectedBody:   nil,
+		},
+		{
+			name:           "modified",
+			header:         "If-Modified-Since",
+			value:          modTime.Add(-time.Hour).Format(http.TimeFormat),
+			expectedStatus: http.StatusOK,
+			expectedBody:   data,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			services := mock_service.NewMockServices(ctrl)
+
+			id := uuid.New().String()
+
+			services.EXPECT().GetImageByID(gomock.Any(), id, "", "").
+				Return(newTestImageFile(data, models.ImageFormatJPEG, modTime), nil)
+
+			handler := NewHandler(services, nil, " ")
+
+			r := chi.NewRouter()
+			r.Get("/api/v1/images/{id}", handler.GetImageByID)
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/images/"+id, nil)
+			require.NoError(t, err)
+			req.Header.Set(tc.header, tc.value)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+			require.Equal(t, tc.expectedBody, w.Body.Bytes())
+		})
+	}
+}
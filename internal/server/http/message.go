@@ -0,0 +1,243 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	sendAdvertMessageAction = "send advert message"
+	sendThreadMessageAction = "send thread message"
+	listThreadsAction       = "list threads"
+	listMessagesAction      = "list messages"
+	countUnreadAction       = "count unread messages"
+	blockUserAction         = "block user"
+	unblockUserAction       = "unblock user"
+)
+
+type messageRequest struct {
+	Body string `json:"body"`
+}
+
+type messageResponse struct {
+	ID        string    `json:"id"`
+	ThreadID  string    `json:"thread_id"`
+	SenderID  string    `json:"sender_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type threadResponse struct {
+	ID          string    `json:"id"`
+	AdvertID    string    `json:"advert_id"`
+	AdvertTitle string    `json:"advert_title"`
+	SellerID    string    `json:"seller_id"`
+	BuyerID     string    `json:"buyer_id"`
+	Unread      int       `json:"unread"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SendAdvertMessage writes to the seller of the advert, the first message of a buyer starts their thread.
+func (h *Handler) SendAdvertMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var body messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, sendAdvertMessageAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	message, err := h.service.SendAdvertMessage(r.Context(), id, body.Body)
+	if err != nil {
+		resp := newResponse("", "error sending message", err)
+		h.logError(resp.Message, sendAdvertMessageAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, newMessageResponse(message))
+}
+
+func (h *Handler) SendThreadMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var body messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, sendThreadMessageAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	message, err := h.service.SendThreadMessage(r.Context(), id, body.Body)
+	if err != nil {
+		resp := newResponse("", "error sending message", err)
+		h.logError(resp.Message, sendThreadMessageAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, newMessageResponse(message))
+}
+
+func (h *Handler) ListThreads(w http.ResponseWriter, r *http.Request) {
+	params := models.ThreadListParams{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r, listThreadsAction)
+	if !ok {
+		return
+	}
+	params.Limit = limit
+
+	threads, nextCursor, err := h.service.ListThreads(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error listing threads", err)
+		h.logError(resp.Message, listThreadsAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	threadsResponse := make([]threadResponse, 0, len(threads))
+	for _, thread := range threads {
+		threadsResponse = append(threadsResponse, threadResponse{
+			ID:          thread.ID,
+			AdvertID:    thread.AdvertID,
+			AdvertTitle: thread.AdvertTitle,
+			SellerID:    thread.SellerID,
+			BuyerID:     thread.BuyerID,
+			Unread:      thread.Unread,
+			CreatedAt:   thread.CreatedAt,
+			UpdatedAt:   thread.UpdatedAt,
+		})
+	}
+
+	jsonResponse := struct {
+		Threads    []threadResponse `json:"threads"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}{
+		Threads:    threadsResponse,
+		NextCursor: nextCursor,
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, jsonResponse)
+}
+
+// ListMessages returns messages of the thread, the newest first. The first page marks the thread as read.
+func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	params := models.MessageListParams{
+		ThreadID: chi.URLParam(r, "id"),
+		Cursor:   r.URL.Query().Get("cursor"),
+	}
+
+	limit, ok := h.parseLimit(w, r, listMessagesAction)
+	if !ok {
+		return
+	}
+	params.Limit = limit
+
+	messages, nextCursor, err := h.service.ListMessages(r.Context(), params)
+	if err != nil {
+		resp := newResponse("", "error listing messages", err)
+		h.logError(resp.Message, listMessagesAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	messagesResponse := make([]messageResponse, 0, len(messages))
+	for _, message := range messages {
+		messagesResponse = append(messagesResponse, newMessageResponse(message))
+	}
+
+	jsonResponse := struct {
+		Messages   []messageResponse `json:"messages"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}{
+		Messages:   messagesResponse,
+		NextCursor: nextCursor,
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, jsonResponse)
+}
+
+func (h *Handler) CountUnreadMessages(w http.ResponseWriter, r *http.Request) {
+	unread, err := h.service.CountUnreadMessages(r.Context())
+	if err != nil {
+		resp := newResponse("", "error counting unread messages", err)
+		h.logError(resp.Message, countUnreadAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]int{"unread": unread})
+}
+
+func (h *Handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.BlockUser(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error blocking user", err)
+		h.logError(resp.Message, blockUserAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.UnblockUser(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error unblocking user", err)
+		h.logError(resp.Message, unblockUserAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseLimit reads the optional limit query parameter, on failure it writes the response and returns false.
+func (h *Handler) parseLimit(w http.ResponseWriter, r *http.Request, action string) (int, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return 0, true
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		resp := newResponse("limit", "must be an integer", err)
+		h.logError(resp.Message, action, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return 0, false
+	}
+
+	return limit, true
+}
+
+func newMessageResponse(message models.Message) messageResponse {
+	return messageResponse{
+		ID:        message.ID,
+		ThreadID:  message.ThreadID,
+		SenderID:  message.SenderID,
+		Body:      message.Body,
+		CreatedAt: message.CreatedAt,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/romandnk/advertisement/internal/models"
 	"github.com/urfave/negroni"
 	"go.uber.org/zap"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 	"time"
 )
 
+var checkRoleAction = "check role"
+
 func (h *Handler) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lrw := negroni.NewResponseWriter(w)
@@ -35,33 +38,102 @@ func (h *Handler) loggingMiddleware(next http.Handler) http.Handler {
 
 func (h *Handler) authorizationMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bearerToken := r.Header.Get("Authorization")
-		if bearerToken == "" {
+		if r.Header.Get("Authorization") == "" {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := strings.Replace(bearerToken, "Bearer ", "", 1)
+		h.authorize(next, w, r)
+	})
+}
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			_, ok := t.Method.(*jwt.SigningMethodHMAC)
-			if !ok {
-				w.WriteHeader(http.StatusUnauthorized)
-				return nil, errors.New("error parsing token")
-			}
-			return []byte(h.secretKey), nil
-		})
-		if err != nil || !token.Valid {
+// optionalAuthorizationMiddleware lets anonymous requests through, but a request with a token must be authorized.
+func (h *Handler) optionalAuthorizationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.authorize(next, w, r)
+	})
+}
+
+func (h *Handler) authorize(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	bearerToken := r.Header.Get("Authorization")
+
+	tokenStr := strings.Replace(bearerToken, "Bearer ", "", 1)
+
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		_, ok := t.Method.(*jwt.SigningMethodHMAC)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return nil, errors.New("error parsing token")
+		}
+		return []byte(h.secretKey), nil
+	})
+	if err != nil || !token.Valid {
+		resp := newResponse("", "unauthorized", err)
+		h.logError(resp.Message, getUserAction, resp.Error)
+		renderResponse(w, r, http.StatusUnauthorized, resp)
+		return
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		accessToken := newAccessToken(claims)
+
+		err = h.service.CheckAccessToken(r.Context(), accessToken)
+		if err != nil {
 			resp := newResponse("", "unauthorized", err)
 			h.logError(resp.Message, getUserAction, resp.Error)
 			renderResponse(w, r, http.StatusUnauthorized, resp)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			userId := claims["user_id"].(string)
-			ctx := context.WithValue(r.Context(), "user_id", userId)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		}
-	})
+		ctx := context.WithValue(r.Context(), "user_id", accessToken.UserID)
+		ctx = context.WithValue(ctx, "role", accessToken.Role)
+		ctx = context.WithValue(ctx, "session_id", accessToken.SessionID)
+		ctx = context.WithValue(ctx, "access_token", accessToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func newAccessToken(claims jwt.MapClaims) models.AccessToken {
+	accessToken := models.AccessToken{
+		Role: models.RoleUser,
+	}
+
+	accessToken.ID, _ = claims["jti"].(string)
+	accessToken.UserID, _ = claims["user_id"].(string)
+	accessToken.SessionID, _ = claims["sid"].(string)
+	if role, ok := claims["role"].(string); ok {
+		accessToken.Role = role
+	}
+	if version, ok := claims["ver"].(float64); ok {
+		accessToken.Version = int(version)
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		accessToken.ExpiresAt = expiresAt.Time
+	}
+
+	return accessToken
+}
+
+// requireRole lets the request through only if authorizationMiddleware put one of the roles into the context.
+func (h *Handler) requireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			resp := newResponse("role", "forbidden", nil)
+			h.logError(resp.Message, checkRoleAction, "role "+role+" is not allowed")
+			renderResponse(w, r, http.StatusForbidden, resp)
+		})
+	}
 }
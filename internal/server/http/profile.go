@@ -0,0 +1,167 @@
+package http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"net/http"
+	"time"
+)
+
+var (
+	getProfileAction       = "get profile"
+	updateProfileAction    = "update profile"
+	getPublicProfileAction = "get public profile"
+)
+
+type profileResponse struct {
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name"`
+	Phone         string    `json:"phone"`
+	City          string    `json:"city"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newProfileResponse(user models.User) profileResponse {
+	return profileResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		Phone:         user.Phone,
+		City:          user.City,
+		AvatarURL:     newAvatarURL(user.AvatarID),
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// publicProfileResponse is what anyone can see about a seller, it must not contain contacts or credentials.
+type publicProfileResponse struct {
+	ID          string           `json:"id"`
+	DisplayName string           `json:"display_name"`
+	City        string           `json:"city"`
+	AvatarURL   string           `json:"avatar_url,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Adverts     []advertResponse `json:"adverts"`
+	NextCursor  string           `json:"next_cursor,omitempty"`
+}
+
+func newAvatarURL(avatarID string) string {
+	if avatarID == "" {
+		return ""
+	}
+	return newImageURL(avatarID)
+}
+
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetProfile(r.Context())
+	if err != nil {
+		resp := newResponse("", "error getting profile", err)
+		h.logError(resp.Message, getProfileAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newProfileResponse(user))
+}
+
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var update models.UserProfileUpdate
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		resp := newResponse("", "error parsing form", err)
+		h.logError(resp.Message, updateProfileAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	form := r.MultipartForm.Value
+
+	if _, ok := form["display_name"]; ok {
+		displayName := r.FormValue("display_name")
+		update.DisplayName = &displayName
+	}
+
+	if _, ok := form["phone"]; ok {
+		phone := r.FormValue("phone")
+		update.Phone = &phone
+	}
+
+	if _, ok := form["city"]; ok {
+		city := r.FormValue("city")
+		update.City = &city
+	}
+
+	update.RemoveAvatar = r.FormValue("remove_avatar") == "true"
+
+	if files := r.MultipartForm.File["avatar"]; len(files) > 0 {
+		avatar, message, err := readImage(files[0])
+		if message != "" {
+			resp := newResponse("avatar", message, err)
+			h.logError(resp.Message, updateProfileAction, resp.Error)
+			renderResponse(w, r, http.StatusBadRequest, resp)
+			return
+		}
+		update.Avatar = avatar
+	}
+
+	user, err := h.service.UpdateProfile(r.Context(), update)
+	if err != nil {
+		resp := newResponse("", "error updating profile", err)
+		h.logError(resp.Message, updateProfileAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newProfileResponse(user))
+}
+
+func (h *Handler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user, err := h.service.GetPublicProfile(r.Context(), id)
+	if err != nil {
+		// a malformed id and a missing user are reported by the service as custom errors
+		code := http.StatusInternalServerError
+		var customErr custom_error.CustomError
+		if errors.As(err, &customErr) {
+			code = http.StatusNotFound
+		}
+		resp := newResponse("", "error getting user", err)
+		h.logError(resp.Message, getPublicProfileAction, resp.Error)
+		renderResponse(w, r, code, resp)
+		return
+	}
+
+	adverts, nextCursor, err := h.service.ListAdverts(r.Context(), models.AdvertListParams{UserID: user.ID})
+	if err != nil {
+		resp := newResponse("", "error listing user adverts", err)
+		h.logError(resp.Message, getPublicProfileAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	advertsResponse := make([]advertResponse, 0, len(adverts))
+	for _, advert := range adverts {
+		advertsResponse = append(advertsResponse, newAdvertResponse(advert))
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, publicProfileResponse{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		City:        user.City,
+		AvatarURL:   newAvatarURL(user.AvatarID),
+		CreatedAt:   user.CreatedAt,
+		Adverts:     advertsResponse,
+		NextCursor:  nextCursor,
+	})
+}
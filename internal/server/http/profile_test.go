@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v2"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/service"
+	mock_service "github.com/romandnk/advertisement/internal/service/mock"
+	"github.com/romandnk/advertisement/internal/storage/postgres"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandlerGetPublicProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mock_service.NewMockServices(ctrl)
+
+	user := models.User{
+		ID:          uuid.New().String(),
+		Email:       "test@vk.com",
+		Password:    "hash",
+		DisplayName: "Test",
+		Phone:       "+79991234567",
+		City:        "Moscow",
+		CreatedAt:   time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	advert := models.Advert{
+		ID:        uuid.New().String(),
+		Title:     "test",
+		Price:     decimal.New(100, 0),
+		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		UserID:    user.ID,
+	}
+
+	service.EXPECT().GetPublicProfile(gomock.Any(), user.ID).Return(user, nil)
+	service.EXPECT().ListAdverts(gomock.Any(), models.AdvertListParams{UserID: user.ID}).Return([]models.Advert{advert}, "", nil)
+
+	handler := NewHandler(service, nil, " ")
+
+	r := chi.NewRouter()
+	r.Get(urlUsers+"/{id}", handler.GetPublicProfile)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlUsers+"/"+user.ID, nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, user.ID, responseBody["id"])
+	require.Equal(t, user.DisplayName, responseBody["display_name"])
+	require.Equal(t, "2000-01-02T00:00:00Z", responseBody["created_at"])
+	require.NotContains(t, responseBody, "email")
+	require.NotContains(t, responseBody, "phone")
+	require.NotContains(t, responseBody, "password")
+	require.Len(t, responseBody["adverts"], 1)
+}
+
+func TestHandlerGetPublicProfileError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "not found",
+			err:          pgx.ErrNoRows,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "db is down",
+			err:          errors.New("db is down"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			id := uuid.New().String()
+
+			// the error goes through the real storage and service as it does in production
+			mock.ExpectQuery("SELECT id, email").WithArgs(id).WillReturnError(tc.err)
+			users := service.NewUserService(postgres.NewPostgresStorage(mock), nil, nil, nil, nil, nil, nil, nil, 0, "", "", 0)
+
+			services := mock_service.NewMockServices(ctrl)
+			services.EXPECT().GetPublicProfile(gomock.Any(), id).DoAndReturn(users.GetPublicProfile)
+
+			logger := mock_logger.NewMockLogger(ctrl)
+			logger.EXPECT().Error("error getting user", gomock.Any())
+
+			handler := NewHandler(services, logger, " ")
+
+			r := chi.NewRouter()
+			r.Get(urlUsers+"/{id}", handler.GetPublicProfile)
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlUsers+"/"+id, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+			require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+		})
+	}
+}
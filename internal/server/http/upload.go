@@ -0,0 +1,143 @@
+package http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+var (
+	createUploadAction = "create upload"
+	getUploadAction    = "get upload"
+	writeUploadAction  = "write upload chunk"
+)
+
+const (
+	// uploadChunkMaxSize caps one chunk like the multipart form of an advert is capped
+	uploadChunkMaxSize  = 10 << 20
+	uploadChunkMimeType = "application/offset+octet-stream"
+)
+
+type uploadResponse struct {
+	ID       string `json:"id"`
+	Offset   int64  `json:"offset"`
+	Length   int64  `json:"length"`
+	Complete bool   `json:"complete"`
+}
+
+// CreateUpload starts a chunked upload of Upload-Length bytes, the chunks are sent to the Location.
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		resp := newResponse("Upload-Length", "must be a number of bytes", err)
+		h.logError(resp.Message, createUploadAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	upload, err := h.service.CreateUpload(r.Context(), length)
+	if err != nil {
+		resp := newResponse("", "error creating upload", err)
+		h.logError(resp.Message, createUploadAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Location", "/api/v1/uploads/"+upload.ID)
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, newUploadResponse(upload))
+}
+
+// GetUpload answers HEAD with the received bytes in Upload-Offset, a client resumes the upload from there.
+func (h *Handler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	upload, err := h.service.GetUpload(r.Context(), id)
+	if err != nil {
+		// a malformed id and a missing upload are reported by the service as custom errors
+		code := http.StatusInternalServerError
+		var customErr custom_error.CustomError
+		if errors.As(err, &customErr) {
+			code = http.StatusNotFound
+		}
+		resp := newResponse("", "error getting upload", err)
+		h.logError(resp.Message, getUploadAction, resp.Error)
+		renderResponse(w, r, code, resp)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// WriteUploadChunk appends the body to the upload, Upload-Offset must be equal to the received bytes.
+func (h *Handler) WriteUploadChunk(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if r.Header.Get("Content-Type") != uploadChunkMimeType {
+		resp := newResponse("Content-Type", "must be "+uploadChunkMimeType, nil)
+		h.logError(resp.Message, writeUploadAction, resp.Error)
+		renderResponse(w, r, http.StatusUnsupportedMediaType, resp)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		resp := newResponse("Upload-Offset", "must be a number of bytes", err)
+		h.logError(resp.Message, writeUploadAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, uploadChunkMaxSize))
+	if err != nil {
+		code := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		resp := newResponse("chunk", "error reading chunk", err)
+		h.logError(resp.Message, writeUploadAction, resp.Error)
+		renderResponse(w, r, code, resp)
+		return
+	}
+
+	upload, err := h.service.WriteUploadChunk(r.Context(), id, offset, chunk)
+	if err != nil {
+		// the client has to ask for the offset again, e.g. a previous chunk was received but the response was lost
+		code := http.StatusInternalServerError
+		var customErr custom_error.CustomError
+		if errors.As(err, &customErr) && customErr.Field == "Upload-Offset" {
+			code = http.StatusConflict
+		}
+		resp := newResponse("", "error writing upload chunk", err)
+		h.logError(resp.Message, writeUploadAction, resp.Error)
+		renderResponse(w, r, code, resp)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setUploadHeaders(w http.ResponseWriter, upload models.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+}
+
+func newUploadResponse(upload models.Upload) uploadResponse {
+	return uploadResponse{
+		ID:       upload.ID,
+		Offset:   upload.Offset,
+		Length:   upload.Length,
+		Complete: upload.Offset == upload.Length,
+	}
+}
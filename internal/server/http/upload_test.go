@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	mock_service "github.com/romandnk/advertisement/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerGetUploadError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "not found",
+			err:          custom_error.CustomError{Field: "id", Message: "upload not found"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "db is down",
+			err:          errors.New("db is down"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			id := uuid.New().String()
+
+			services := mock_service.NewMockServices(ctrl)
+			services.EXPECT().GetUpload(gomock.Any(), id).Return(models.Upload{}, tc.err)
+
+			logger := mock_logger.NewMockLogger(ctrl)
+			logger.EXPECT().Error("error getting upload", gomock.Any())
+
+			handler := NewHandler(services, logger, " ")
+
+			r := chi.NewRouter()
+			r.Head("/uploads/{id}", handler.GetUpload)
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodHead, "/uploads/"+id, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
@@ -2,14 +2,25 @@ package http
 
 import (
 	"encoding/json"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/romandnk/advertisement/internal/models"
 	"net/http"
+	"time"
 )
 
 var (
-	createUserAction = "create user"
-	getUserAction    = "get user"
+	createUserAction    = "create user"
+	getUserAction       = "get user"
+	refreshTokensAction = "refresh tokens"
+	listSessionsAction  = "list sessions"
+	revokeSessionAction = "revoke session"
+	logoutAction        = "logout"
+	logoutAllAction     = "logout everywhere"
+	verifyEmailAction   = "verify email"
+	resendEmailAction   = "resend verification email"
+	requestResetAction  = "request password reset"
+	resetPasswordAction = "reset password"
 )
 
 type bodyUser struct {
@@ -56,7 +67,7 @@ func (h *Handler) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.service.SignIn(r.Context(), userFromBody.Email, userFromBody.Password)
+	tokens, err := h.service.SignIn(r.Context(), userFromBody.Email, userFromBody.Password, r.UserAgent())
 	if err != nil {
 		resp := newResponse("", "error getting user", err)
 		h.logError(resp.Message, getUserAction, resp.Error)
@@ -65,5 +76,188 @@ func (h *Handler) SignIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, map[string]string{"token": token})
+	render.JSON(w, r, newTokensResponse(tokens))
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	err := h.service.VerifyEmail(r.Context(), token)
+	if err != nil {
+		resp := newResponse("", "error verifying email", err)
+		h.logError(resp.Message, verifyEmailAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	err := h.service.ResendVerificationEmail(r.Context())
+	if err != nil {
+		resp := newResponse("", "error sending verification email", err)
+		h.logError(resp.Message, resendEmailAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, requestResetAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	err = h.service.RequestPasswordReset(r.Context(), body.Email)
+	if err != nil {
+		resp := newResponse("", "error requesting password reset", err)
+		h.logError(resp.Message, requestResetAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, resetPasswordAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	err = h.service.ResetPassword(r.Context(), body.Token, body.Password)
+	if err != nil {
+		resp := newResponse("", "error resetting password", err)
+		h.logError(resp.Message, resetPasswordAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		resp := newResponse("", "invalid JSON data", err)
+		h.logError(resp.Message, refreshTokensAction, resp.Error)
+		renderResponse(w, r, http.StatusBadRequest, resp)
+		return
+	}
+
+	tokens, err := h.service.Refresh(r.Context(), body.RefreshToken)
+	if err != nil {
+		resp := newResponse("", "error refreshing tokens", err)
+		h.logError(resp.Message, refreshTokensAction, resp.Error)
+		renderResponse(w, r, http.StatusUnauthorized, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newTokensResponse(tokens))
+}
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.service.ListSessions(r.Context())
+	if err != nil {
+		resp := newResponse("", "error listing sessions", err)
+		h.logError(resp.Message, listSessionsAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	type sessionResponse struct {
+		ID         string    `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	}
+
+	currentSessionID, _ := r.Context().Value("session_id").(string)
+
+	sessionsResponse := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		sessionsResponse = append(sessionsResponse, sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"sessions": sessionsResponse})
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := h.service.RevokeSession(r.Context(), id)
+	if err != nil {
+		resp := newResponse("", "error revoking session", err)
+		h.logError(resp.Message, revokeSessionAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	accessToken, _ := r.Context().Value("access_token").(models.AccessToken)
+
+	err := h.service.Logout(r.Context(), accessToken)
+	if err != nil {
+		resp := newResponse("", "error logging out", err)
+		h.logError(resp.Message, logoutAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	err := h.service.LogoutAll(r.Context())
+	if err != nil {
+		resp := newResponse("", "error logging out everywhere", err)
+		h.logError(resp.Message, logoutAllAction, resp.Error)
+		renderResponse(w, r, http.StatusInternalServerError, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func newTokensResponse(tokens models.Tokens) map[string]string {
+	return map[string]string{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
@@ -16,6 +17,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 const urlUsers = "/api/v1/users"
@@ -214,3 +216,256 @@ func TestHandlerSignUpErrorCreatingUser(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerSignIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mock_service.NewMockServices(ctrl)
+
+	expectedTokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+
+	service.EXPECT().SignIn(gomock.Any(), "test@vk.com", "Qwerty123", "test agent").Return(expectedTokens, nil)
+
+	handler := NewHandler(service, nil, " ")
+
+	r := chi.NewRouter()
+	r.Post(urlUsers+"/sign-in", handler.SignIn)
+
+	jsonBody, err := json.Marshal(map[string]string{
+		"email":    "test@vk.com",
+		"password": "Qwerty123",
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlUsers+"/sign-in", bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test agent")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"access_token":  "access",
+		"refresh_token": "refresh",
+	}, responseBody)
+}
+
+func TestHandlerRefreshError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	expectedError := custom_error.CustomError{Field: "refresh_token", Message: "invalid refresh token"}
+
+	service.EXPECT().Refresh(gomock.Any(), "stolen").Return(models.Tokens{}, expectedError)
+	logger.EXPECT().Error("error refreshing tokens",
+		zap.String("action", refreshTokensAction),
+		zap.String("error", expectedError.Error()),
+	)
+
+	handler := NewHandler(service, logger, " ")
+
+	r := chi.NewRouter()
+	r.Post(urlUsers+"/refresh", handler.Refresh)
+
+	jsonBody, err := json.Marshal(map[string]string{"refresh_token": "stolen"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlUsers+"/refresh", bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var responseBody map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"field":   "refresh_token",
+		"message": "error refreshing tokens",
+		"error":   "invalid refresh token",
+	}, responseBody)
+}
+
+func TestHandlerAuthorizationMiddleware(t *testing.T) {
+	revokedError := custom_error.CustomError{Field: "token", Message: "token has been revoked"}
+
+	testCases := []struct {
+		name     string
+		checkErr error
+		code     int
+	}{
+		{
+			name:     "valid token",
+			checkErr: nil,
+			code:     http.StatusOK,
+		},
+		{
+			name:     "revoked token",
+			checkErr: revokedError,
+			code:     http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+			expectedToken := models.AccessToken{
+				ID:        uuid.New().String(),
+				UserID:    uuid.New().String(),
+				Role:      models.RoleUser,
+				SessionID: uuid.New().String(),
+				Version:   3,
+				ExpiresAt: expiresAt,
+			}
+
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"jti":     expectedToken.ID,
+				"exp":     expiresAt.Unix(),
+				"user_id": expectedToken.UserID,
+				"role":    expectedToken.Role,
+				"sid":     expectedToken.SessionID,
+				"ver":     expectedToken.Version,
+			})
+			tokenStr, err := token.SignedString([]byte(" "))
+			require.NoError(t, err)
+
+			service.EXPECT().CheckAccessToken(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, accessToken models.AccessToken) error {
+					require.Equal(t, expectedToken.ID, accessToken.ID)
+					require.Equal(t, expectedToken.UserID, accessToken.UserID)
+					require.Equal(t, expectedToken.Role, accessToken.Role)
+					require.Equal(t, expectedToken.SessionID, accessToken.SessionID)
+					require.Equal(t, expectedToken.Version, accessToken.Version)
+					require.True(t, expectedToken.ExpiresAt.Equal(accessToken.ExpiresAt))
+					return tc.checkErr
+				})
+			if tc.checkErr != nil {
+				logger.EXPECT().Error("unauthorized",
+					zap.String("action", getUserAction),
+					zap.String("error", tc.checkErr.Error()),
+				)
+			}
+
+			handler := NewHandler(service, logger, " ")
+
+			r := chi.NewRouter()
+			r.With(handler.authorizationMiddleware).Post(urlUsers+"/logout", func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, expectedToken.UserID, r.Context().Value("user_id"))
+				require.Equal(t, expectedToken.SessionID, r.Context().Value("session_id"))
+				w.WriteHeader(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlUsers+"/logout", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tokenStr)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerVerifyEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{
+			name: "valid token",
+			err:  nil,
+			code: http.StatusOK,
+		},
+		{
+			name: "used token",
+			err:  custom_error.CustomError{Field: "token", Message: "token is invalid, expired or already used"},
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			service.EXPECT().VerifyEmail(gomock.Any(), "secret").Return(tc.err)
+			if tc.err != nil {
+				logger.EXPECT().Error("error verifying email",
+					zap.String("action", verifyEmailAction),
+					zap.String("error", "token is invalid, expired or already used"),
+				)
+			}
+
+			handler := NewHandler(service, logger, " ")
+
+			r := chi.NewRouter()
+			r.Get(urlUsers+"/verify", handler.VerifyEmail)
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlUsers+"/verify?token=secret", nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerRequestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mock_service.NewMockServices(ctrl)
+
+	service.EXPECT().RequestPasswordReset(gomock.Any(), "unknown@vk.com").Return(nil)
+
+	handler := NewHandler(service, nil, " ")
+
+	r := chi.NewRouter()
+	r.Post(urlUsers+"/password/reset-request", handler.RequestPasswordReset)
+
+	jsonBody, err := json.Marshal(map[string]string{"email": "unknown@vk.com"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, urlUsers+"/password/reset-request", bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Empty(t, w.Body.String())
+}
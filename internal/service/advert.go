@@ -1,16 +1,21 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/mailer"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/romandnk/advertisement/internal/storage"
 	"go.uber.org/zap"
+	"image"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var (
@@ -19,25 +24,89 @@ var (
 	ErrAdvertServiceNoImages      = errors.New("no images")
 	ErrAdvertServiceManyImages    = errors.New("max number of images is 7")
 	ErrAdvertServiceNoUserID      = errors.New("no user id")
+	ErrAdvertServiceNotFound      = errors.New("advert not found")
+	ErrAdvertServiceImageNotFound = errors.New("image does not belong to advert")
+	ErrAdvertServiceInvalidLimit  = errors.New("limit must be from 1 to 100")
+	ErrAdvertServiceInvalidSort   = errors.New("sort must be price or date")
+	ErrAdvertServiceInvalidOrder  = errors.New("order must be asc or desc")
+	ErrAdvertServiceInvalidCursor = errors.New("invalid cursor")
+	ErrAdvertServicePriceRange    = errors.New("min price is greater than max price")
+	ErrAdvertServiceDateRange     = errors.New("created from is later than created to")
+	ErrAdvertServiceEmptyQuery    = errors.New("empty search query")
+	ErrAdvertServiceLongQuery     = errors.New("max search query length is 256")
+	ErrAdvertServiceInvalidOffset = errors.New("offset must not be negative")
+	ErrAdvertServiceNotVerified   = errors.New("email is not verified")
+	ErrAdvertServiceInvalidStatus = errors.New("status can be changed to pending_review, draft, archived or sold")
+	ErrAdvertServiceEmptyReason   = errors.New("empty reject reason")
 )
 
+const (
+	defaultAdvertsLimit = 20
+	maxAdvertsLimit     = 100
+	// duplicateAdvertsLimit caps the rows of one advert, an advert has at most 7 images
+	duplicateAdvertsLimit = 100
+)
+
+// ownerStatusTransitions maps a status the owner may set to the statuses the advert may have before.
+// Publishing and rejecting are left to moderators.
+var ownerStatusTransitions = map[string][]string{
+	models.AdvertStatusDraft:         {models.AdvertStatusPendingReview, models.AdvertStatusRejected},
+	models.AdvertStatusPendingReview: {models.AdvertStatusDraft, models.AdvertStatusRejected, models.AdvertStatusArchived},
+	models.AdvertStatusArchived:      {models.AdvertStatusPublished},
+	models.AdvertStatusSold:          {models.AdvertStatusPublished, models.AdvertStatusArchived},
+}
+
 type AdvertService struct {
-	advert       storage.AdvertStorage
-	logger       logger.Logger
-	pathToImages string
+	advert         storage.AdvertStorage
+	category       storage.CategoryStorage
+	user           storage.UserStorage
+	upload         storage.UploadStorage
+	favourite      storage.FavouriteStorage
+	mailer         mailer.Mailer
+	logger         logger.Logger
+	blob           blob.BlobStore
+	imageFormats   map[string]struct{}
+	imageMaxPixels int
+	// images whose perceptual hashes differ in at most duplicateMaxDistance bits are duplicates
+	duplicateMaxDistance int
+	publicURL            string
+	advertTTL            time.Duration
+	expiryBatchSize      int
 }
 
-func NewAdvertService(advert storage.AdvertStorage, logger logger.Logger, pathToImages string) *AdvertService {
+func NewAdvertService(advert storage.AdvertStorage, category storage.CategoryStorage, user storage.UserStorage,
+	upload storage.UploadStorage, favourite storage.FavouriteStorage, mailer mailer.Mailer,
+	logger logger.Logger, blob blob.BlobStore, imageFormats []string, imageMaxPixels int,
+	duplicateMaxDistance int, publicURL string, advertTTL time.Duration, expiryBatchSize int) *AdvertService {
 	return &AdvertService{
-		advert:       advert,
-		logger:       logger,
-		pathToImages: pathToImages,
+		advert:               advert,
+		category:             category,
+		user:                 user,
+		upload:               upload,
+		favourite:            favourite,
+		mailer:               mailer,
+		logger:               logger,
+		blob:                 blob,
+		imageFormats:         newImageFormats(imageFormats),
+		imageMaxPixels:       imageMaxPixels,
+		duplicateMaxDistance: duplicateMaxDistance,
+		publicURL:            publicURL,
+		advertTTL:            advertTTL,
+		expiryBatchSize:      expiryBatchSize,
 	}
 }
 
 func (a *AdvertService) CreateAdvert(ctx context.Context, advert models.Advert) (string, error) {
 	advert.ID = uuid.New().String()
 
+	user, err := a.user.GetUserByID(ctx, advert.UserID)
+	if err != nil {
+		return "", err
+	}
+	if !user.EmailVerified {
+		return "", custom_error.CustomError{Field: "email", Message: ErrAdvertServiceNotVerified.Error()}
+	}
+
 	advert.Title = strings.TrimSpace(advert.Title)
 	if advert.Title == "" {
 		return "", custom_error.CustomError{Field: "title", Message: ErrAdvertServiceEmptyTitle.Error()}
@@ -49,21 +118,56 @@ func (a *AdvertService) CreateAdvert(ctx context.Context, advert models.Advert)
 		return "", custom_error.CustomError{Field: "price", Message: ErrAdvertServiceNegativePrice.Error()}
 	}
 
+	categoryID, err := validateLeafCategory(ctx, a.category, advert.CategoryID)
+	if err != nil {
+		return "", err
+	}
+	advert.CategoryID = categoryID
+
+	// a new advert is either kept as a draft or sent straight to moderators
+	if advert.Status != models.AdvertStatusDraft {
+		advert.Status = models.AdvertStatusPendingReview
+	}
+
 	now := time.Now()
 	advert.CreatedAt = now
 	advert.UpdatedAt = now
-	if len(advert.Images) == 0 {
-		return "", custom_error.CustomError{Field: "images", Message: ErrAdvertServiceNoImages.Error()}
+	advert.ExpiresAt = now.Add(a.advertTTL)
+
+	uploaded, err := a.uploadedImages(ctx, advert.UserID, advert.UploadIDs)
+	if err != nil {
+		return "", err
 	}
-	if len(advert.Images) > 7 {
-		return "", custom_error.CustomError{Field: "images", Message: ErrAdvertServiceManyImages.Error()}
+	advert.Images = append(advert.Images, uploaded...)
+
+	for i, id := range advert.ImageIDs {
+		parsedID, err := uuid.Parse(id)
+		if err != nil {
+			return "", custom_error.CustomError{Field: "image_ids", Message: err.Error()}
+		}
+		advert.ImageIDs[i] = parsedID.String()
+	}
+
+	// images uploaded beforehand are already validated, their ownership is checked when they are attached
+	if err := validateImagesCount(len(advert.Images) + len(advert.ImageIDs)); err != nil {
+		return "", err
+	}
+	for _, image := range advert.Images {
+		if err := validateImageFormat("images", image, a.imageFormats); err != nil {
+			return "", err
+		}
+		if err := checkImagePixels("images", image, a.imageMaxPixels); err != nil {
+			return "", err
+		}
 	}
 
+	// images are decoded, resized and stored by the image jobs, the advert is shown when they are ready
 	for _, image := range advert.Images {
 		image.ID = uuid.New().String()
 		image.AdvertID = advert.ID
+		image.Status = models.ImageStatusProcessing
 		image.CreatedAt = now
-		err := saveImage(image, a.pathToImages)
+		err := saveImageUpload(ctx, a.blob, image)
 		if err != nil {
 			return "", custom_error.CustomError{Field: "images", Message: err.Error()}
 		}
@@ -72,7 +176,7 @@ func (a *AdvertService) CreateAdvert(ctx context.Context, advert models.Advert)
 	id, err := a.advert.CreateAdvert(ctx, advert)
 	if err != nil {
 		for _, image := range advert.Images {
-			err := deleteImage(image.ID, a.pathToImages)
+			err := a.blob.Delete(ctx, imageUploadKey(image.ID))
 			if err != nil {
 				a.logger.Error("error deleting image while creating advert", zap.String("error", err.Error()))
 			}
@@ -80,21 +184,157 @@ func (a *AdvertService) CreateAdvert(ctx context.Context, advert models.Advert)
 		return "", err
 	}
 
+	a.removeUploads(ctx, advert.UserID, advert.UploadIDs)
+
 	return id, nil
 }
 
+func (a *AdvertService) UpdateAdvert(ctx context.Context, update models.AdvertUpdate) (models.Advert, error) {
+	parsedID, err := uuid.Parse(update.ID)
+	if err != nil {
+		return models.Advert{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+	update.ID = parsedID.String()
+
+	update.UserID, err = getUserID(ctx)
+	if err != nil {
+		return models.Advert{}, err
+	}
+
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if title == "" {
+			return models.Advert{}, custom_error.CustomError{Field: "title", Message: ErrAdvertServiceEmptyTitle.Error()}
+		}
+		update.Title = &title
+	}
+
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		update.Description = &description
+	}
+
+	if update.Price != nil && update.Price.IsNegative() {
+		return models.Advert{}, custom_error.CustomError{Field: "price", Message: ErrAdvertServiceNegativePrice.Error()}
+	}
+
+	if update.CategoryID != nil {
+		categoryID, err := validateLeafCategory(ctx, a.category, *update.CategoryID)
+		if err != nil {
+			return models.Advert{}, err
+		}
+		update.CategoryID = &categoryID
+	}
+
+	current, err := a.advert.GetAdvertByID(ctx, update.ID)
+	if err != nil {
+		return models.Advert{}, err
+	}
+	if current.UserID != update.UserID {
+		return models.Advert{}, custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()}
+	}
+
+	currentImages := make(map[string]struct{}, len(current.Images))
+	for _, image := range current.Images {
+		currentImages[image.ID] = struct{}{}
+	}
+
+	removeImages := make(map[string]struct{}, len(update.RemoveImageIDs))
+	for _, imageID := range update.RemoveImageIDs {
+		if _, ok := currentImages[imageID]; !ok {
+			return models.Advert{}, custom_error.CustomError{Field: "remove_images", Message: ErrAdvertServiceImageNotFound.Error()}
+		}
+		removeImages[imageID] = struct{}{}
+	}
+
+	update.RemoveImageIDs = update.RemoveImageIDs[:0]
+	for imageID := range removeImages {
+		update.RemoveImageIDs = append(update.RemoveImageIDs, imageID)
+	}
+
+	uploaded, err := a.uploadedImages(ctx, update.UserID, update.AddUploadIDs)
+	if err != nil {
+		return models.Advert{}, err
+	}
+	update.AddImages = append(update.AddImages, uploaded...)
+
+	if err := validateImagesCount(len(current.Images) - len(removeImages) + len(update.AddImages)); err != nil {
+		return models.Advert{}, err
+	}
+	for _, image := range update.AddImages {
+		if err := validateImageFormat("images", image, a.imageFormats); err != nil {
+			return models.Advert{}, err
+		}
+		if err := checkImagePixels("images", image, a.imageMaxPixels); err != nil {
+			return models.Advert{}, err
+		}
+	}
+
+	update.ContentChanged = advertContentChanged(current, update)
+
+	now := time.Now()
+	update.UpdatedAt = now
+
+	for _, image := range update.AddImages {
+		image.ID = uuid.New().String()
+		image.AdvertID = update.ID
+		image.Status = models.ImageStatusProcessing
+		image.CreatedAt = now
+		err := saveImageUpload(ctx, a.blob, image)
+		if err != nil {
+			return models.Advert{}, custom_error.CustomError{Field: "images", Message: err.Error()}
+		}
+	}
+
+	err = a.advert.UpdateAdvert(ctx, update)
+	if err != nil {
+		for _, image := range update.AddImages {
+			err := a.blob.Delete(ctx, imageUploadKey(image.ID))
+			if err != nil {
+				a.logger.Error("error deleting image while updating advert", zap.String("error", err.Error()))
+			}
+		}
+		return models.Advert{}, err
+	}
+
+	a.removeUploads(ctx, update.UserID, update.AddUploadIDs)
+
+	for _, imageID := range update.RemoveImageIDs {
+		err := deleteImage(ctx, a.blob, imageID)
+		if err != nil {
+			a.logger.Error("error deleting image while updating advert", zap.String("error", err.Error()))
+		}
+	}
+
+	return a.advert.GetAdvertByID(ctx, update.ID)
+}
+
+// advertContentChanged reports whether the update changes anything a moderator has to review.
+func advertContentChanged(current models.Advert, update models.AdvertUpdate) bool {
+	if len(update.AddImages) > 0 || len(update.RemoveImageIDs) > 0 {
+		return true
+	}
+	if update.Title != nil && *update.Title != current.Title {
+		return true
+	}
+	if update.Description != nil && *update.Description != current.Description {
+		return true
+	}
+	if update.Price != nil && !update.Price.Equal(current.Price) {
+		return true
+	}
+	return update.CategoryID != nil && *update.CategoryID != current.CategoryID
+}
+
 func (a *AdvertService) DeleteAdvert(ctx context.Context, id string) error {
 	parsedID, err := uuid.Parse(id)
 	if err != nil {
 		return custom_error.CustomError{Field: "id", Message: err.Error()}
 	}
 
-	var userID string
-	switch ctx.Value("user_id").(type) {
-	case string:
-		userID = ctx.Value("user_id").(string)
-	default:
-		return custom_error.CustomError{Field: "user_id", Message: ErrAdvertServiceNoUserID.Error()}
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
 	}
 
 	imageIDs, err := a.advert.DeleteAdvert(ctx, parsedID.String(), userID)
@@ -103,7 +343,39 @@ func (a *AdvertService) DeleteAdvert(ctx context.Context, id string) error {
 	}
 
 	for _, imageID := range imageIDs {
-		err := deleteImage(imageID, a.pathToImages)
+		err := deleteImage(ctx, a.blob, imageID)
+		if err != nil {
+			a.logger.Error("error deleting image while deleting advert", zap.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// DeleteAnyAdvert lets moderators delete adverts of other users.
+func (a *AdvertService) DeleteAnyAdvert(ctx context.Context, id string) error {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	moderatorID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	imageIDs, err := a.advert.DeleteAnyAdvert(ctx, parsedID.String())
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("advert deleted by moderator",
+		zap.String("advert_id", parsedID.String()),
+		zap.String("moderator_id", moderatorID),
+	)
+
+	for _, imageID := range imageIDs {
+		err := deleteImage(ctx, a.blob, imageID)
 		if err != nil {
 			a.logger.Error("error deleting image while deleting advert", zap.String("error", err.Error()))
 		}
@@ -117,5 +389,360 @@ func (a *AdvertService) GetAdvertByID(ctx context.Context, id string) (models.Ad
 	if err != nil {
 		return models.Advert{}, custom_error.CustomError{Field: "id", Message: err.Error()}
 	}
+
+	advert, err := a.advert.GetAdvertByID(ctx, parsedID.String())
+	if err != nil {
+		return models.Advert{}, err
+	}
+
+	// images which are still processing or have failed are seen only by those who can fix or review them
+	if !advertVisible(ctx, advert) {
+		return models.Advert{}, custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()}
+	}
+
+	// anonymous requests have no favourites
+	if userID, err := getUserID(ctx); err == nil {
+		favourited, err := a.favourite.IsFavourite(ctx, userID, advert.ID)
+		if err != nil {
+			return models.Advert{}, err
+		}
+		advert.Favourited = &favourited
+	}
+
+	return advert, nil
+}
+
+// ChangeAdvertStatus lets the owner submit an advert for review, keep it as a draft, archive it or mark it as sold.
+func (a *AdvertService) ChangeAdvertStatus(ctx context.Context, id, status string) error {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	from, ok := ownerStatusTransitions[status]
+	if !ok {
+		return custom_error.CustomError{Field: "status", Message: ErrAdvertServiceInvalidStatus.Error()}
+	}
+
+	return a.advert.UpdateAdvertStatus(ctx, models.AdvertStatusChange{
+		ID:        parsedID.String(),
+		UserID:    userID,
+		From:      from,
+		To:        status,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// ListModerationQueue returns adverts waiting for review, the oldest first.
+func (a *AdvertService) ListModerationQueue(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	params.Status = models.AdvertStatusPendingReview
+	if params.Order == "" {
+		params.Order = models.SortOrderAsc
+	}
+	return a.listAdverts(ctx, params)
+}
+
+func (a *AdvertService) ApproveAdvert(ctx context.Context, id string) error {
+	return a.moderateAdvert(ctx, id, models.AdvertStatusPublished, "")
+}
+
+func (a *AdvertService) RejectAdvert(ctx context.Context, id, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return custom_error.CustomError{Field: "reason", Message: ErrAdvertServiceEmptyReason.Error()}
+	}
+	return a.moderateAdvert(ctx, id, models.AdvertStatusRejected, reason)
+}
+
+// FindDuplicateAdverts lists other adverts with pictures similar to the pictures of the advert,
+// e.g. stolen photos reused by scammers.
+func (a *AdvertService) FindDuplicateAdverts(ctx context.Context, id string) ([]models.DuplicateAdvert, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	return a.advert.FindDuplicateAdverts(ctx, parsedID.String(), a.duplicateMaxDistance, duplicateAdvertsLimit)
+}
+
+// uploadedImages reads complete chunked uploads of the user as images, the ids are normalized in place.
+func (a *AdvertService) uploadedImages(ctx context.Context, userID string, uploadIDs []string) ([]*models.Image, error) {
+	var images []*models.Image
+
+	for i, id := range uploadIDs {
+		parsedID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, custom_error.CustomError{Field: "upload_ids", Message: err.Error()}
+		}
+		uploadIDs[i] = parsedID.String()
+
+		upload, err := a.upload.GetUpload(ctx, uploadIDs[i], userID)
+		if err != nil {
+			return nil, err
+		}
+		if upload.Offset < upload.Length {
+			return nil, custom_error.CustomError{Field: "upload_ids", Message: ErrUploadServiceIncomplete.Error()}
+		}
+
+		data, err := readUpload(ctx, a.blob, upload)
+		if err != nil {
+			return nil, err
+		}
+
+		// like a file of a multipart form, the format is detected by the content
+		_, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, custom_error.CustomError{Field: "upload_ids", Message: ErrImageServiceCannotDecode.Error()}
+		}
+
+		images = append(images, &models.Image{Data: data, Format: format})
+	}
+
+	return images, nil
+}
+
+// removeUploads deletes uploads attached to an advert, their content is stored as images now.
+func (a *AdvertService) removeUploads(ctx context.Context, userID string, uploadIDs []string) {
+	for _, id := range uploadIDs {
+		if err := a.upload.DeleteUpload(ctx, id, userID); err != nil {
+			a.logger.Error("error deleting attached upload", zap.String("upload_id", id), zap.String("error", err.Error()))
+		}
+		if err := a.blob.Delete(ctx, uploadKey(id)); err != nil {
+			a.logger.Error("error deleting attached upload", zap.String("upload_id", id), zap.String("error", err.Error()))
+		}
+	}
+}
+
+func (a *AdvertService) moderateAdvert(ctx context.Context, id, status, reason string) error {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	moderatorID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	change := models.AdvertStatusChange{
+		ID:        parsedID.String(),
+		From:      []string{models.AdvertStatusPendingReview},
+		To:        status,
+		Reason:    reason,
+		UpdatedAt: now,
+	}
+	// the publication period starts when the advert is approved, not when it is created
+	if status == models.AdvertStatusPublished {
+		expiresAt := now.Add(a.advertTTL)
+		change.ExpiresAt = &expiresAt
+	}
+
+	err = a.advert.UpdateAdvertStatus(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("advert moderated",
+		zap.String("advert_id", parsedID.String()),
+		zap.String("status", status),
+		zap.String("moderator_id", moderatorID),
+	)
+
+	return nil
+}
+
+// RenewAdvert extends the publication period of an owner's advert, an expired advert is published again.
+func (a *AdvertService) RenewAdvert(ctx context.Context, id string) (models.Advert, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Advert{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Advert{}, err
+	}
+
+	now := time.Now()
+	err = a.advert.RenewAdvert(ctx, parsedID.String(), userID, now.Add(a.advertTTL), now)
+	if err != nil {
+		return models.Advert{}, err
+	}
+
 	return a.advert.GetAdvertByID(ctx, parsedID.String())
 }
+
+// ArchiveExpiredAdverts archives expired adverts batch by batch and notifies their owners.
+// It returns the number of archived adverts.
+func (a *AdvertService) ArchiveExpiredAdverts(ctx context.Context) (int, error) {
+	var archived int
+
+	for {
+		adverts, err := a.advert.ArchiveExpiredAdverts(ctx, time.Now(), a.expiryBatchSize)
+		if err != nil {
+			return archived, err
+		}
+		archived += len(adverts)
+
+		for _, advert := range adverts {
+			if err := a.sendAdvertExpiredEmail(ctx, advert); err != nil {
+				a.logger.Error("error sending advert expired email",
+					zap.String("advert_id", advert.ID),
+					zap.String("user_id", advert.UserID),
+					zap.String("error", err.Error()),
+				)
+			}
+		}
+
+		if len(adverts) < a.expiryBatchSize {
+			return archived, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return archived, err
+		}
+	}
+}
+
+func (a *AdvertService) sendAdvertExpiredEmail(ctx context.Context, advert models.ExpiredAdvert) error {
+	ctx, cancel := context.WithTimeout(ctx, sendEmailTimeout)
+	defer cancel()
+
+	link := a.publicURL + "/api/v1/adverts/" + advert.ID
+
+	return a.mailer.Send(ctx, mailer.Message{
+		To:      advert.UserEmail,
+		Subject: "Срок публикации объявления истек",
+		Body: "Здравствуйте!\n\n" +
+			"Срок публикации объявления «" + advert.Title + "» истек, и оно перенесено в архив:\n" +
+			link + "\n\n" +
+			"Чтобы снова опубликовать объявление, продлите его.",
+	})
+}
+
+// ListAdverts is the public feed, it never shows adverts that are not published.
+func (a *AdvertService) ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	params.Status = models.AdvertStatusPublished
+	return a.listAdverts(ctx, params)
+}
+
+func (a *AdvertService) listAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	if params.Limit == 0 {
+		params.Limit = defaultAdvertsLimit
+	}
+	if params.Limit < 0 || params.Limit > maxAdvertsLimit {
+		return nil, "", custom_error.CustomError{Field: "limit", Message: ErrAdvertServiceInvalidLimit.Error()}
+	}
+
+	if params.SortBy == "" {
+		params.SortBy = models.AdvertSortByDate
+	}
+	if params.SortBy != models.AdvertSortByDate && params.SortBy != models.AdvertSortByPrice {
+		return nil, "", custom_error.CustomError{Field: "sort", Message: ErrAdvertServiceInvalidSort.Error()}
+	}
+
+	if params.Order == "" {
+		params.Order = models.SortOrderDesc
+	}
+	if params.Order != models.SortOrderAsc && params.Order != models.SortOrderDesc {
+		return nil, "", custom_error.CustomError{Field: "order", Message: ErrAdvertServiceInvalidOrder.Error()}
+	}
+
+	if params.MinPrice != nil && params.MaxPrice != nil && params.MinPrice.GreaterThan(*params.MaxPrice) {
+		return nil, "", custom_error.CustomError{Field: "min_price", Message: ErrAdvertServicePriceRange.Error()}
+	}
+
+	if params.CreatedFrom != nil && params.CreatedTo != nil && params.CreatedFrom.After(*params.CreatedTo) {
+		return nil, "", custom_error.CustomError{Field: "created_from", Message: ErrAdvertServiceDateRange.Error()}
+	}
+
+	if params.UserID != "" {
+		parsedUserID, err := uuid.Parse(params.UserID)
+		if err != nil {
+			return nil, "", custom_error.CustomError{Field: "user_id", Message: err.Error()}
+		}
+		params.UserID = parsedUserID.String()
+	}
+
+	if params.CategoryID != "" {
+		parsedCategoryID, err := uuid.Parse(params.CategoryID)
+		if err != nil {
+			return nil, "", custom_error.CustomError{Field: "category_id", Message: err.Error()}
+		}
+		params.CategoryID = parsedCategoryID.String()
+	}
+
+	if params.Cursor != "" {
+		after, err := decodeAdvertCursor(params.Cursor, params.SortBy, params.Order)
+		if err != nil {
+			return nil, "", custom_error.CustomError{Field: "cursor", Message: ErrAdvertServiceInvalidCursor.Error()}
+		}
+		params.After = &after
+	}
+
+	// one extra advert tells whether there is a next page
+	limit := params.Limit
+	params.Limit++
+
+	adverts, err := a.advert.ListAdverts(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(adverts) <= limit {
+		return adverts, "", nil
+	}
+
+	adverts = adverts[:limit]
+	last := adverts[limit-1]
+
+	nextCursor, err := encodeAdvertCursor(models.AdvertCursor{
+		Price:     last.Price,
+		CreatedAt: last.CreatedAt,
+		ID:        last.ID,
+	}, params.SortBy, params.Order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return adverts, nextCursor, nil
+}
+
+func (a *AdvertService) SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return nil, custom_error.CustomError{Field: "q", Message: ErrAdvertServiceEmptyQuery.Error()}
+	}
+	if utf8.RuneCountInString(params.Query) > 256 {
+		return nil, custom_error.CustomError{Field: "q", Message: ErrAdvertServiceLongQuery.Error()}
+	}
+
+	if params.Limit == 0 {
+		params.Limit = defaultAdvertsLimit
+	}
+	if params.Limit < 0 || params.Limit > maxAdvertsLimit {
+		return nil, custom_error.CustomError{Field: "limit", Message: ErrAdvertServiceInvalidLimit.Error()}
+	}
+
+	if params.Offset < 0 {
+		return nil, custom_error.CustomError{Field: "offset", Message: ErrAdvertServiceInvalidOffset.Error()}
+	}
+
+	return a.advert.SearchAdverts(ctx, params)
+}
+
+func validateImagesCount(count int) error {
+	if count == 0 {
+		return custom_error.CustomError{Field: "images", Message: ErrAdvertServiceNoImages.Error()}
+	}
+	if count > 7 {
+		return custom_error.CustomError{Field: "images", Message: ErrAdvertServiceManyImages.Error()}
+	}
+	return nil
+}
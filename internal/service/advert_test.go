@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"github.com/romandnk/advertisement/internal/blob"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// testAdvertStorage returns the same advert for any id and records the last update.
+type testAdvertStorage struct {
+	storage.AdvertStorage
+	advert  models.Advert
+	updated *models.AdvertUpdate
+}
+
+func (s testAdvertStorage) GetAdvertByID(ctx context.Context, id string) (models.Advert, error) {
+	return s.advert, nil
+}
+
+func (s testAdvertStorage) UpdateAdvert(ctx context.Context, update models.AdvertUpdate) error {
+	if s.updated != nil {
+		*s.updated = update
+	}
+	return nil
+}
+
+func TestAdvertServiceGetAdvertByIDImagesNotReady(t *testing.T) {
+	advert := models.Advert{
+		ID:     "3c1fa3d6-d3d7-4e37-8d5c-2bd3cbd1ad5a",
+		UserID: "owner id",
+		Status: models.AdvertStatusPublished,
+		Images: []*models.Image{
+			{ID: "image id 1", Status: models.ImageStatusReady},
+			{ID: "image id 2", Status: models.ImageStatusProcessing},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		ctx         context.Context
+		expectedErr error
+		favourited  bool
+	}{
+		{
+			name:        "anonymous",
+			ctx:         context.Background(),
+			expectedErr: custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()},
+		},
+		{
+			name:       "owner",
+			ctx:        context.WithValue(context.Background(), "user_id", "owner id"),
+			favourited: true,
+		},
+		{
+			name: "moderator",
+			ctx:  context.WithValue(context.Background(), "role", models.RoleModerator),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			favourites := testFavouriteStorage{favourites: map[string]time.Time{"owner id/" + advert.ID: time.Now()}}
+			service := NewAdvertService(testAdvertStorage{advert: advert}, nil, nil, nil, favourites, nil, nil, nil, nil, 100, 2, "", 0, 0)
+
+			result, err := service.GetAdvertByID(tc.ctx, advert.ID)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, advert.ID, result.ID)
+
+			// only a request with a user tells whether the advert is favourite
+			if _, ok := tc.ctx.Value("user_id").(string); ok {
+				require.NotNil(t, result.Favourited)
+				require.Equal(t, tc.favourited, *result.Favourited)
+			} else {
+				require.Nil(t, result.Favourited)
+			}
+		})
+	}
+}
+
+func TestAdvertServiceUpdateAdvertContentChanged(t *testing.T) {
+	advert := models.Advert{
+		ID:          "3c1fa3d6-d3d7-4e37-8d5c-2bd3cbd1ad5a",
+		Title:       "title",
+		Description: "description",
+		Price:       decimal.New(100, 0),
+		UserID:      "owner id",
+		Status:      models.AdvertStatusPublished,
+		Images: []*models.Image{
+			{ID: "image id 1", Status: models.ImageStatusReady},
+			{ID: "image id 2", Status: models.ImageStatusReady},
+		},
+	}
+
+	sameTitle := "title"
+	newTitle := "new title"
+	samePrice := decimal.New(100, 0)
+	newPrice := decimal.New(200, 0)
+
+	testCases := []struct {
+		name     string
+		update   models.AdvertUpdate
+		expected bool
+	}{
+		{
+			name:   "same values",
+			update: models.AdvertUpdate{Title: &sameTitle, Price: &samePrice},
+		},
+		{
+			name:     "new title",
+			update:   models.AdvertUpdate{Title: &newTitle},
+			expected: true,
+		},
+		{
+			name:     "new price",
+			update:   models.AdvertUpdate{Price: &newPrice},
+			expected: true,
+		},
+		{
+			name:     "removed image",
+			update:   models.AdvertUpdate{RemoveImageIDs: []string{"image id 2"}},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var updated models.AdvertUpdate
+			advertStorage := testAdvertStorage{advert: advert, updated: &updated}
+			service := NewAdvertService(advertStorage, nil, nil, nil, nil, nil, nil, blob.NewFileSystemStore(t.TempDir()), nil, 100, 2, "", 0, 0)
+
+			tc.update.ID = advert.ID
+			ctx := context.WithValue(context.Background(), "user_id", "owner id")
+
+			_, err := service.UpdateAdvert(ctx, tc.update)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, updated.ContentChanged)
+		})
+	}
+}
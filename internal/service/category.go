@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrCategoryServiceEmptyName      = errors.New("empty name")
+	ErrCategoryServiceLongName       = errors.New("max name length is 100")
+	ErrCategoryServiceCycle          = errors.New("category cannot be moved into itself or its subcategory")
+	ErrCategoryServiceNotLeaf        = errors.New("category has subcategories, choose one of them")
+	ErrCategoryServiceNoCategory     = errors.New("no category")
+	ErrCategoryServiceNotFound       = errors.New("category not found")
+	ErrCategoryServiceParentNotFound = errors.New("parent category not found")
+	ErrCategoryServiceParentInUse    = errors.New("parent category has adverts, only categories without adverts can have subcategories")
+)
+
+type CategoryService struct {
+	category storage.CategoryStorage
+	logger   logger.Logger
+}
+
+func NewCategoryService(category storage.CategoryStorage, logger logger.Logger) *CategoryService {
+	return &CategoryService{
+		category: category,
+		logger:   logger,
+	}
+}
+
+func (c *CategoryService) CreateCategory(ctx context.Context, category models.Category) (string, error) {
+	category.ID = uuid.New().String()
+
+	if err := c.validateCategory(ctx, &category); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	return c.category.CreateCategory(ctx, category)
+}
+
+func (c *CategoryService) GetCategoryByID(ctx context.Context, id string) (models.Category, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Category{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+	return c.category.GetCategoryByID(ctx, parsedID.String())
+}
+
+// ListCategories returns the root categories with all their descendants attached as children.
+func (c *CategoryService) ListCategories(ctx context.Context) ([]*models.Category, error) {
+	categories, err := c.category.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+
+	roots := make([]*models.Category, 0)
+	for i := range categories {
+		category := &categories[i]
+		parent, ok := byID[category.ParentID]
+		if !ok {
+			roots = append(roots, category)
+			continue
+		}
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots, nil
+}
+
+func (c *CategoryService) UpdateCategory(ctx context.Context, category models.Category) error {
+	parsedID, err := uuid.Parse(category.ID)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+	category.ID = parsedID.String()
+
+	if err := c.validateCategory(ctx, &category); err != nil {
+		return err
+	}
+
+	if category.ParentID != "" {
+		categories, err := c.category.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+
+		parents := make(map[string]string, len(categories))
+		for _, existing := range categories {
+			parents[existing.ID] = existing.ParentID
+		}
+
+		for id := category.ParentID; id != ""; id = parents[id] {
+			if id == category.ID {
+				return custom_error.CustomError{Field: "parent_id", Message: ErrCategoryServiceCycle.Error()}
+			}
+		}
+	}
+
+	category.UpdatedAt = time.Now()
+
+	return c.category.UpdateCategory(ctx, category)
+}
+
+func (c *CategoryService) DeleteCategory(ctx context.Context, id string) error {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+	return c.category.DeleteCategory(ctx, parsedID.String())
+}
+
+func (c *CategoryService) validateCategory(ctx context.Context, category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return custom_error.CustomError{Field: "name", Message: ErrCategoryServiceEmptyName.Error()}
+	}
+	if utf8.RuneCountInString(category.Name) > 100 {
+		return custom_error.CustomError{Field: "name", Message: ErrCategoryServiceLongName.Error()}
+	}
+
+	if category.ParentID == "" {
+		return nil
+	}
+
+	parsedParentID, err := uuid.Parse(category.ParentID)
+	if err != nil {
+		return custom_error.CustomError{Field: "parent_id", Message: err.Error()}
+	}
+	category.ParentID = parsedParentID.String()
+
+	if _, err := c.category.GetCategoryByID(ctx, category.ParentID); err != nil {
+		var customError custom_error.CustomError
+		if errors.As(err, &customError) {
+			return custom_error.CustomError{Field: "parent_id", Message: ErrCategoryServiceParentNotFound.Error()}
+		}
+		return err
+	}
+
+	// adverts are placed only into leaf categories, a subcategory would leave them in the parent
+	hasAdverts, err := c.category.CategoryHasAdverts(ctx, category.ParentID)
+	if err != nil {
+		return err
+	}
+	if hasAdverts {
+		return custom_error.CustomError{Field: "parent_id", Message: ErrCategoryServiceParentInUse.Error()}
+	}
+
+	return nil
+}
+
+// validateLeafCategory checks that adverts are placed only into the most specific categories.
+func validateLeafCategory(ctx context.Context, categories storage.CategoryStorage, id string) (string, error) {
+	if id == "" {
+		return "", custom_error.CustomError{Field: "category_id", Message: ErrCategoryServiceNoCategory.Error()}
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return "", custom_error.CustomError{Field: "category_id", Message: err.Error()}
+	}
+
+	category, err := categories.GetCategoryByID(ctx, parsedID.String())
+	if err != nil {
+		var customError custom_error.CustomError
+		if errors.As(err, &customError) {
+			return "", custom_error.CustomError{Field: "category_id", Message: ErrCategoryServiceNotFound.Error()}
+		}
+		return "", err
+	}
+
+	if len(category.Children) > 0 {
+		return "", custom_error.CustomError{Field: "category_id", Message: ErrCategoryServiceNotLeaf.Error()}
+	}
+
+	return category.ID, nil
+}
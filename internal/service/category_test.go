@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testCategoryStorage keeps categories in memory, withAdverts holds the ids of categories having adverts.
+type testCategoryStorage struct {
+	storage.CategoryStorage
+	categories  map[string]models.Category
+	withAdverts map[string]bool
+	saved       map[string]models.Category
+}
+
+func (s testCategoryStorage) GetCategoryByID(ctx context.Context, id string) (models.Category, error) {
+	category, ok := s.categories[id]
+	if !ok {
+		return models.Category{}, custom_error.CustomError{Field: "id", Message: "category not found"}
+	}
+	return category, nil
+}
+
+func (s testCategoryStorage) ListCategories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	for _, category := range s.categories {
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (s testCategoryStorage) CategoryHasAdverts(ctx context.Context, id string) (bool, error) {
+	return s.withAdverts[id], nil
+}
+
+func (s testCategoryStorage) CreateCategory(ctx context.Context, category models.Category) (string, error) {
+	s.saved[category.ID] = category
+	return category.ID, nil
+}
+
+func (s testCategoryStorage) UpdateCategory(ctx context.Context, category models.Category) error {
+	s.saved[category.ID] = category
+	return nil
+}
+
+func TestCategoryServiceParentWithAdverts(t *testing.T) {
+	empty := models.Category{ID: uuid.New().String(), Name: "Транспорт"}
+	used := models.Category{ID: uuid.New().String(), Name: "Велосипеды"}
+	moved := models.Category{ID: uuid.New().String(), Name: "Самокаты"}
+
+	newStorage := func() testCategoryStorage {
+		return testCategoryStorage{
+			categories:  map[string]models.Category{empty.ID: empty, used.ID: used, moved.ID: moved},
+			withAdverts: map[string]bool{used.ID: true},
+			saved:       make(map[string]models.Category),
+		}
+	}
+
+	parentInUse := custom_error.CustomError{Field: "parent_id", Message: ErrCategoryServiceParentInUse.Error()}
+
+	testCases := []struct {
+		name        string
+		parentID    string
+		expectedErr error
+	}{
+		{name: "parent without adverts", parentID: empty.ID},
+		{name: "parent with adverts", parentID: used.ID, expectedErr: parentInUse},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run("create/"+tc.name, func(t *testing.T) {
+			categories := newStorage()
+			service := NewCategoryService(categories, nil)
+
+			id, err := service.CreateCategory(context.Background(), models.Category{Name: "Детские", ParentID: tc.parentID})
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				require.Equal(t, tc.parentID, categories.saved[id].ParentID)
+			} else {
+				require.Empty(t, categories.saved)
+			}
+		})
+
+		t.Run("move/"+tc.name, func(t *testing.T) {
+			categories := newStorage()
+			service := NewCategoryService(categories, nil)
+
+			err := service.UpdateCategory(context.Background(), models.Category{ID: moved.ID, Name: moved.Name, ParentID: tc.parentID})
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				require.Equal(t, tc.parentID, categories.saved[moved.ID].ParentID)
+			} else {
+				require.Empty(t, categories.saved)
+			}
+		})
+	}
+}
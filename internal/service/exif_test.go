@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"testing"
+)
+
+// newTestEXIF builds a TIFF structured EXIF payload with the orientation tag
+// followed by a fake GPS string, as phones put coordinates next to it.
+func newTestEXIF(order binary.ByteOrder, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("Exif\x00\x00")
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	_ = binary.Write(buf, order, uint16(42))
+	_ = binary.Write(buf, order, uint32(8))
+	_ = binary.Write(buf, order, uint16(1))
+	_ = binary.Write(buf, order, uint16(exifOrientationTag))
+	_ = binary.Write(buf, order, uint16(3))
+	_ = binary.Write(buf, order, uint32(1))
+	_ = binary.Write(buf, order, orientation)
+	_ = binary.Write(buf, order, uint16(0))
+	_ = binary.Write(buf, order, uint32(0))
+	buf.WriteString("GPS 55.7558 37.6173")
+	return buf.Bytes()
+}
+
+func newTestJPEGWithEXIF(t *testing.T, width, height int, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	require.NoError(t, jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	data := buf.Bytes()
+
+	exif := newTestEXIF(binary.BigEndian, orientation)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func newTestPNGWithEXIF(t *testing.T, width, height int, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	data := buf.Bytes()
+
+	exif := bytes.TrimPrefix(newTestEXIF(binary.LittleEndian, orientation), []byte("Exif\x00\x00"))
+	chunk := make([]byte, 4, 12+len(exif))
+	binary.BigEndian.PutUint32(chunk, uint32(len(exif)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, exif...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	// the eXIf chunk goes right after the IHDR chunk: 8 bytes of signature and 25 of IHDR
+	result := append([]byte{}, data[:33]...)
+	result = append(result, chunk...)
+	return append(result, data[33:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	require.Equal(t, 6, exifOrientation(newTestEXIF(binary.LittleEndian, 6)))
+	require.Equal(t, 8, exifOrientation(newTestEXIF(binary.BigEndian, 8)))
+	require.Equal(t, 1, exifOrientation(newTestEXIF(binary.BigEndian, 9)))
+	require.Equal(t, 1, exifOrientation([]byte("Exif\x00\x00II")))
+	require.Equal(t, 1, exifOrientation(nil))
+}
+
+func TestOrientImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	marked := color.RGBA{R: 255, A: 255}
+	src.Set(0, 0, marked)
+
+	testCases := []struct {
+		orientation    int
+		expectedBounds image.Rectangle
+		expectedX      int
+		expectedY      int
+	}{
+		{orientation: 1, expectedBounds: image.Rect(0, 0, 3, 2), expectedX: 0, expectedY: 0},
+		{orientation: 2, expectedBounds: image.Rect(0, 0, 3, 2), expectedX: 2, expectedY: 0},
+		{orientation: 3, expectedBounds: image.Rect(0, 0, 3, 2), expectedX: 2, expectedY: 1},
+		{orientation: 4, expectedBounds: image.Rect(0, 0, 3, 2), expectedX: 0, expectedY: 1},
+		{orientation: 5, expectedBounds: image.Rect(0, 0, 2, 3), expectedX: 0, expectedY: 0},
+		{orientation: 6, expectedBounds: image.Rect(0, 0, 2, 3), expectedX: 1, expectedY: 0},
+		{orientation: 7, expectedBounds: image.Rect(0, 0, 2, 3), expectedX: 1, expectedY: 2},
+		{orientation: 8, expectedBounds: image.Rect(0, 0, 2, 3), expectedX: 0, expectedY: 2},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(strconv.Itoa(tc.orientation), func(t *testing.T) {
+			dst := orientImage(src, tc.orientation)
+			require.Equal(t, tc.expectedBounds, dst.Bounds())
+			require.Equal(t, marked, color.RGBAModel.Convert(dst.At(tc.expectedX, tc.expectedY)))
+		})
+	}
+}
+
+func TestPrepareImage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		data           []byte
+		format         string
+		expectedFormat string
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{
+			name:           "jpeg rotated",
+			data:           newTestJPEGWithEXIF(t, 4, 2, 6),
+			format:         models.ImageFormatJPEG,
+			expectedFormat: models.ImageFormatJPEG,
+			expectedWidth:  2,
+			expectedHeight: 4,
+		},
+		{
+			name:           "jpeg upright",
+			data:           newTestJPEGWithEXIF(t, 4, 2, 1),
+			format:         models.ImageFormatJPEG,
+			expectedFormat: models.ImageFormatJPEG,
+			expectedWidth:  4,
+			expectedHeight: 2,
+		},
+		{
+			name:           "png rotated",
+			data:           newTestPNGWithEXIF(t, 4, 2, 8),
+			format:         models.ImageFormatPNG,
+			expectedFormat: models.ImageFormatPNG,
+			expectedWidth:  2,
+			expectedHeight: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			img := &models.Image{Data: tc.data, Format: tc.format}
+
+			err := prepareImage("images", img, 100)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expectedFormat, img.Format)
+			require.NotContains(t, string(img.Data), "GPS")
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedFormat, format)
+			require.Equal(t, tc.expectedWidth, cfg.Width)
+			require.Equal(t, tc.expectedHeight, cfg.Height)
+		})
+	}
+}
+
+func TestPrepareImageTooManyPixels(t *testing.T) {
+	img := &models.Image{Data: newTestImageData(t, 10, 10), Format: models.ImageFormatPNG}
+
+	err := prepareImage("images", img, 99)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "images", Message: ErrImageServiceTooManyPixels.Error()})
+}
+
+func TestPrepareImageCannotDecode(t *testing.T) {
+	img := &models.Image{Data: []byte("not an image"), Format: models.ImageFormatPNG}
+
+	err := prepareImage("avatar", img, 100)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "avatar", Message: ErrImageServiceCannotDecode.Error()})
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(payload)))
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+
+	vp8x := chunk("VP8X", []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 1, 0, 0, 1, 0, 0})
+	vp8 := chunk("VP8 ", []byte("image"))
+	body := append([]byte("WEBP"), vp8x...)
+	body = append(body, vp8...)
+	body = append(body, chunk("EXIF", newTestEXIF(binary.LittleEndian, 3))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+
+	data := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(body)))
+	data = append(data, body...)
+
+	stripped, orientation, err := stripWebPMetadata(data)
+	require.NoError(t, err)
+	require.Equal(t, 3, orientation)
+
+	expectedVP8X := append([]byte{}, vp8x...)
+	expectedVP8X[8] = 0x10
+	expected := append([]byte("RIFF"), 0, 0, 0, 0)
+	expected = append(expected, "WEBP"...)
+	expected = append(expected, expectedVP8X...)
+	expected = append(expected, vp8...)
+	binary.LittleEndian.PutUint32(expected[4:], uint32(len(expected)-8))
+
+	require.Equal(t, expected, stripped)
+
+	_, _, err = stripWebPMetadata([]byte("RIFF\x00\x00\x00\x00WEBPVP8"))
+	require.ErrorIs(t, err, errInvalidWebP)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"time"
+)
+
+// favouritesSortBy tags cursors of favourites, they are ordered by the time the advert was added.
+const favouritesSortBy = "favourited_at"
+
+// favouriteStatuses are shown in favourites, a buyer keeps seeing an advert which was sold or archived.
+var favouriteStatuses = []string{models.AdvertStatusPublished, models.AdvertStatusArchived, models.AdvertStatusSold}
+
+type FavouriteService struct {
+	favourite storage.FavouriteStorage
+	advert    storage.AdvertStorage
+	logger    logger.Logger
+}
+
+func NewFavouriteService(favourite storage.FavouriteStorage, advert storage.AdvertStorage, logger logger.Logger) *FavouriteService {
+	return &FavouriteService{
+		favourite: favourite,
+		advert:    advert,
+		logger:    logger,
+	}
+}
+
+// AddFavourite adds an advert the caller can see to the favourites of the caller.
+func (f *FavouriteService) AddFavourite(ctx context.Context, advertID string) error {
+	parsedID, err := uuid.Parse(advertID)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	advert, err := f.advert.GetAdvertByID(ctx, parsedID.String())
+	if err != nil {
+		return err
+	}
+	if !advertVisible(ctx, advert) {
+		return custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()}
+	}
+
+	return f.favourite.AddFavourite(ctx, models.Favourite{
+		UserID:    userID,
+		AdvertID:  advert.ID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (f *FavouriteService) RemoveFavourite(ctx context.Context, advertID string) error {
+	parsedID, err := uuid.Parse(advertID)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return f.favourite.RemoveFavourite(ctx, userID, parsedID.String())
+}
+
+// ListFavourites returns a page of favourite adverts of the caller, the most recently added first,
+// and the cursor of the next page, which is empty on the last page.
+func (f *FavouriteService) ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, string, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	params.UserID = userID
+	params.Statuses = favouriteStatuses
+
+	after, err := parsePage(&params.Limit, params.Cursor, favouritesSortBy)
+	if err != nil {
+		return nil, "", err
+	}
+	params.After = after
+
+	// one extra advert tells whether there is a next page
+	limit := params.Limit
+	params.Limit++
+
+	favourites, err := f.favourite.ListFavourites(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(favourites) <= limit {
+		return favourites, "", nil
+	}
+
+	favourites = favourites[:limit]
+	last := favourites[limit-1]
+
+	nextCursor, err := encodeAdvertCursor(models.AdvertCursor{
+		CreatedAt: last.FavouritedAt,
+		ID:        last.Advert.ID,
+	}, favouritesSortBy, models.SortOrderDesc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return favourites, nextCursor, nil
+}
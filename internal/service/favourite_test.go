@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testFavouriteStorage keeps the time an advert was added by "<user id>/<advert id>".
+type testFavouriteStorage struct {
+	storage.FavouriteStorage
+	favourites map[string]time.Time
+}
+
+func (s testFavouriteStorage) AddFavourite(ctx context.Context, favourite models.Favourite) error {
+	key := favourite.UserID + "/" + favourite.AdvertID
+	if _, ok := s.favourites[key]; !ok {
+		s.favourites[key] = favourite.CreatedAt
+	}
+	return nil
+}
+
+func (s testFavouriteStorage) IsFavourite(ctx context.Context, userID, advertID string) (bool, error) {
+	_, ok := s.favourites[userID+"/"+advertID]
+	return ok, nil
+}
+
+func (s testFavouriteStorage) ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, error) {
+	var favourites []models.FavouriteAdvert
+	for key, favouritedAt := range s.favourites {
+		userID, advertID := key[:len(params.UserID)], key[len(params.UserID)+1:]
+		if userID != params.UserID {
+			continue
+		}
+		favourites = append(favourites, models.FavouriteAdvert{Advert: models.Advert{ID: advertID}, FavouritedAt: favouritedAt})
+	}
+
+	sort.Slice(favourites, func(i, j int) bool {
+		return favourites[i].FavouritedAt.After(favourites[j].FavouritedAt)
+	})
+
+	var page []models.FavouriteAdvert
+	for _, favourite := range favourites {
+		if params.After != nil && !favourite.FavouritedAt.Before(params.After.CreatedAt) {
+			continue
+		}
+		if len(page) == params.Limit {
+			break
+		}
+		page = append(page, favourite)
+	}
+	return page, nil
+}
+
+func TestFavouriteServiceAddFavouriteHidden(t *testing.T) {
+	advert := models.Advert{
+		ID:     uuid.New().String(),
+		UserID: "owner id",
+		Status: models.AdvertStatusDraft,
+		Images: []*models.Image{{ID: "image id", Status: models.ImageStatusReady}},
+	}
+
+	favourites := testFavouriteStorage{favourites: make(map[string]time.Time)}
+	service := NewFavouriteService(favourites, testAdvertStorage{advert: advert}, nil)
+
+	ctx := context.WithValue(context.Background(), "user_id", "buyer id")
+
+	err := service.AddFavourite(ctx, advert.ID)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()})
+	require.Empty(t, favourites.favourites)
+}
+
+func TestFavouriteServiceListFavourites(t *testing.T) {
+	favourites := testFavouriteStorage{favourites: make(map[string]time.Time)}
+	service := NewFavouriteService(favourites, nil, nil)
+
+	now := time.Now().UTC()
+	var expectedIDs []string
+	for i := 0; i < 3; i++ {
+		id := uuid.New().String()
+		favourites.favourites["buyer id/"+id] = now.Add(-time.Duration(i) * time.Minute)
+		expectedIDs = append(expectedIDs, id)
+	}
+	favourites.favourites["another buyer id/"+uuid.New().String()] = now
+
+	ctx := context.WithValue(context.Background(), "user_id", "buyer id")
+
+	var ids []string
+	params := models.FavouriteListParams{Limit: 2}
+	for {
+		page, nextCursor, err := service.ListFavourites(ctx, params)
+		require.NoError(t, err)
+
+		for _, favourite := range page {
+			ids = append(ids, favourite.Advert.ID)
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		params.Cursor = nextCursor
+	}
+
+	require.Equal(t, expectedIDs, ids)
+
+	_, _, err := service.ListFavourites(ctx, models.FavouriteListParams{Cursor: "broken"})
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "cursor", Message: ErrAdvertServiceInvalidCursor.Error()})
+}
@@ -4,52 +4,458 @@ import (
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/configs"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/logger"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/romandnk/advertisement/internal/storage"
+	"go.uber.org/zap"
+	"time"
 )
 
-var ErrImageServiceImageNotFound = errors.New("image not found")
+var (
+	ErrImageServiceImageNotFound    = errors.New("image not found")
+	ErrImageServiceFormatNotAllowed = errors.New("image format is not allowed")
+	ErrImageServiceInvalidSize      = errors.New("size must be thumb, medium or original")
+	ErrImageServiceCannotDecode     = errors.New("image cannot be decoded")
+	ErrImageServiceTooManyPixels    = errors.New("image has too many pixels")
+	ErrImageServiceBannedImage      = errors.New("image matches an image of a banned user")
+	ErrImageServiceProcessing       = errors.New("image is being processed")
+	ErrImageServiceFailed           = errors.New("image could not be processed")
+	ErrImageServiceUploadNotFound   = errors.New("uploaded image not found")
+	ErrImageServiceTooManyAttempts  = errors.New("too many failed processing attempts")
+)
+
+const (
+	reshardBatchSize  = 100
+	gcBatchSize       = 500
+	imageJobBatchSize = 10
+	purgeBatchSize    = 100
+	// imageJobRetryDelay is the delay after the first failed attempt, it grows with the square of attempts
+	imageJobRetryDelay = 10 * time.Second
+)
 
 type ImageService struct {
 	image        storage.ImageStorage
 	logger       logger.Logger
-	pathToImages string
+	blob         blob.BlobStore
+	imageFormats map[string]struct{}
+	gcMinAge     time.Duration
+	// images uploaded before their advert and not attached within uploadTTL are purged
+	uploadTTL time.Duration
+	// processing of uploaded advert images
+	maxPixels             int
+	duplicateMaxDistance  int
+	blockBannedDuplicates bool
+	jobLease              time.Duration
+	jobMaxAttempts        int
 }
 
-func NewImageService(image storage.ImageStorage, logger logger.Logger, pathToImages string) *ImageService {
+func NewImageService(image storage.ImageStorage, logger logger.Logger, blob blob.BlobStore, imageFormats []string,
+	maxPixels int, imageGC configs.ImageGCConf, imageDuplicates configs.ImageDuplicatesConf, imageJobs configs.ImageJobsConf,
+	uploadPurge configs.UploadPurgeConf) *ImageService {
 	return &ImageService{
-		image:        image,
-		logger:       logger,
-		pathToImages: pathToImages,
+		image:                 image,
+		logger:                logger,
+		blob:                  blob,
+		imageFormats:          newImageFormats(imageFormats),
+		gcMinAge:              imageGC.MinAge,
+		uploadTTL:             uploadPurge.TTL,
+		maxPixels:             maxPixels,
+		duplicateMaxDistance:  imageDuplicates.MaxDistance,
+		blockBannedDuplicates: imageDuplicates.BlockBanned,
+		jobLease:              imageJobs.Lease,
+		jobMaxAttempts:        imageJobs.MaxAttempts,
 	}
 }
 
-func (i *ImageService) GetImageByID(ctx context.Context, id string) (models.Image, error) {
-	parsedID, err := uuid.Parse(id)
+// UploadImage stores an image of the caller before the advert is created. The image is processed
+// in the background like an advert image and is attached to an advert of the caller by its id.
+func (i *ImageService) UploadImage(ctx context.Context, image *models.Image) (models.Image, error) {
+	userID, err := getUserID(ctx)
 	if err != nil {
 		return models.Image{}, err
 	}
 
+	if err := validateImageFormat("image", image, i.imageFormats); err != nil {
+		return models.Image{}, err
+	}
+	if err := checkImagePixels("image", image, i.maxPixels); err != nil {
+		return models.Image{}, err
+	}
+
+	image.ID = uuid.New().String()
+	image.UserID = userID
+	image.Status = models.ImageStatusProcessing
+	image.CreatedAt = time.Now()
+
+	if err := saveImageUpload(ctx, i.blob, image); err != nil {
+		return models.Image{}, custom_error.CustomError{Field: "image", Message: err.Error()}
+	}
+
+	if err := i.image.CreateImage(ctx, *image); err != nil {
+		i.deleteImageUpload(ctx, image.ID)
+		return models.Image{}, err
+	}
+
+	uploaded := *image
+	uploaded.Data = nil
+
+	return uploaded, nil
+}
+
+// GetImageByID opens the original image or one of its resized variants for streaming.
+// Variants missing in the store, e.g. of images uploaded before they were introduced, are made on the first request.
+// If ifNoneMatch lists the image ETag, the file is not opened and NotModified is set.
+func (i *ImageService) GetImageByID(ctx context.Context, id, size, ifNoneMatch string) (models.ImageFile, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.ImageFile{}, err
+	}
+
+	if size == "" {
+		size = models.ImageSizeOriginal
+	}
+	if _, ok := imageVariants[size]; !ok && size != models.ImageSizeOriginal {
+		return models.ImageFile{}, custom_error.CustomError{Field: "size", Message: ErrImageServiceInvalidSize.Error()}
+	}
+
 	image, err := i.image.GetImageByID(ctx, parsedID.String())
 	if err != nil {
-		return image, err
+		return models.ImageFile{}, err
 	}
 
 	if image.Deleted == true {
-		return models.Image{}, custom_error.CustomError{
+		return models.ImageFile{}, custom_error.CustomError{
 			Field:   "id",
 			Message: ErrImageServiceImageNotFound.Error(),
 		}
 	}
 
-	data, err := findImageByID(i.pathToImages, parsedID.String())
+	switch image.Status {
+	case models.ImageStatusProcessing:
+		return models.ImageFile{}, custom_error.CustomError{Field: "id", Message: ErrImageServiceProcessing.Error()}
+	case models.ImageStatusFailed:
+		return models.ImageFile{}, custom_error.CustomError{Field: "id", Message: ErrImageServiceFailed.Error() + ": " + image.StatusReason}
+	}
+
+	format := image.Format
+	if size != models.ImageSizeOriginal {
+		format = variantFormat(image.Format)
+	}
+
+	etag := imageETag(image.ContentHash, size)
+	if etagMatches(ifNoneMatch, etag) {
+		return models.ImageFile{Format: format, ETag: etag, NotModified: true}, nil
+	}
+
+	file, err := openImage(ctx, i.blob, image.ID, size, image.Format)
+	if errors.Is(err, blob.ErrNotFound) && size != models.ImageSizeOriginal {
+		err = i.createImageVariant(ctx, image, size)
+		if err == nil {
+			file, err = openImage(ctx, i.blob, image.ID, size, image.Format)
+		}
+	}
 	if err != nil {
-		return models.Image{}, err
+		if errors.Is(err, blob.ErrNotFound) {
+			return models.ImageFile{}, custom_error.CustomError{Field: "id", Message: ErrImageServiceImageNotFound.Error()}
+		}
+		return models.ImageFile{}, err
+	}
+
+	file.Format = format
+	file.ETag = etag
+
+	return file, nil
+}
+
+func (i *ImageService) createImageVariant(ctx context.Context, image models.Image, size string) error {
+	original, err := findImageByID(ctx, i.blob, image.ID, models.ImageSizeOriginal, image.Format)
+	if err != nil {
+		return err
+	}
+
+	image.Data = original
+	_, err = saveImageVariant(ctx, i.blob, &image, size)
+	if err != nil {
+		return err
+	}
+
+	i.logger.Info("image variant created", zap.String("image_id", image.ID), zap.String("size", size))
+
+	return nil
+}
+
+// ReshardImages moves images saved at the root of the store before sharding into their subdirectories.
+// It is safe to run repeatedly, already moved images are skipped. It returns the number of moved files.
+func (i *ImageService) ReshardImages(ctx context.Context) (int, error) {
+	var (
+		moved   int
+		afterID string
+	)
+
+	for {
+		images, err := i.image.ListImages(ctx, afterID, reshardBatchSize)
+		if err != nil {
+			return moved, err
+		}
+
+		for _, image := range images {
+			n, err := reshardImage(ctx, i.blob, image)
+			moved += n
+			if err != nil {
+				return moved, err
+			}
+		}
+
+		if len(images) < reshardBatchSize {
+			return moved, nil
+		}
+		afterID = images[len(images)-1].ID
+	}
+}
+
+// CollectOrphanedImages reconciles the stored files with the images table and removes files
+// of deleted images and of images that have no row, e.g. left by an advert whose transaction failed,
+// as well as uploads of images which are already processed.
+// Files younger than gcMinAge are skipped, their rows may not be committed yet.
+// With dryRun the orphaned files are only reported.
+func (i *ImageService) CollectOrphanedImages(ctx context.Context, dryRun bool) (models.ImageGCResult, error) {
+	var result models.ImageGCResult
+
+	pending := make(map[string][]string)
+	flush := func() error {
+		err := i.collectOrphanedImages(ctx, pending, dryRun, &result)
+		pending = make(map[string][]string)
+		return err
+	}
+
+	err := i.blob.Walk(ctx, func(info blob.Info) error {
+		id := imageIDFromKey(info.Key)
+		if id == "" || time.Since(info.ModTime) < i.gcMinAge {
+			return nil
+		}
+
+		result.Checked++
+		pending[id] = append(pending[id], info.Key)
+		if len(pending) < gcBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (i *ImageService) collectOrphanedImages(ctx context.Context, keys map[string][]string, dryRun bool, result *models.ImageGCResult) error {
+	if len(keys) == 0 {
+		return nil
 	}
 
-	image.Data = data
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+
+	images, err := i.image.GetImagesByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	alive := make(map[string]models.Image, len(images))
+	for _, image := range images {
+		if !image.Deleted {
+			alive[image.ID] = image
+		}
+	}
+
+	for id, imageKeys := range keys {
+		for _, key := range imageKeys {
+			// the upload is needed only until the image job has processed it
+			if image, ok := alive[id]; ok && (!isImageUploadKey(key) || image.Status == models.ImageStatusProcessing) {
+				continue
+			}
+
+			result.Orphaned++
+			i.logger.Info("orphaned image file", zap.String("key", key), zap.Bool("dry_run", dryRun))
+
+			if dryRun {
+				continue
+			}
+
+			if err := i.blob.Delete(ctx, key); err != nil {
+				return err
+			}
+			result.Removed++
+		}
+	}
+
+	return nil
+}
+
+// PurgeUnattachedImages deletes images uploaded before their advert more than uploadTTL ago and never attached.
+// Files which could not be removed are left to the garbage collector. It returns the number of purged images.
+func (i *ImageService) PurgeUnattachedImages(ctx context.Context) (int, error) {
+	var purged int
+
+	for {
+		ids, err := i.image.DeleteUnattachedImages(ctx, time.Now().Add(-i.uploadTTL), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, id := range ids {
+			if err := deleteImage(ctx, i.blob, id); err != nil {
+				i.logger.Error("error deleting unattached image", zap.String("image_id", id), zap.String("error", err.Error()))
+			}
+		}
+		purged += len(ids)
+
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// ProcessImageJobs processes uploaded advert images batch by batch until no job is due:
+// the metadata is removed, the resized variants are made and the image becomes ready.
+// A job which fails for a reason retrying cannot fix, e.g. an undecodable image,
+// marks the image as failed, other failures are retried later. It returns the number of ready images.
+func (i *ImageService) ProcessImageJobs(ctx context.Context) (int, error) {
+	var processed int
+
+	for {
+		now := time.Now()
+		jobs, err := i.image.ClaimImageJobs(ctx, now, now.Add(i.jobLease), imageJobBatchSize)
+		if err != nil {
+			return processed, err
+		}
+
+		for _, job := range jobs {
+			err := i.processImageJob(ctx, job)
+			if err == nil {
+				processed++
+				continue
+			}
+
+			if err := i.handleImageJobError(ctx, job, err); err != nil {
+				return processed, err
+			}
+		}
+
+		if len(jobs) < imageJobBatchSize {
+			return processed, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return processed, err
+		}
+	}
+}
+
+func (i *ImageService) processImageJob(ctx context.Context, job models.ImageJob) error {
+	data, err := i.blob.Get(ctx, imageUploadKey(job.ImageID))
+	if err != nil {
+		return err
+	}
+
+	image := models.Image{
+		ID:       job.ImageID,
+		AdvertID: job.AdvertID,
+		Format:   job.Format,
+		Data:     data,
+	}
+
+	if err := prepareImage("images", &image, i.maxPixels); err != nil {
+		return err
+	}
+
+	if err := i.rejectBannedUserImage(ctx, image); err != nil {
+		return err
+	}
+
+	if err := saveImage(ctx, i.blob, &image); err != nil {
+		return err
+	}
+
+	if err := i.image.CompleteImageJob(ctx, image); err != nil {
+		return err
+	}
+
+	i.deleteImageUpload(ctx, image.ID)
+
+	return nil
+}
+
+// handleImageJobError fails the image for validation errors, a missing upload and after the last attempt,
+// otherwise the job is retried with a growing delay.
+func (i *ImageService) handleImageJobError(ctx context.Context, job models.ImageJob, jobErr error) error {
+	var reason string
+	var customErr custom_error.CustomError
+
+	switch {
+	case errors.As(jobErr, &customErr):
+		reason = customErr.Message
+	case errors.Is(jobErr, blob.ErrNotFound):
+		reason = ErrImageServiceUploadNotFound.Error()
+	case job.Attempts >= i.jobMaxAttempts:
+		reason = ErrImageServiceTooManyAttempts.Error()
+	default:
+		i.logger.Error("error processing image, retrying",
+			zap.String("image_id", job.ImageID),
+			zap.Int("attempts", job.Attempts),
+			zap.String("error", jobErr.Error()),
+		)
+
+		runAt := time.Now().Add(time.Duration(job.Attempts*job.Attempts) * imageJobRetryDelay)
+		return i.image.RetryImageJob(ctx, job.ImageID, runAt, jobErr.Error())
+	}
+
+	i.logger.Error("image processing failed",
+		zap.String("image_id", job.ImageID),
+		zap.String("advert_id", job.AdvertID),
+		zap.String("error", jobErr.Error()),
+	)
+
+	if err := i.image.FailImageJob(ctx, job.ImageID, reason); err != nil {
+		return err
+	}
+
+	i.deleteImageUpload(ctx, job.ImageID)
+
+	return nil
+}
+
+// deleteImageUpload removes the upload of a processed image, a leftover is removed by the garbage collector
+// since the image is no longer processing.
+func (i *ImageService) deleteImageUpload(ctx context.Context, id string) {
+	if err := i.blob.Delete(ctx, imageUploadKey(id)); err != nil {
+		i.logger.Error("error deleting image upload", zap.String("image_id", id), zap.String("error", err.Error()))
+	}
+}
+
+// rejectBannedUserImage stops reposting pictures of banned users, they are mostly scammers.
+func (i *ImageService) rejectBannedUserImage(ctx context.Context, image models.Image) error {
+	if !i.blockBannedDuplicates {
+		return nil
+	}
+
+	found, err := i.image.HasBannedUserImage(ctx, image.PHash, i.duplicateMaxDistance)
+	if err != nil {
+		return err
+	}
+	if found {
+		i.logger.Info("image of banned user rejected", zap.String("advert_id", image.AdvertID))
+		return custom_error.CustomError{Field: "images", Message: ErrImageServiceBannedImage.Error()}
+	}
 
-	return image, nil
+	return nil
 }
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/configs"
+	"github.com/romandnk/advertisement/internal/blob"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/logger"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testImageStorage keeps image rows and jobs in memory and knows the perceptual hashes of images of banned users.
+// Completing a job of an image listed in unavailable fails as if the database were down.
+type testImageStorage struct {
+	storage.ImageStorage
+	images       map[string]models.Image
+	bannedHashes []int64
+	jobs         []models.ImageJob
+	claimed      map[string]bool
+	unavailable  map[string]bool
+	completed    map[string]models.Image
+	failed       map[string]string
+	retried      map[string]time.Time
+	created      map[string]models.Image
+}
+
+func (s testImageStorage) HasBannedUserImage(ctx context.Context, phash int64, maxDistance int) (bool, error) {
+	for _, banned := range s.bannedHashes {
+		if bits.OnesCount64(uint64(banned^phash)) <= maxDistance {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s testImageStorage) ClaimImageJobs(ctx context.Context, now, lockedUntil time.Time, limit int) ([]models.ImageJob, error) {
+	var jobs []models.ImageJob
+	for _, job := range s.jobs {
+		if len(jobs) == limit {
+			break
+		}
+		if !s.claimed[job.ImageID] {
+			s.claimed[job.ImageID] = true
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s testImageStorage) CompleteImageJob(ctx context.Context, image models.Image) error {
+	if s.unavailable[image.ID] {
+		return errors.New("db is down")
+	}
+	s.completed[image.ID] = image
+	return nil
+}
+
+func (s testImageStorage) RetryImageJob(ctx context.Context, imageID string, runAt time.Time, lastError string) error {
+	s.retried[imageID] = runAt
+	return nil
+}
+
+func (s testImageStorage) FailImageJob(ctx context.Context, imageID, reason string) error {
+	s.failed[imageID] = reason
+	return nil
+}
+
+func (s testImageStorage) CreateImage(ctx context.Context, image models.Image) error {
+	s.created[image.ID] = image
+	return nil
+}
+
+func (s testImageStorage) GetImageByID(ctx context.Context, id string) (models.Image, error) {
+	return s.images[id], nil
+}
+
+func (s testImageStorage) GetImagesByIDs(ctx context.Context, ids []string) ([]models.Image, error) {
+	var images []models.Image
+	for _, id := range ids {
+		if image, ok := s.images[id]; ok {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+// newTestImageService creates an image service which allows images with at most 2 different hash bits
+// to be considered duplicates.
+func newTestImageService(image storage.ImageStorage, logger logger.Logger, store blob.BlobStore, imageFormats []string,
+	maxPixels int, blockBanned bool) *ImageService {
+	return NewImageService(image, logger, store, imageFormats, maxPixels,
+		configs.ImageGCConf{MinAge: time.Hour},
+		configs.ImageDuplicatesConf{MaxDistance: 2, BlockBanned: blockBanned},
+		configs.ImageJobsConf{Lease: time.Minute, MaxAttempts: 3},
+		configs.UploadPurgeConf{TTL: time.Hour})
+}
+
+func TestImageServiceCollectOrphanedImages(t *testing.T) {
+	alive := uuid.New().String()
+	processing := uuid.New().String()
+	deleted := uuid.New().String()
+	unknown := uuid.New().String()
+	recent := uuid.New().String()
+
+	imageStorage := testImageStorage{images: map[string]models.Image{
+		alive:      {ID: alive, Status: models.ImageStatusReady},
+		processing: {ID: processing, Status: models.ImageStatusProcessing},
+		deleted:    {ID: deleted, Deleted: true},
+	}}
+
+	testCases := []struct {
+		name            string
+		dryRun          bool
+		expectedResult  models.ImageGCResult
+		expectedRemoved bool
+	}{
+		{
+			name:           "dry run",
+			dryRun:         true,
+			expectedResult: models.ImageGCResult{Checked: 7, Orphaned: 4, Removed: 0},
+		},
+		{
+			name:            "remove",
+			dryRun:          false,
+			expectedResult:  models.ImageGCResult{Checked: 7, Orphaned: 4, Removed: 4},
+			expectedRemoved: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := mock_logger.NewMockLogger(ctrl)
+			logger.EXPECT().Info("orphaned image file", gomock.Any()).Times(tc.expectedResult.Orphaned)
+
+			dir := t.TempDir()
+			store := blob.NewFileSystemStore(dir)
+
+			keys := map[string]bool{
+				imageKey(alive, models.ImageSizeOriginal, models.ImageFormatJPEG):   false,
+				imageKey(alive, models.ImageSizeThumb, models.ImageFormatJPEG):      false,
+				imageKey(deleted, models.ImageSizeOriginal, models.ImageFormatPNG):  true,
+				imageKey(deleted, models.ImageSizeMedium, models.ImageFormatPNG):    true,
+				imageKey(unknown, models.ImageSizeOriginal, models.ImageFormatWebP): true,
+				// the upload of a processed image is left if deleting it after the job failed
+				imageUploadKey(alive):      true,
+				imageUploadKey(processing): false,
+				"notes.txt":                false,
+			}
+			old := time.Now().Add(-2 * time.Hour)
+			for key := range keys {
+				require.NoError(t, store.Put(context.Background(), key, bytes.NewReader([]byte(key)), int64(len(key)), ""))
+				require.NoError(t, os.Chtimes(filepath.Join(dir, filepath.FromSlash(key)), old, old))
+			}
+
+			// may belong to an advert whose transaction is not committed yet
+			recentKey := imageKey(recent, models.ImageSizeOriginal, models.ImageFormatJPEG)
+			require.NoError(t, store.Put(context.Background(), recentKey, bytes.NewReader([]byte("new")), 3, ""))
+			keys[recentKey] = false
+
+			service := newTestImageService(imageStorage, logger, store, nil, 100, false)
+
+			result, err := service.CollectOrphanedImages(context.Background(), tc.dryRun)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedResult, result)
+
+			for key, orphaned := range keys {
+				_, err := store.Stat(context.Background(), key)
+				if orphaned && tc.expectedRemoved {
+					require.ErrorIs(t, err, blob.ErrNotFound, key)
+				} else {
+					require.NoError(t, err, key)
+				}
+			}
+		})
+	}
+}
+
+func TestImageIDFromKey(t *testing.T) {
+	id := uuid.New().String()
+
+	require.Equal(t, id, imageIDFromKey(imageKey(id, models.ImageSizeOriginal, models.ImageFormatJPEG)))
+	require.Equal(t, id, imageIDFromKey(imageKey(id, models.ImageSizeThumb, models.ImageFormatWebP)))
+	require.Equal(t, id, imageIDFromKey(id+".gif"))
+	require.Equal(t, id, imageIDFromKey(imageUploadKey(id)))
+	require.Empty(t, imageIDFromKey("notes.txt"))
+	require.Empty(t, imageIDFromKey(""))
+}
+
+func TestImageServiceProcessImageJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error("error processing image, retrying", gomock.Any()).Times(1)
+	logger.EXPECT().Error("image processing failed", gomock.Any()).Times(3)
+
+	ready := uuid.New().String()
+	broken := uuid.New().String()
+	missing := uuid.New().String()
+	retried := uuid.New().String()
+	exhausted := uuid.New().String()
+
+	imageStorage := testImageStorage{
+		jobs: []models.ImageJob{
+			{ImageID: ready, AdvertID: "advert id", Format: models.ImageFormatPNG, Attempts: 1},
+			{ImageID: broken, AdvertID: "advert id", Format: models.ImageFormatJPEG, Attempts: 1},
+			{ImageID: missing, AdvertID: "advert id", Format: models.ImageFormatPNG, Attempts: 1},
+			{ImageID: retried, AdvertID: "advert id", Format: models.ImageFormatPNG, Attempts: 2},
+			{ImageID: exhausted, AdvertID: "advert id", Format: models.ImageFormatPNG, Attempts: 3},
+		},
+		claimed:     make(map[string]bool),
+		unavailable: map[string]bool{retried: true, exhausted: true},
+		completed:   make(map[string]models.Image),
+		failed:      make(map[string]string),
+		retried:     make(map[string]time.Time),
+	}
+
+	store := blob.NewFileSystemStore(t.TempDir())
+	data := newTestImageData(t, 10, 10)
+	for _, id := range []string{ready, retried, exhausted} {
+		require.NoError(t, saveImageUpload(context.Background(), store, &models.Image{ID: id, Format: models.ImageFormatPNG, Data: data}))
+	}
+	require.NoError(t, saveImageUpload(context.Background(), store, &models.Image{ID: broken, Format: models.ImageFormatJPEG, Data: []byte("not an image")}))
+
+	service := newTestImageService(imageStorage, logger, store, nil, 1000, false)
+
+	processed, err := service.ProcessImageJobs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+
+	require.Len(t, imageStorage.completed, 1)
+	require.NotEmpty(t, imageStorage.completed[ready].ContentHash)
+	_, err = store.Stat(context.Background(), imageKey(ready, models.ImageSizeThumb, models.ImageFormatPNG))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		broken:    ErrImageServiceCannotDecode.Error(),
+		missing:   ErrImageServiceUploadNotFound.Error(),
+		exhausted: ErrImageServiceTooManyAttempts.Error(),
+	}, imageStorage.failed)
+
+	require.Len(t, imageStorage.retried, 1)
+	require.True(t, imageStorage.retried[retried].After(time.Now().Add(30*time.Second)))
+
+	// only the upload of the image to retry is kept
+	for _, id := range []string{ready, broken, exhausted} {
+		_, err := store.Stat(context.Background(), imageUploadKey(id))
+		require.ErrorIs(t, err, blob.ErrNotFound, id)
+	}
+	_, err = store.Stat(context.Background(), imageUploadKey(retried))
+	require.NoError(t, err)
+}
+
+func TestImageServiceRejectBannedUserImage(t *testing.T) {
+	imageStorage := testImageStorage{bannedHashes: []int64{0b1111}}
+
+	testCases := []struct {
+		name        string
+		block       bool
+		phash       int64
+		expectedErr error
+	}{
+		{
+			name:        "similar",
+			block:       true,
+			phash:       0b0111,
+			expectedErr: custom_error.CustomError{Field: "images", Message: ErrImageServiceBannedImage.Error()},
+		},
+		{
+			name:  "different",
+			block: true,
+			phash: -1,
+		},
+		{
+			name:  "blocking disabled",
+			block: false,
+			phash: 0b1111,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			logger := mock_logger.NewMockLogger(ctrl)
+			if tc.expectedErr != nil {
+				logger.EXPECT().Info("image of banned user rejected", gomock.Any())
+			}
+
+			service := newTestImageService(imageStorage, logger, nil, nil, 100, tc.block)
+
+			err := service.rejectBannedUserImage(context.Background(), models.Image{AdvertID: "advert id", PHash: tc.phash})
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestImageServiceGetImageByIDNotReady(t *testing.T) {
+	processing := uuid.New().String()
+	failed := uuid.New().String()
+
+	imageStorage := testImageStorage{images: map[string]models.Image{
+		processing: {ID: processing, Format: models.ImageFormatPNG, Status: models.ImageStatusProcessing},
+		failed:     {ID: failed, Format: models.ImageFormatPNG, Status: models.ImageStatusFailed, StatusReason: "image cannot be decoded"},
+	}}
+
+	service := newTestImageService(imageStorage, nil, nil, nil, 100, false)
+
+	_, err := service.GetImageByID(context.Background(), processing, "", "")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrImageServiceProcessing.Error()})
+
+	_, err = service.GetImageByID(context.Background(), failed, "", "")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: "image could not be processed: image cannot be decoded"})
+}
+
+func TestImageServiceUploadImage(t *testing.T) {
+	store := blob.NewFileSystemStore(t.TempDir())
+	imageStorage := testImageStorage{created: make(map[string]models.Image)}
+	service := newTestImageService(imageStorage, nil, store, []string{models.ImageFormatPNG}, 100, false)
+
+	ctx := context.WithValue(context.Background(), "user_id", "owner id")
+
+	_, err := service.UploadImage(ctx, &models.Image{Data: newTestImageData(t, 2, 2), Format: models.ImageFormatJPEG})
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "image", Message: ErrImageServiceFormatNotAllowed.Error()})
+
+	data := newTestImageData(t, 2, 2)
+	uploaded, err := service.UploadImage(ctx, &models.Image{Data: data, Format: models.ImageFormatPNG})
+	require.NoError(t, err)
+	require.Equal(t, models.ImageStatusProcessing, uploaded.Status)
+	require.Nil(t, uploaded.Data)
+
+	created := imageStorage.created[uploaded.ID]
+	require.Equal(t, "owner id", created.UserID)
+	require.Empty(t, created.AdvertID)
+
+	// the image waits for its processing job like an advert image
+	stored, err := store.Get(ctx, imageUploadKey(uploaded.ID))
+	require.NoError(t, err)
+	require.Equal(t, data, stored)
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrMessageServiceEmptyBody = errors.New("empty message")
+	ErrMessageServiceLongBody  = errors.New("max message length is 2000")
+	ErrMessageServiceOwnAdvert = errors.New("cannot write about your own advert")
+	ErrMessageServiceBlocked   = errors.New("messages between these users are blocked")
+	ErrMessageServiceBlockSelf = errors.New("cannot block yourself")
+)
+
+const (
+	maxMessageLength = 2000
+	// threadsSortBy and messagesSortBy tag cursors, threads are ordered by the last message, messages by the time they were sent
+	threadsSortBy  = "thread_updated_at"
+	messagesSortBy = "message_created_at"
+)
+
+// MessageService lets a buyer write to the seller of an advert. Every advert has a separate thread for each buyer.
+type MessageService struct {
+	message storage.MessageStorage
+	advert  storage.AdvertStorage
+	logger  logger.Logger
+}
+
+func NewMessageService(message storage.MessageStorage, advert storage.AdvertStorage, logger logger.Logger) *MessageService {
+	return &MessageService{
+		message: message,
+		advert:  advert,
+		logger:  logger,
+	}
+}
+
+// SendAdvertMessage writes to the seller of an advert the caller can see, the first message starts the thread.
+func (m *MessageService) SendAdvertMessage(ctx context.Context, advertID, body string) (models.Message, error) {
+	parsedID, err := uuid.Parse(advertID)
+	if err != nil {
+		return models.Message{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	advert, err := m.advert.GetAdvertByID(ctx, parsedID.String())
+	if err != nil {
+		return models.Message{}, err
+	}
+	if !advertVisible(ctx, advert) {
+		return models.Message{}, custom_error.CustomError{Field: "id", Message: ErrAdvertServiceNotFound.Error()}
+	}
+	if advert.UserID == userID {
+		return models.Message{}, custom_error.CustomError{Field: "id", Message: ErrMessageServiceOwnAdvert.Error()}
+	}
+
+	now := time.Now()
+	thread := models.Thread{
+		ID:        uuid.New().String(),
+		AdvertID:  advert.ID,
+		SellerID:  advert.UserID,
+		BuyerID:   userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	return m.sendMessage(ctx, thread, userID, body, now)
+}
+
+// SendThreadMessage replies in a thread of the caller.
+func (m *MessageService) SendThreadMessage(ctx context.Context, threadID, body string) (models.Message, error) {
+	thread, err := m.getThread(ctx, threadID)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return m.sendMessage(ctx, thread, userID, body, time.Now())
+}
+
+func (m *MessageService) sendMessage(ctx context.Context, thread models.Thread, senderID, body string, now time.Time) (models.Message, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return models.Message{}, custom_error.CustomError{Field: "body", Message: ErrMessageServiceEmptyBody.Error()}
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return models.Message{}, custom_error.CustomError{Field: "body", Message: ErrMessageServiceLongBody.Error()}
+	}
+
+	recipientID := thread.SellerID
+	if senderID == thread.SellerID {
+		recipientID = thread.BuyerID
+	}
+
+	blocked, err := m.message.IsBlocked(ctx, senderID, recipientID)
+	if err != nil {
+		return models.Message{}, err
+	}
+	if blocked {
+		return models.Message{}, custom_error.CustomError{Field: "user_id", Message: ErrMessageServiceBlocked.Error()}
+	}
+
+	message := models.Message{
+		ID:        uuid.New().String(),
+		SenderID:  senderID,
+		Body:      body,
+		CreatedAt: now,
+	}
+
+	message.ThreadID, err = m.message.SendMessage(ctx, thread, message)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return message, nil
+}
+
+func (m *MessageService) getThread(ctx context.Context, id string) (models.Thread, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Thread{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	return m.message.GetThreadByID(ctx, parsedID.String(), userID)
+}
+
+// ListThreads returns a page of threads of the caller, the most recently updated first,
+// and the cursor of the next page, which is empty on the last page.
+func (m *MessageService) ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, string, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	params.UserID = userID
+
+	after, err := parsePage(&params.Limit, params.Cursor, threadsSortBy)
+	if err != nil {
+		return nil, "", err
+	}
+	params.After = after
+
+	// one extra thread tells whether there is a next page
+	limit := params.Limit
+	params.Limit++
+
+	threads, err := m.message.ListThreads(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(threads) <= limit {
+		return threads, "", nil
+	}
+
+	threads = threads[:limit]
+	last := threads[limit-1]
+
+	nextCursor, err := encodeAdvertCursor(models.AdvertCursor{CreatedAt: last.UpdatedAt, ID: last.ID}, threadsSortBy, models.SortOrderDesc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return threads, nextCursor, nil
+}
+
+// ListMessages returns a page of messages of a thread of the caller, the newest first.
+// The first page marks the messages up to the newest shown one as read by the caller.
+func (m *MessageService) ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, string, error) {
+	thread, err := m.getThread(ctx, params.ThreadID)
+	if err != nil {
+		return nil, "", err
+	}
+	params.ThreadID = thread.ID
+
+	after, err := parsePage(&params.Limit, params.Cursor, messagesSortBy)
+	if err != nil {
+		return nil, "", err
+	}
+	params.After = after
+
+	limit := params.Limit
+	params.Limit++
+
+	messages, err := m.message.ListMessages(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextPage bool
+	if len(messages) > limit {
+		messages = messages[:limit]
+		nextPage = true
+	}
+
+	if params.Cursor == "" && len(messages) > 0 {
+		userID, err := getUserID(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		// a message sent after the page was loaded is newer than the shown ones and stays unread
+		readUntil := models.AdvertCursor{CreatedAt: messages[0].CreatedAt, ID: messages[0].ID}
+		if err := m.message.MarkThreadRead(ctx, thread.ID, userID, readUntil); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if !nextPage {
+		return messages, "", nil
+	}
+
+	last := messages[limit-1]
+
+	nextCursor, err := encodeAdvertCursor(models.AdvertCursor{CreatedAt: last.CreatedAt, ID: last.ID}, messagesSortBy, models.SortOrderDesc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return messages, nextCursor, nil
+}
+
+// CountUnreadMessages returns the number of messages the caller has not read in all threads.
+func (m *MessageService) CountUnreadMessages(ctx context.Context) (int, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.message.CountUnreadMessages(ctx, userID)
+}
+
+// BlockUser stops messages between the caller and the user in both directions.
+func (m *MessageService) BlockUser(ctx context.Context, id string) error {
+	userID, blockedID, err := m.blockedUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return m.message.BlockUser(ctx, models.UserBlock{
+		UserID:    userID,
+		BlockedID: blockedID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (m *MessageService) UnblockUser(ctx context.Context, id string) error {
+	userID, blockedID, err := m.blockedUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return m.message.UnblockUser(ctx, userID, blockedID)
+}
+
+func (m *MessageService) blockedUser(ctx context.Context, id string) (string, string, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return "", "", custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	if parsedID.String() == userID {
+		return "", "", custom_error.CustomError{Field: "id", Message: ErrMessageServiceBlockSelf.Error()}
+	}
+
+	return userID, parsedID.String(), nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testMessageStorage keeps one thread per advert and buyer in memory, blocked holds "<user id>/<blocked id>".
+// afterList is called after a page of messages is read, e.g. to send a message meanwhile.
+type testMessageStorage struct {
+	storage.MessageStorage
+	threads   map[string]*models.Thread
+	unread    map[string]int
+	messages  map[string][]models.Message
+	blocked   map[string]bool
+	afterList func()
+}
+
+func newTestMessageStorage() testMessageStorage {
+	return testMessageStorage{
+		threads:  make(map[string]*models.Thread),
+		unread:   make(map[string]int),
+		messages: make(map[string][]models.Message),
+		blocked:  make(map[string]bool),
+	}
+}
+
+func (s testMessageStorage) SendMessage(ctx context.Context, thread models.Thread, message models.Message) (string, error) {
+	for _, existing := range s.threads {
+		if existing.AdvertID == thread.AdvertID && existing.BuyerID == thread.BuyerID {
+			thread = *existing
+		}
+	}
+	s.threads[thread.ID] = &thread
+
+	recipientID := thread.SellerID
+	if message.SenderID == thread.SellerID {
+		recipientID = thread.BuyerID
+	}
+	s.unread[thread.ID+"/"+recipientID]++
+
+	message.ThreadID = thread.ID
+	s.messages[thread.ID] = append([]models.Message{message}, s.messages[thread.ID]...)
+
+	return thread.ID, nil
+}
+
+func (s testMessageStorage) GetThreadByID(ctx context.Context, id, userID string) (models.Thread, error) {
+	thread, ok := s.threads[id]
+	if !ok || (thread.SellerID != userID && thread.BuyerID != userID) {
+		return models.Thread{}, custom_error.CustomError{Field: "id", Message: "thread not found"}
+	}
+	result := *thread
+	result.Unread = s.unread[id+"/"+userID]
+	return result, nil
+}
+
+func (s testMessageStorage) ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, error) {
+	messages := s.messages[params.ThreadID]
+	if len(messages) > params.Limit {
+		messages = messages[:params.Limit]
+	}
+	if s.afterList != nil {
+		s.afterList()
+	}
+	return messages, nil
+}
+
+func (s testMessageStorage) MarkThreadRead(ctx context.Context, id, userID string, readUntil models.AdvertCursor) error {
+	var unread int
+	for _, message := range s.messages[id] {
+		if message.ID == readUntil.ID {
+			break
+		}
+		if message.SenderID != userID {
+			unread++
+		}
+	}
+	if unread < s.unread[id+"/"+userID] {
+		s.unread[id+"/"+userID] = unread
+	}
+	return nil
+}
+
+func (s testMessageStorage) BlockUser(ctx context.Context, block models.UserBlock) error {
+	s.blocked[block.UserID+"/"+block.BlockedID] = true
+	return nil
+}
+
+func (s testMessageStorage) IsBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+	return s.blocked[userID+"/"+otherID] || s.blocked[otherID+"/"+userID], nil
+}
+
+func TestMessageServiceConversation(t *testing.T) {
+	advert := models.Advert{
+		ID:     uuid.New().String(),
+		UserID: "seller id",
+		Status: models.AdvertStatusPublished,
+		Images: []*models.Image{{ID: "image id", Status: models.ImageStatusReady}},
+	}
+
+	var meanwhile func()
+	messages := newTestMessageStorage()
+	messages.afterList = func() {
+		if send := meanwhile; send != nil {
+			meanwhile = nil
+			send()
+		}
+	}
+	service := NewMessageService(messages, testAdvertStorage{advert: advert}, nil)
+
+	buyerCtx := context.WithValue(context.Background(), "user_id", "buyer id")
+	sellerCtx := context.WithValue(context.Background(), "user_id", "seller id")
+
+	first, err := service.SendAdvertMessage(buyerCtx, advert.ID, "  is it still available?  ")
+	require.NoError(t, err)
+	require.Equal(t, "is it still available?", first.Body)
+
+	// the second message about the same advert continues the thread
+	second, err := service.SendAdvertMessage(buyerCtx, advert.ID, "can you ship it?")
+	require.NoError(t, err)
+	require.Equal(t, first.ThreadID, second.ThreadID)
+
+	_, err = service.SendAdvertMessage(sellerCtx, advert.ID, "hello")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrMessageServiceOwnAdvert.Error()})
+
+	thread, err := messages.GetThreadByID(sellerCtx, first.ThreadID, "seller id")
+	require.NoError(t, err)
+	require.Equal(t, 2, thread.Unread)
+
+	// the buyer writes again while the page is loading
+	meanwhile = func() {
+		_, err := service.SendThreadMessage(buyerCtx, first.ThreadID, "hello?")
+		require.NoError(t, err)
+	}
+
+	page, nextCursor, err := service.ListMessages(sellerCtx, models.MessageListParams{ThreadID: first.ThreadID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, second.ID, page[0].ID)
+	require.NotEmpty(t, nextCursor)
+
+	thread, err = messages.GetThreadByID(sellerCtx, first.ThreadID, "seller id")
+	require.NoError(t, err)
+	require.Equal(t, 1, thread.Unread, "the message sent after the shown one stays unread")
+
+	// the reply of the seller is not counted as read by them
+	_, err = service.SendThreadMessage(sellerCtx, first.ThreadID, "yes")
+	require.NoError(t, err)
+
+	page, _, err = service.ListMessages(sellerCtx, models.MessageListParams{ThreadID: first.ThreadID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	thread, err = messages.GetThreadByID(sellerCtx, first.ThreadID, "seller id")
+	require.NoError(t, err)
+	require.Zero(t, thread.Unread)
+
+	_, _, err = service.ListMessages(context.WithValue(context.Background(), "user_id", "stranger id"),
+		models.MessageListParams{ThreadID: first.ThreadID})
+	require.Error(t, err)
+}
+
+func TestMessageServiceBlockedUser(t *testing.T) {
+	buyerID := uuid.New().String()
+	advert := models.Advert{
+		ID:     uuid.New().String(),
+		UserID: uuid.New().String(),
+		Status: models.AdvertStatusPublished,
+		Images: []*models.Image{{ID: "image id", Status: models.ImageStatusReady}},
+	}
+
+	messages := newTestMessageStorage()
+	service := NewMessageService(messages, testAdvertStorage{advert: advert}, nil)
+
+	buyerCtx := context.WithValue(context.Background(), "user_id", buyerID)
+	sellerCtx := context.WithValue(context.Background(), "user_id", advert.UserID)
+
+	message, err := service.SendAdvertMessage(buyerCtx, advert.ID, "hello")
+	require.NoError(t, err)
+
+	err = service.BlockUser(sellerCtx, advert.UserID)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrMessageServiceBlockSelf.Error()})
+
+	require.NoError(t, service.BlockUser(sellerCtx, buyerID))
+
+	blocked := custom_error.CustomError{Field: "user_id", Message: ErrMessageServiceBlocked.Error()}
+
+	_, err = service.SendAdvertMessage(buyerCtx, advert.ID, "hello again")
+	require.ErrorIs(t, err, blocked)
+
+	// the block works in both directions
+	_, err = service.SendThreadMessage(sellerCtx, message.ThreadID, "hello")
+	require.ErrorIs(t, err, blocked)
+}
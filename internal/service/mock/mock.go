@@ -35,19 +35,220 @@ func (m *MockUser) EXPECT() *MockUserMockRecorder {
 	return m.recorder
 }
 
+// BanUser mocks base method.
+func (m *MockUser) BanUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BanUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BanUser indicates an expected call of BanUser.
+func (mr *MockUserMockRecorder) BanUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BanUser", reflect.TypeOf((*MockUser)(nil).BanUser), ctx, id)
+}
+
+// CheckAccessToken mocks base method.
+func (m *MockUser) CheckAccessToken(ctx context.Context, token models.AccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAccessToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckAccessToken indicates an expected call of CheckAccessToken.
+func (mr *MockUserMockRecorder) CheckAccessToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAccessToken", reflect.TypeOf((*MockUser)(nil).CheckAccessToken), ctx, token)
+}
+
+// GetProfile mocks base method.
+func (m *MockUser) GetProfile(ctx context.Context) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfile", ctx)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockUserMockRecorder) GetProfile(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockUser)(nil).GetProfile), ctx)
+}
+
+// GetPublicProfile mocks base method.
+func (m *MockUser) GetPublicProfile(ctx context.Context, id string) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPublicProfile", ctx, id)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPublicProfile indicates an expected call of GetPublicProfile.
+func (mr *MockUserMockRecorder) GetPublicProfile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPublicProfile", reflect.TypeOf((*MockUser)(nil).GetPublicProfile), ctx, id)
+}
+
+// ListSessions mocks base method.
+func (m *MockUser) ListSessions(ctx context.Context) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockUserMockRecorder) ListSessions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockUser)(nil).ListSessions), ctx)
+}
+
+// Logout mocks base method.
+func (m *MockUser) Logout(ctx context.Context, token models.AccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUserMockRecorder) Logout(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUser)(nil).Logout), ctx, token)
+}
+
+// LogoutAll mocks base method.
+func (m *MockUser) LogoutAll(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockUserMockRecorder) LogoutAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockUser)(nil).LogoutAll), ctx)
+}
+
+// PurgeExpiredTokens mocks base method.
+func (m *MockUser) PurgeExpiredTokens(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpiredTokens", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpiredTokens indicates an expected call of PurgeExpiredTokens.
+func (mr *MockUserMockRecorder) PurgeExpiredTokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpiredTokens", reflect.TypeOf((*MockUser)(nil).PurgeExpiredTokens), ctx)
+}
+
+// Refresh mocks base method.
+func (m *MockUser) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(models.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockUserMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockUser)(nil).Refresh), ctx, refreshToken)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockUser) RequestPasswordReset(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockUserMockRecorder) RequestPasswordReset(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockUser)(nil).RequestPasswordReset), ctx, email)
+}
+
+// ResendVerificationEmail mocks base method.
+func (m *MockUser) ResendVerificationEmail(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerificationEmail", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerificationEmail indicates an expected call of ResendVerificationEmail.
+func (mr *MockUserMockRecorder) ResendVerificationEmail(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerificationEmail", reflect.TypeOf((*MockUser)(nil).ResendVerificationEmail), ctx)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUser) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserMockRecorder) ResetPassword(ctx, token, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUser)(nil).ResetPassword), ctx, token, password)
+}
+
+// RevokeSession mocks base method.
+func (m *MockUser) RevokeSession(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUserMockRecorder) RevokeSession(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUser)(nil).RevokeSession), ctx, id)
+}
+
+// SetUserRole mocks base method.
+func (m *MockUser) SetUserRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockUserMockRecorder) SetUserRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockUser)(nil).SetUserRole), ctx, id, role)
+}
+
 // SignIn mocks base method.
-func (m *MockUser) SignIn(ctx context.Context, email, password string) (string, error) {
+func (m *MockUser) SignIn(ctx context.Context, email, password, userAgent string) (models.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignIn", ctx, email, password)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SignIn", ctx, email, password, userAgent)
+	ret0, _ := ret[0].(models.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignIn indicates an expected call of SignIn.
-func (mr *MockUserMockRecorder) SignIn(ctx, email, password interface{}) *gomock.Call {
+func (mr *MockUserMockRecorder) SignIn(ctx, email, password, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockUser)(nil).SignIn), ctx, email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockUser)(nil).SignIn), ctx, email, password, userAgent)
 }
 
 // SignUp mocks base method.
@@ -65,6 +266,49 @@ func (mr *MockUserMockRecorder) SignUp(ctx, user interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockUser)(nil).SignUp), ctx, user)
 }
 
+// UnbanUser mocks base method.
+func (m *MockUser) UnbanUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnbanUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnbanUser indicates an expected call of UnbanUser.
+func (mr *MockUserMockRecorder) UnbanUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnbanUser", reflect.TypeOf((*MockUser)(nil).UnbanUser), ctx, id)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockUser) UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, update)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockUserMockRecorder) UpdateProfile(ctx, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockUser)(nil).UpdateProfile), ctx, update)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUser) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUser)(nil).VerifyEmail), ctx, token)
+}
+
 // MockAdvert is a mock of Advert interface.
 type MockAdvert struct {
 	ctrl     *gomock.Controller
@@ -88,6 +332,49 @@ func (m *MockAdvert) EXPECT() *MockAdvertMockRecorder {
 	return m.recorder
 }
 
+// ApproveAdvert mocks base method.
+func (m *MockAdvert) ApproveAdvert(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveAdvert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveAdvert indicates an expected call of ApproveAdvert.
+func (mr *MockAdvertMockRecorder) ApproveAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdvert", reflect.TypeOf((*MockAdvert)(nil).ApproveAdvert), ctx, id)
+}
+
+// ArchiveExpiredAdverts mocks base method.
+func (m *MockAdvert) ArchiveExpiredAdverts(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveExpiredAdverts", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveExpiredAdverts indicates an expected call of ArchiveExpiredAdverts.
+func (mr *MockAdvertMockRecorder) ArchiveExpiredAdverts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveExpiredAdverts", reflect.TypeOf((*MockAdvert)(nil).ArchiveExpiredAdverts), ctx)
+}
+
+// ChangeAdvertStatus mocks base method.
+func (m *MockAdvert) ChangeAdvertStatus(ctx context.Context, id, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeAdvertStatus", ctx, id, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeAdvertStatus indicates an expected call of ChangeAdvertStatus.
+func (mr *MockAdvertMockRecorder) ChangeAdvertStatus(ctx, id, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdvertStatus", reflect.TypeOf((*MockAdvert)(nil).ChangeAdvertStatus), ctx, id, status)
+}
+
 // CreateAdvert mocks base method.
 func (m *MockAdvert) CreateAdvert(ctx context.Context, advert models.Advert) (string, error) {
 	m.ctrl.T.Helper()
@@ -117,6 +404,35 @@ func (mr *MockAdvertMockRecorder) DeleteAdvert(ctx, id interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdvert", reflect.TypeOf((*MockAdvert)(nil).DeleteAdvert), ctx, id)
 }
 
+// DeleteAnyAdvert mocks base method.
+func (m *MockAdvert) DeleteAnyAdvert(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAnyAdvert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAnyAdvert indicates an expected call of DeleteAnyAdvert.
+func (mr *MockAdvertMockRecorder) DeleteAnyAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAnyAdvert", reflect.TypeOf((*MockAdvert)(nil).DeleteAnyAdvert), ctx, id)
+}
+
+// FindDuplicateAdverts mocks base method.
+func (m *MockAdvert) FindDuplicateAdverts(ctx context.Context, id string) ([]models.DuplicateAdvert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDuplicateAdverts", ctx, id)
+	ret0, _ := ret[0].([]models.DuplicateAdvert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDuplicateAdverts indicates an expected call of FindDuplicateAdverts.
+func (mr *MockAdvertMockRecorder) FindDuplicateAdverts(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicateAdverts", reflect.TypeOf((*MockAdvert)(nil).FindDuplicateAdverts), ctx, id)
+}
+
 // GetAdvertByID mocks base method.
 func (m *MockAdvert) GetAdvertByID(ctx context.Context, id string) (models.Advert, error) {
 	m.ctrl.T.Helper()
@@ -132,139 +448,1327 @@ func (mr *MockAdvertMockRecorder) GetAdvertByID(ctx, id interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertByID", reflect.TypeOf((*MockAdvert)(nil).GetAdvertByID), ctx, id)
 }
 
-// MockImage is a mock of Image interface.
-type MockImage struct {
-	ctrl     *gomock.Controller
-	recorder *MockImageMockRecorder
+// ListAdverts mocks base method.
+func (m *MockAdvert) ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdverts", ctx, params)
+	ret0, _ := ret[0].([]models.Advert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
-// MockImageMockRecorder is the mock recorder for MockImage.
-type MockImageMockRecorder struct {
-	mock *MockImage
+// ListAdverts indicates an expected call of ListAdverts.
+func (mr *MockAdvertMockRecorder) ListAdverts(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdverts", reflect.TypeOf((*MockAdvert)(nil).ListAdverts), ctx, params)
 }
 
-// NewMockImage creates a new mock instance.
-func NewMockImage(ctrl *gomock.Controller) *MockImage {
-	mock := &MockImage{ctrl: ctrl}
-	mock.recorder = &MockImageMockRecorder{mock}
-	return mock
+// ListModerationQueue mocks base method.
+func (m *MockAdvert) ListModerationQueue(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModerationQueue", ctx, params)
+	ret0, _ := ret[0].([]models.Advert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockImage) EXPECT() *MockImageMockRecorder {
-	return m.recorder
+// ListModerationQueue indicates an expected call of ListModerationQueue.
+func (mr *MockAdvertMockRecorder) ListModerationQueue(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModerationQueue", reflect.TypeOf((*MockAdvert)(nil).ListModerationQueue), ctx, params)
 }
 
-// GetImageByID mocks base method.
-func (m *MockImage) GetImageByID(ctx context.Context, id string) (models.Image, error) {
+// RejectAdvert mocks base method.
+func (m *MockAdvert) RejectAdvert(ctx context.Context, id, reason string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByID", ctx, id)
-	ret0, _ := ret[0].(models.Image)
+	ret := m.ctrl.Call(m, "RejectAdvert", ctx, id, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectAdvert indicates an expected call of RejectAdvert.
+func (mr *MockAdvertMockRecorder) RejectAdvert(ctx, id, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdvert", reflect.TypeOf((*MockAdvert)(nil).RejectAdvert), ctx, id, reason)
+}
+
+// RenewAdvert mocks base method.
+func (m *MockAdvert) RenewAdvert(ctx context.Context, id string) (models.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewAdvert", ctx, id)
+	ret0, _ := ret[0].(models.Advert)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetImageByID indicates an expected call of GetImageByID.
-func (mr *MockImageMockRecorder) GetImageByID(ctx, id interface{}) *gomock.Call {
+// RenewAdvert indicates an expected call of RenewAdvert.
+func (mr *MockAdvertMockRecorder) RenewAdvert(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImage)(nil).GetImageByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewAdvert", reflect.TypeOf((*MockAdvert)(nil).RenewAdvert), ctx, id)
 }
 
-// MockServices is a mock of Services interface.
-type MockServices struct {
+// SearchAdverts mocks base method.
+func (m *MockAdvert) SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAdverts", ctx, params)
+	ret0, _ := ret[0].([]models.AdvertSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAdverts indicates an expected call of SearchAdverts.
+func (mr *MockAdvertMockRecorder) SearchAdverts(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAdverts", reflect.TypeOf((*MockAdvert)(nil).SearchAdverts), ctx, params)
+}
+
+// UpdateAdvert mocks base method.
+func (m *MockAdvert) UpdateAdvert(ctx context.Context, update models.AdvertUpdate) (models.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAdvert", ctx, update)
+	ret0, _ := ret[0].(models.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAdvert indicates an expected call of UpdateAdvert.
+func (mr *MockAdvertMockRecorder) UpdateAdvert(ctx, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAdvert", reflect.TypeOf((*MockAdvert)(nil).UpdateAdvert), ctx, update)
+}
+
+// MockCategory is a mock of Category interface.
+type MockCategory struct {
 	ctrl     *gomock.Controller
-	recorder *MockServicesMockRecorder
+	recorder *MockCategoryMockRecorder
 }
 
-// MockServicesMockRecorder is the mock recorder for MockServices.
-type MockServicesMockRecorder struct {
-	mock *MockServices
+// MockCategoryMockRecorder is the mock recorder for MockCategory.
+type MockCategoryMockRecorder struct {
+	mock *MockCategory
 }
 
-// NewMockServices creates a new mock instance.
-func NewMockServices(ctrl *gomock.Controller) *MockServices {
-	mock := &MockServices{ctrl: ctrl}
-	mock.recorder = &MockServicesMockRecorder{mock}
+// NewMockCategory creates a new mock instance.
+func NewMockCategory(ctrl *gomock.Controller) *MockCategory {
+	mock := &MockCategory{ctrl: ctrl}
+	mock.recorder = &MockCategoryMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockServices) EXPECT() *MockServicesMockRecorder {
+func (m *MockCategory) EXPECT() *MockCategoryMockRecorder {
 	return m.recorder
 }
 
-// CreateAdvert mocks base method.
-func (m *MockServices) CreateAdvert(ctx context.Context, advert models.Advert) (string, error) {
+// CreateCategory mocks base method.
+func (m *MockCategory) CreateCategory(ctx context.Context, category models.Category) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAdvert", ctx, advert)
+	ret := m.ctrl.Call(m, "CreateCategory", ctx, category)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAdvert indicates an expected call of CreateAdvert.
-func (mr *MockServicesMockRecorder) CreateAdvert(ctx, advert interface{}) *gomock.Call {
+// CreateCategory indicates an expected call of CreateCategory.
+func (mr *MockCategoryMockRecorder) CreateCategory(ctx, category interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdvert", reflect.TypeOf((*MockServices)(nil).CreateAdvert), ctx, advert)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategory", reflect.TypeOf((*MockCategory)(nil).CreateCategory), ctx, category)
 }
 
-// DeleteAdvert mocks base method.
-func (m *MockServices) DeleteAdvert(ctx context.Context, id string) error {
+// DeleteCategory mocks base method.
+func (m *MockCategory) DeleteCategory(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAdvert", ctx, id)
+	ret := m.ctrl.Call(m, "DeleteCategory", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteAdvert indicates an expected call of DeleteAdvert.
-func (mr *MockServicesMockRecorder) DeleteAdvert(ctx, id interface{}) *gomock.Call {
+// DeleteCategory indicates an expected call of DeleteCategory.
+func (mr *MockCategoryMockRecorder) DeleteCategory(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdvert", reflect.TypeOf((*MockServices)(nil).DeleteAdvert), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCategory", reflect.TypeOf((*MockCategory)(nil).DeleteCategory), ctx, id)
 }
 
-// GetAdvertByID mocks base method.
-func (m *MockServices) GetAdvertByID(ctx context.Context, id string) (models.Advert, error) {
+// GetCategoryByID mocks base method.
+func (m *MockCategory) GetCategoryByID(ctx context.Context, id string) (models.Category, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAdvertByID", ctx, id)
-	ret0, _ := ret[0].(models.Advert)
+	ret := m.ctrl.Call(m, "GetCategoryByID", ctx, id)
+	ret0, _ := ret[0].(models.Category)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAdvertByID indicates an expected call of GetAdvertByID.
-func (mr *MockServicesMockRecorder) GetAdvertByID(ctx, id interface{}) *gomock.Call {
+// GetCategoryByID indicates an expected call of GetCategoryByID.
+func (mr *MockCategoryMockRecorder) GetCategoryByID(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertByID", reflect.TypeOf((*MockServices)(nil).GetAdvertByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryByID", reflect.TypeOf((*MockCategory)(nil).GetCategoryByID), ctx, id)
 }
 
-// GetImageByID mocks base method.
-func (m *MockServices) GetImageByID(ctx context.Context, id string) (models.Image, error) {
+// ListCategories mocks base method.
+func (m *MockCategory) ListCategories(ctx context.Context) ([]*models.Category, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByID", ctx, id)
+	ret := m.ctrl.Call(m, "ListCategories", ctx)
+	ret0, _ := ret[0].([]*models.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCategories indicates an expected call of ListCategories.
+func (mr *MockCategoryMockRecorder) ListCategories(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCategories", reflect.TypeOf((*MockCategory)(nil).ListCategories), ctx)
+}
+
+// UpdateCategory mocks base method.
+func (m *MockCategory) UpdateCategory(ctx context.Context, category models.Category) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCategory", ctx, category)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCategory indicates an expected call of UpdateCategory.
+func (mr *MockCategoryMockRecorder) UpdateCategory(ctx, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCategory", reflect.TypeOf((*MockCategory)(nil).UpdateCategory), ctx, category)
+}
+
+// MockImage is a mock of Image interface.
+type MockImage struct {
+	ctrl     *gomock.Controller
+	recorder *MockImageMockRecorder
+}
+
+// MockImageMockRecorder is the mock recorder for MockImage.
+type MockImageMockRecorder struct {
+	mock *MockImage
+}
+
+// NewMockImage creates a new mock instance.
+func NewMockImage(ctrl *gomock.Controller) *MockImage {
+	mock := &MockImage{ctrl: ctrl}
+	mock.recorder = &MockImageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImage) EXPECT() *MockImageMockRecorder {
+	return m.recorder
+}
+
+// CollectOrphanedImages mocks base method.
+func (m *MockImage) CollectOrphanedImages(ctx context.Context, dryRun bool) (models.ImageGCResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectOrphanedImages", ctx, dryRun)
+	ret0, _ := ret[0].(models.ImageGCResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectOrphanedImages indicates an expected call of CollectOrphanedImages.
+func (mr *MockImageMockRecorder) CollectOrphanedImages(ctx, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectOrphanedImages", reflect.TypeOf((*MockImage)(nil).CollectOrphanedImages), ctx, dryRun)
+}
+
+// GetImageByID mocks base method.
+func (m *MockImage) GetImageByID(ctx context.Context, id, size, ifNoneMatch string) (models.ImageFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageByID", ctx, id, size, ifNoneMatch)
+	ret0, _ := ret[0].(models.ImageFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageByID indicates an expected call of GetImageByID.
+func (mr *MockImageMockRecorder) GetImageByID(ctx, id, size, ifNoneMatch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImage)(nil).GetImageByID), ctx, id, size, ifNoneMatch)
+}
+
+// ProcessImageJobs mocks base method.
+func (m *MockImage) ProcessImageJobs(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessImageJobs", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessImageJobs indicates an expected call of ProcessImageJobs.
+func (mr *MockImageMockRecorder) ProcessImageJobs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessImageJobs", reflect.TypeOf((*MockImage)(nil).ProcessImageJobs), ctx)
+}
+
+// PurgeUnattachedImages mocks base method.
+func (m *MockImage) PurgeUnattachedImages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUnattachedImages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeUnattachedImages indicates an expected call of PurgeUnattachedImages.
+func (mr *MockImageMockRecorder) PurgeUnattachedImages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUnattachedImages", reflect.TypeOf((*MockImage)(nil).PurgeUnattachedImages), ctx)
+}
+
+// ReshardImages mocks base method.
+func (m *MockImage) ReshardImages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReshardImages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReshardImages indicates an expected call of ReshardImages.
+func (mr *MockImageMockRecorder) ReshardImages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReshardImages", reflect.TypeOf((*MockImage)(nil).ReshardImages), ctx)
+}
+
+// UploadImage mocks base method.
+func (m *MockImage) UploadImage(ctx context.Context, image *models.Image) (models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadImage", ctx, image)
 	ret0, _ := ret[0].(models.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetImageByID indicates an expected call of GetImageByID.
-func (mr *MockServicesMockRecorder) GetImageByID(ctx, id interface{}) *gomock.Call {
+// UploadImage indicates an expected call of UploadImage.
+func (mr *MockImageMockRecorder) UploadImage(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockImage)(nil).UploadImage), ctx, image)
+}
+
+// MockUpload is a mock of Upload interface.
+type MockUpload struct {
+	ctrl     *gomock.Controller
+	recorder *MockUploadMockRecorder
+}
+
+// MockUploadMockRecorder is the mock recorder for MockUpload.
+type MockUploadMockRecorder struct {
+	mock *MockUpload
+}
+
+// NewMockUpload creates a new mock instance.
+func NewMockUpload(ctrl *gomock.Controller) *MockUpload {
+	mock := &MockUpload{ctrl: ctrl}
+	mock.recorder = &MockUploadMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpload) EXPECT() *MockUploadMockRecorder {
+	return m.recorder
+}
+
+// CreateUpload mocks base method.
+func (m *MockUpload) CreateUpload(ctx context.Context, length int64) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpload", ctx, length)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUpload indicates an expected call of CreateUpload.
+func (mr *MockUploadMockRecorder) CreateUpload(ctx, length interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpload", reflect.TypeOf((*MockUpload)(nil).CreateUpload), ctx, length)
+}
+
+// GetUpload mocks base method.
+func (m *MockUpload) GetUpload(ctx context.Context, id string) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", ctx, id)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockUploadMockRecorder) GetUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockUpload)(nil).GetUpload), ctx, id)
+}
+
+// PurgeStaleUploads mocks base method.
+func (m *MockUpload) PurgeStaleUploads(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeStaleUploads", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeStaleUploads indicates an expected call of PurgeStaleUploads.
+func (mr *MockUploadMockRecorder) PurgeStaleUploads(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeStaleUploads", reflect.TypeOf((*MockUpload)(nil).PurgeStaleUploads), ctx)
+}
+
+// WriteUploadChunk mocks base method.
+func (m *MockUpload) WriteUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteUploadChunk", ctx, id, offset, chunk)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteUploadChunk indicates an expected call of WriteUploadChunk.
+func (mr *MockUploadMockRecorder) WriteUploadChunk(ctx, id, offset, chunk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteUploadChunk", reflect.TypeOf((*MockUpload)(nil).WriteUploadChunk), ctx, id, offset, chunk)
+}
+
+// MockFavourite is a mock of Favourite interface.
+type MockFavourite struct {
+	ctrl     *gomock.Controller
+	recorder *MockFavouriteMockRecorder
+}
+
+// MockFavouriteMockRecorder is the mock recorder for MockFavourite.
+type MockFavouriteMockRecorder struct {
+	mock *MockFavourite
+}
+
+// NewMockFavourite creates a new mock instance.
+func NewMockFavourite(ctrl *gomock.Controller) *MockFavourite {
+	mock := &MockFavourite{ctrl: ctrl}
+	mock.recorder = &MockFavouriteMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFavourite) EXPECT() *MockFavouriteMockRecorder {
+	return m.recorder
+}
+
+// AddFavourite mocks base method.
+func (m *MockFavourite) AddFavourite(ctx context.Context, advertID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFavourite", ctx, advertID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddFavourite indicates an expected call of AddFavourite.
+func (mr *MockFavouriteMockRecorder) AddFavourite(ctx, advertID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFavourite", reflect.TypeOf((*MockFavourite)(nil).AddFavourite), ctx, advertID)
+}
+
+// ListFavourites mocks base method.
+func (m *MockFavourite) ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFavourites", ctx, params)
+	ret0, _ := ret[0].([]models.FavouriteAdvert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListFavourites indicates an expected call of ListFavourites.
+func (mr *MockFavouriteMockRecorder) ListFavourites(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFavourites", reflect.TypeOf((*MockFavourite)(nil).ListFavourites), ctx, params)
+}
+
+// RemoveFavourite mocks base method.
+func (m *MockFavourite) RemoveFavourite(ctx context.Context, advertID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFavourite", ctx, advertID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFavourite indicates an expected call of RemoveFavourite.
+func (mr *MockFavouriteMockRecorder) RemoveFavourite(ctx, advertID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFavourite", reflect.TypeOf((*MockFavourite)(nil).RemoveFavourite), ctx, advertID)
+}
+
+// MockMessage is a mock of Message interface.
+type MockMessage struct {
+	ctrl     *gomock.Controller
+	recorder *MockMessageMockRecorder
+}
+
+// MockMessageMockRecorder is the mock recorder for MockMessage.
+type MockMessageMockRecorder struct {
+	mock *MockMessage
+}
+
+// NewMockMessage creates a new mock instance.
+func NewMockMessage(ctrl *gomock.Controller) *MockMessage {
+	mock := &MockMessage{ctrl: ctrl}
+	mock.recorder = &MockMessageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMessage) EXPECT() *MockMessageMockRecorder {
+	return m.recorder
+}
+
+// BlockUser mocks base method.
+func (m *MockMessage) BlockUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockUser indicates an expected call of BlockUser.
+func (mr *MockMessageMockRecorder) BlockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockMessage)(nil).BlockUser), ctx, id)
+}
+
+// CountUnreadMessages mocks base method.
+func (m *MockMessage) CountUnreadMessages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnreadMessages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnreadMessages indicates an expected call of CountUnreadMessages.
+func (mr *MockMessageMockRecorder) CountUnreadMessages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnreadMessages", reflect.TypeOf((*MockMessage)(nil).CountUnreadMessages), ctx)
+}
+
+// ListMessages mocks base method.
+func (m *MockMessage) ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessages", ctx, params)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMessages indicates an expected call of ListMessages.
+func (mr *MockMessageMockRecorder) ListMessages(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockMessage)(nil).ListMessages), ctx, params)
+}
+
+// ListThreads mocks base method.
+func (m *MockMessage) ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListThreads", ctx, params)
+	ret0, _ := ret[0].([]models.Thread)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListThreads indicates an expected call of ListThreads.
+func (mr *MockMessageMockRecorder) ListThreads(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListThreads", reflect.TypeOf((*MockMessage)(nil).ListThreads), ctx, params)
+}
+
+// SendAdvertMessage mocks base method.
+func (m *MockMessage) SendAdvertMessage(ctx context.Context, advertID, body string) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendAdvertMessage", ctx, advertID, body)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendAdvertMessage indicates an expected call of SendAdvertMessage.
+func (mr *MockMessageMockRecorder) SendAdvertMessage(ctx, advertID, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendAdvertMessage", reflect.TypeOf((*MockMessage)(nil).SendAdvertMessage), ctx, advertID, body)
+}
+
+// SendThreadMessage mocks base method.
+func (m *MockMessage) SendThreadMessage(ctx context.Context, threadID, body string) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendThreadMessage", ctx, threadID, body)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendThreadMessage indicates an expected call of SendThreadMessage.
+func (mr *MockMessageMockRecorder) SendThreadMessage(ctx, threadID, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendThreadMessage", reflect.TypeOf((*MockMessage)(nil).SendThreadMessage), ctx, threadID, body)
+}
+
+// UnblockUser mocks base method.
+func (m *MockMessage) UnblockUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnblockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnblockUser indicates an expected call of UnblockUser.
+func (mr *MockMessageMockRecorder) UnblockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockMessage)(nil).UnblockUser), ctx, id)
+}
+
+// MockServices is a mock of Services interface.
+type MockServices struct {
+	ctrl     *gomock.Controller
+	recorder *MockServicesMockRecorder
+}
+
+// MockServicesMockRecorder is the mock recorder for MockServices.
+type MockServicesMockRecorder struct {
+	mock *MockServices
+}
+
+// NewMockServices creates a new mock instance.
+func NewMockServices(ctrl *gomock.Controller) *MockServices {
+	mock := &MockServices{ctrl: ctrl}
+	mock.recorder = &MockServicesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServices) EXPECT() *MockServicesMockRecorder {
+	return m.recorder
+}
+
+// AddFavourite mocks base method.
+func (m *MockServices) AddFavourite(ctx context.Context, advertID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFavourite", ctx, advertID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddFavourite indicates an expected call of AddFavourite.
+func (mr *MockServicesMockRecorder) AddFavourite(ctx, advertID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFavourite", reflect.TypeOf((*MockServices)(nil).AddFavourite), ctx, advertID)
+}
+
+// ApproveAdvert mocks base method.
+func (m *MockServices) ApproveAdvert(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveAdvert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveAdvert indicates an expected call of ApproveAdvert.
+func (mr *MockServicesMockRecorder) ApproveAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdvert", reflect.TypeOf((*MockServices)(nil).ApproveAdvert), ctx, id)
+}
+
+// ArchiveExpiredAdverts mocks base method.
+func (m *MockServices) ArchiveExpiredAdverts(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveExpiredAdverts", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveExpiredAdverts indicates an expected call of ArchiveExpiredAdverts.
+func (mr *MockServicesMockRecorder) ArchiveExpiredAdverts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveExpiredAdverts", reflect.TypeOf((*MockServices)(nil).ArchiveExpiredAdverts), ctx)
+}
+
+// BanUser mocks base method.
+func (m *MockServices) BanUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BanUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BanUser indicates an expected call of BanUser.
+func (mr *MockServicesMockRecorder) BanUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BanUser", reflect.TypeOf((*MockServices)(nil).BanUser), ctx, id)
+}
+
+// BlockUser mocks base method.
+func (m *MockServices) BlockUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockUser indicates an expected call of BlockUser.
+func (mr *MockServicesMockRecorder) BlockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockServices)(nil).BlockUser), ctx, id)
+}
+
+// ChangeAdvertStatus mocks base method.
+func (m *MockServices) ChangeAdvertStatus(ctx context.Context, id, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeAdvertStatus", ctx, id, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeAdvertStatus indicates an expected call of ChangeAdvertStatus.
+func (mr *MockServicesMockRecorder) ChangeAdvertStatus(ctx, id, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdvertStatus", reflect.TypeOf((*MockServices)(nil).ChangeAdvertStatus), ctx, id, status)
+}
+
+// CheckAccessToken mocks base method.
+func (m *MockServices) CheckAccessToken(ctx context.Context, token models.AccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAccessToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckAccessToken indicates an expected call of CheckAccessToken.
+func (mr *MockServicesMockRecorder) CheckAccessToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAccessToken", reflect.TypeOf((*MockServices)(nil).CheckAccessToken), ctx, token)
+}
+
+// CollectOrphanedImages mocks base method.
+func (m *MockServices) CollectOrphanedImages(ctx context.Context, dryRun bool) (models.ImageGCResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectOrphanedImages", ctx, dryRun)
+	ret0, _ := ret[0].(models.ImageGCResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectOrphanedImages indicates an expected call of CollectOrphanedImages.
+func (mr *MockServicesMockRecorder) CollectOrphanedImages(ctx, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectOrphanedImages", reflect.TypeOf((*MockServices)(nil).CollectOrphanedImages), ctx, dryRun)
+}
+
+// CountUnreadMessages mocks base method.
+func (m *MockServices) CountUnreadMessages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnreadMessages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnreadMessages indicates an expected call of CountUnreadMessages.
+func (mr *MockServicesMockRecorder) CountUnreadMessages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnreadMessages", reflect.TypeOf((*MockServices)(nil).CountUnreadMessages), ctx)
+}
+
+// CreateAdvert mocks base method.
+func (m *MockServices) CreateAdvert(ctx context.Context, advert models.Advert) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdvert", ctx, advert)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAdvert indicates an expected call of CreateAdvert.
+func (mr *MockServicesMockRecorder) CreateAdvert(ctx, advert interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdvert", reflect.TypeOf((*MockServices)(nil).CreateAdvert), ctx, advert)
+}
+
+// CreateCategory mocks base method.
+func (m *MockServices) CreateCategory(ctx context.Context, category models.Category) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCategory", ctx, category)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCategory indicates an expected call of CreateCategory.
+func (mr *MockServicesMockRecorder) CreateCategory(ctx, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategory", reflect.TypeOf((*MockServices)(nil).CreateCategory), ctx, category)
+}
+
+// CreateUpload mocks base method.
+func (m *MockServices) CreateUpload(ctx context.Context, length int64) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpload", ctx, length)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUpload indicates an expected call of CreateUpload.
+func (mr *MockServicesMockRecorder) CreateUpload(ctx, length interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpload", reflect.TypeOf((*MockServices)(nil).CreateUpload), ctx, length)
+}
+
+// DeleteAdvert mocks base method.
+func (m *MockServices) DeleteAdvert(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAdvert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAdvert indicates an expected call of DeleteAdvert.
+func (mr *MockServicesMockRecorder) DeleteAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdvert", reflect.TypeOf((*MockServices)(nil).DeleteAdvert), ctx, id)
+}
+
+// DeleteAnyAdvert mocks base method.
+func (m *MockServices) DeleteAnyAdvert(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAnyAdvert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAnyAdvert indicates an expected call of DeleteAnyAdvert.
+func (mr *MockServicesMockRecorder) DeleteAnyAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAnyAdvert", reflect.TypeOf((*MockServices)(nil).DeleteAnyAdvert), ctx, id)
+}
+
+// DeleteCategory mocks base method.
+func (m *MockServices) DeleteCategory(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCategory", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCategory indicates an expected call of DeleteCategory.
+func (mr *MockServicesMockRecorder) DeleteCategory(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCategory", reflect.TypeOf((*MockServices)(nil).DeleteCategory), ctx, id)
+}
+
+// FindDuplicateAdverts mocks base method.
+func (m *MockServices) FindDuplicateAdverts(ctx context.Context, id string) ([]models.DuplicateAdvert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDuplicateAdverts", ctx, id)
+	ret0, _ := ret[0].([]models.DuplicateAdvert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDuplicateAdverts indicates an expected call of FindDuplicateAdverts.
+func (mr *MockServicesMockRecorder) FindDuplicateAdverts(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicateAdverts", reflect.TypeOf((*MockServices)(nil).FindDuplicateAdverts), ctx, id)
+}
+
+// GetAdvertByID mocks base method.
+func (m *MockServices) GetAdvertByID(ctx context.Context, id string) (models.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdvertByID", ctx, id)
+	ret0, _ := ret[0].(models.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdvertByID indicates an expected call of GetAdvertByID.
+func (mr *MockServicesMockRecorder) GetAdvertByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertByID", reflect.TypeOf((*MockServices)(nil).GetAdvertByID), ctx, id)
+}
+
+// GetCategoryByID mocks base method.
+func (m *MockServices) GetCategoryByID(ctx context.Context, id string) (models.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryByID", ctx, id)
+	ret0, _ := ret[0].(models.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryByID indicates an expected call of GetCategoryByID.
+func (mr *MockServicesMockRecorder) GetCategoryByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryByID", reflect.TypeOf((*MockServices)(nil).GetCategoryByID), ctx, id)
+}
+
+// GetImageByID mocks base method.
+func (m *MockServices) GetImageByID(ctx context.Context, id, size, ifNoneMatch string) (models.ImageFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageByID", ctx, id, size, ifNoneMatch)
+	ret0, _ := ret[0].(models.ImageFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageByID indicates an expected call of GetImageByID.
+func (mr *MockServicesMockRecorder) GetImageByID(ctx, id, size, ifNoneMatch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockServices)(nil).GetImageByID), ctx, id, size, ifNoneMatch)
+}
+
+// GetProfile mocks base method.
+func (m *MockServices) GetProfile(ctx context.Context) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfile", ctx)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockServicesMockRecorder) GetProfile(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockServices)(nil).GetProfile), ctx)
+}
+
+// GetPublicProfile mocks base method.
+func (m *MockServices) GetPublicProfile(ctx context.Context, id string) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPublicProfile", ctx, id)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPublicProfile indicates an expected call of GetPublicProfile.
+func (mr *MockServicesMockRecorder) GetPublicProfile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPublicProfile", reflect.TypeOf((*MockServices)(nil).GetPublicProfile), ctx, id)
+}
+
+// GetUpload mocks base method.
+func (m *MockServices) GetUpload(ctx context.Context, id string) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", ctx, id)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockServicesMockRecorder) GetUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockServices)(nil).GetUpload), ctx, id)
+}
+
+// ListAdverts mocks base method.
+func (m *MockServices) ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdverts", ctx, params)
+	ret0, _ := ret[0].([]models.Advert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAdverts indicates an expected call of ListAdverts.
+func (mr *MockServicesMockRecorder) ListAdverts(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdverts", reflect.TypeOf((*MockServices)(nil).ListAdverts), ctx, params)
+}
+
+// ListCategories mocks base method.
+func (m *MockServices) ListCategories(ctx context.Context) ([]*models.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCategories", ctx)
+	ret0, _ := ret[0].([]*models.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCategories indicates an expected call of ListCategories.
+func (mr *MockServicesMockRecorder) ListCategories(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCategories", reflect.TypeOf((*MockServices)(nil).ListCategories), ctx)
+}
+
+// ListFavourites mocks base method.
+func (m *MockServices) ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFavourites", ctx, params)
+	ret0, _ := ret[0].([]models.FavouriteAdvert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListFavourites indicates an expected call of ListFavourites.
+func (mr *MockServicesMockRecorder) ListFavourites(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFavourites", reflect.TypeOf((*MockServices)(nil).ListFavourites), ctx, params)
+}
+
+// ListMessages mocks base method.
+func (m *MockServices) ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessages", ctx, params)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMessages indicates an expected call of ListMessages.
+func (mr *MockServicesMockRecorder) ListMessages(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockServices)(nil).ListMessages), ctx, params)
+}
+
+// ListModerationQueue mocks base method.
+func (m *MockServices) ListModerationQueue(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModerationQueue", ctx, params)
+	ret0, _ := ret[0].([]models.Advert)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListModerationQueue indicates an expected call of ListModerationQueue.
+func (mr *MockServicesMockRecorder) ListModerationQueue(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModerationQueue", reflect.TypeOf((*MockServices)(nil).ListModerationQueue), ctx, params)
+}
+
+// ListSessions mocks base method.
+func (m *MockServices) ListSessions(ctx context.Context) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockServicesMockRecorder) ListSessions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockServices)(nil).ListSessions), ctx)
+}
+
+// ListThreads mocks base method.
+func (m *MockServices) ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListThreads", ctx, params)
+	ret0, _ := ret[0].([]models.Thread)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListThreads indicates an expected call of ListThreads.
+func (mr *MockServicesMockRecorder) ListThreads(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListThreads", reflect.TypeOf((*MockServices)(nil).ListThreads), ctx, params)
+}
+
+// Logout mocks base method.
+func (m *MockServices) Logout(ctx context.Context, token models.AccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockServicesMockRecorder) Logout(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockServices)(nil).Logout), ctx, token)
+}
+
+// LogoutAll mocks base method.
+func (m *MockServices) LogoutAll(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockServicesMockRecorder) LogoutAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockServices)(nil).LogoutAll), ctx)
+}
+
+// ProcessImageJobs mocks base method.
+func (m *MockServices) ProcessImageJobs(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessImageJobs", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessImageJobs indicates an expected call of ProcessImageJobs.
+func (mr *MockServicesMockRecorder) ProcessImageJobs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessImageJobs", reflect.TypeOf((*MockServices)(nil).ProcessImageJobs), ctx)
+}
+
+// PurgeExpiredTokens mocks base method.
+func (m *MockServices) PurgeExpiredTokens(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpiredTokens", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpiredTokens indicates an expected call of PurgeExpiredTokens.
+func (mr *MockServicesMockRecorder) PurgeExpiredTokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpiredTokens", reflect.TypeOf((*MockServices)(nil).PurgeExpiredTokens), ctx)
+}
+
+// PurgeStaleUploads mocks base method.
+func (m *MockServices) PurgeStaleUploads(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeStaleUploads", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeStaleUploads indicates an expected call of PurgeStaleUploads.
+func (mr *MockServicesMockRecorder) PurgeStaleUploads(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeStaleUploads", reflect.TypeOf((*MockServices)(nil).PurgeStaleUploads), ctx)
+}
+
+// PurgeUnattachedImages mocks base method.
+func (m *MockServices) PurgeUnattachedImages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUnattachedImages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeUnattachedImages indicates an expected call of PurgeUnattachedImages.
+func (mr *MockServicesMockRecorder) PurgeUnattachedImages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUnattachedImages", reflect.TypeOf((*MockServices)(nil).PurgeUnattachedImages), ctx)
+}
+
+// Refresh mocks base method.
+func (m *MockServices) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(models.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockServicesMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockServices)(nil).Refresh), ctx, refreshToken)
+}
+
+// RejectAdvert mocks base method.
+func (m *MockServices) RejectAdvert(ctx context.Context, id, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAdvert", ctx, id, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectAdvert indicates an expected call of RejectAdvert.
+func (mr *MockServicesMockRecorder) RejectAdvert(ctx, id, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdvert", reflect.TypeOf((*MockServices)(nil).RejectAdvert), ctx, id, reason)
+}
+
+// RemoveFavourite mocks base method.
+func (m *MockServices) RemoveFavourite(ctx context.Context, advertID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFavourite", ctx, advertID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFavourite indicates an expected call of RemoveFavourite.
+func (mr *MockServicesMockRecorder) RemoveFavourite(ctx, advertID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFavourite", reflect.TypeOf((*MockServices)(nil).RemoveFavourite), ctx, advertID)
+}
+
+// RenewAdvert mocks base method.
+func (m *MockServices) RenewAdvert(ctx context.Context, id string) (models.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewAdvert", ctx, id)
+	ret0, _ := ret[0].(models.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenewAdvert indicates an expected call of RenewAdvert.
+func (mr *MockServicesMockRecorder) RenewAdvert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewAdvert", reflect.TypeOf((*MockServices)(nil).RenewAdvert), ctx, id)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockServices) RequestPasswordReset(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockServicesMockRecorder) RequestPasswordReset(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockServices)(nil).RequestPasswordReset), ctx, email)
+}
+
+// ResendVerificationEmail mocks base method.
+func (m *MockServices) ResendVerificationEmail(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerificationEmail", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerificationEmail indicates an expected call of ResendVerificationEmail.
+func (mr *MockServicesMockRecorder) ResendVerificationEmail(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerificationEmail", reflect.TypeOf((*MockServices)(nil).ResendVerificationEmail), ctx)
+}
+
+// ResetPassword mocks base method.
+func (m *MockServices) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockServicesMockRecorder) ResetPassword(ctx, token, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockServices)(nil).ResetPassword), ctx, token, password)
+}
+
+// ReshardImages mocks base method.
+func (m *MockServices) ReshardImages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReshardImages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReshardImages indicates an expected call of ReshardImages.
+func (mr *MockServicesMockRecorder) ReshardImages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReshardImages", reflect.TypeOf((*MockServices)(nil).ReshardImages), ctx)
+}
+
+// RevokeSession mocks base method.
+func (m *MockServices) RevokeSession(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockServicesMockRecorder) RevokeSession(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockServices)(nil).RevokeSession), ctx, id)
+}
+
+// SearchAdverts mocks base method.
+func (m *MockServices) SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAdverts", ctx, params)
+	ret0, _ := ret[0].([]models.AdvertSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAdverts indicates an expected call of SearchAdverts.
+func (mr *MockServicesMockRecorder) SearchAdverts(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAdverts", reflect.TypeOf((*MockServices)(nil).SearchAdverts), ctx, params)
+}
+
+// SendAdvertMessage mocks base method.
+func (m *MockServices) SendAdvertMessage(ctx context.Context, advertID, body string) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendAdvertMessage", ctx, advertID, body)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendAdvertMessage indicates an expected call of SendAdvertMessage.
+func (mr *MockServicesMockRecorder) SendAdvertMessage(ctx, advertID, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendAdvertMessage", reflect.TypeOf((*MockServices)(nil).SendAdvertMessage), ctx, advertID, body)
+}
+
+// SendThreadMessage mocks base method.
+func (m *MockServices) SendThreadMessage(ctx context.Context, threadID, body string) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendThreadMessage", ctx, threadID, body)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendThreadMessage indicates an expected call of SendThreadMessage.
+func (mr *MockServicesMockRecorder) SendThreadMessage(ctx, threadID, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendThreadMessage", reflect.TypeOf((*MockServices)(nil).SendThreadMessage), ctx, threadID, body)
+}
+
+// SetUserRole mocks base method.
+func (m *MockServices) SetUserRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockServicesMockRecorder) SetUserRole(ctx, id, role interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockServices)(nil).GetImageByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockServices)(nil).SetUserRole), ctx, id, role)
 }
 
 // SignIn mocks base method.
-func (m *MockServices) SignIn(ctx context.Context, email, password string) (string, error) {
+func (m *MockServices) SignIn(ctx context.Context, email, password, userAgent string) (models.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignIn", ctx, email, password)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SignIn", ctx, email, password, userAgent)
+	ret0, _ := ret[0].(models.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignIn indicates an expected call of SignIn.
-func (mr *MockServicesMockRecorder) SignIn(ctx, email, password interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) SignIn(ctx, email, password, userAgent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockServices)(nil).SignIn), ctx, email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockServices)(nil).SignIn), ctx, email, password, userAgent)
 }
 
 // SignUp mocks base method.
@@ -281,3 +1785,119 @@ func (mr *MockServicesMockRecorder) SignUp(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockServices)(nil).SignUp), ctx, user)
 }
+
+// UnbanUser mocks base method.
+func (m *MockServices) UnbanUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnbanUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnbanUser indicates an expected call of UnbanUser.
+func (mr *MockServicesMockRecorder) UnbanUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnbanUser", reflect.TypeOf((*MockServices)(nil).UnbanUser), ctx, id)
+}
+
+// UnblockUser mocks base method.
+func (m *MockServices) UnblockUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnblockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnblockUser indicates an expected call of UnblockUser.
+func (mr *MockServicesMockRecorder) UnblockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockServices)(nil).UnblockUser), ctx, id)
+}
+
+// UpdateAdvert mocks base method.
+func (m *MockServices) UpdateAdvert(ctx context.Context, update models.AdvertUpdate) (models.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAdvert", ctx, update)
+	ret0, _ := ret[0].(models.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAdvert indicates an expected call of UpdateAdvert.
+func (mr *MockServicesMockRecorder) UpdateAdvert(ctx, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAdvert", reflect.TypeOf((*MockServices)(nil).UpdateAdvert), ctx, update)
+}
+
+// UpdateCategory mocks base method.
+func (m *MockServices) UpdateCategory(ctx context.Context, category models.Category) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCategory", ctx, category)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCategory indicates an expected call of UpdateCategory.
+func (mr *MockServicesMockRecorder) UpdateCategory(ctx, category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCategory", reflect.TypeOf((*MockServices)(nil).UpdateCategory), ctx, category)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockServices) UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, update)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockServicesMockRecorder) UpdateProfile(ctx, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockServices)(nil).UpdateProfile), ctx, update)
+}
+
+// UploadImage mocks base method.
+func (m *MockServices) UploadImage(ctx context.Context, image *models.Image) (models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadImage", ctx, image)
+	ret0, _ := ret[0].(models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadImage indicates an expected call of UploadImage.
+func (mr *MockServicesMockRecorder) UploadImage(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockServices)(nil).UploadImage), ctx, image)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockServices) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockServicesMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockServices)(nil).VerifyEmail), ctx, token)
+}
+
+// WriteUploadChunk mocks base method.
+func (m *MockServices) WriteUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (models.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteUploadChunk", ctx, id, offset, chunk)
+	ret0, _ := ret[0].(models.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteUploadChunk indicates an expected call of WriteUploadChunk.
+func (mr *MockServicesMockRecorder) WriteUploadChunk(ctx, id, offset, chunk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteUploadChunk", reflect.TypeOf((*MockServices)(nil).WriteUploadChunk), ctx, id, offset, chunk)
+}
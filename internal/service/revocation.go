@@ -0,0 +1,114 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// revocationCacheTTL is how long a negative lookup is trusted, so a logout
+	// made through another replica is noticed within this period.
+	revocationCacheTTL = 30 * time.Second
+	// revocationCacheSize is the number of entries after which expired ones are swept.
+	revocationCacheSize = 10000
+)
+
+type revocationEntry struct {
+	revoked    bool
+	validUntil time.Time
+}
+
+type versionEntry struct {
+	version    int
+	validUntil time.Time
+}
+
+// revocationCache keeps the results of revocation lookups so that authorizing a request
+// does not hit the database every time. Revoked tokens and sessions are remembered
+// until their access tokens expire.
+type revocationCache struct {
+	mu       sync.RWMutex
+	tokens   map[string]revocationEntry
+	sessions map[string]revocationEntry
+	versions map[string]versionEntry
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		tokens:   make(map[string]revocationEntry),
+		sessions: make(map[string]revocationEntry),
+		versions: make(map[string]versionEntry),
+	}
+}
+
+func (c *revocationCache) tokenRevoked(id string, now time.Time) (revoked bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return getRevocation(c.tokens, id, now)
+}
+
+func (c *revocationCache) setTokenRevoked(id string, revoked bool, validUntil, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	setRevocation(c.tokens, id, revoked, validUntil, now)
+}
+
+func (c *revocationCache) sessionRevoked(id string, now time.Time) (revoked bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return getRevocation(c.sessions, id, now)
+}
+
+func (c *revocationCache) setSessionRevoked(id string, revoked bool, validUntil, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	setRevocation(c.sessions, id, revoked, validUntil, now)
+}
+
+func (c *revocationCache) tokenVersion(userID string, now time.Time) (version int, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.versions[userID]
+	if !ok || now.After(entry.validUntil) {
+		return 0, false
+	}
+	return entry.version, true
+}
+
+func (c *revocationCache) setTokenVersion(userID string, version int, validUntil, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.versions) >= revocationCacheSize {
+		for key, entry := range c.versions {
+			if now.After(entry.validUntil) {
+				delete(c.versions, key)
+			}
+		}
+	}
+	c.versions[userID] = versionEntry{version: version, validUntil: validUntil}
+}
+
+func getRevocation(entries map[string]revocationEntry, id string, now time.Time) (revoked bool, ok bool) {
+	entry, ok := entries[id]
+	if !ok || now.After(entry.validUntil) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+func setRevocation(entries map[string]revocationEntry, id string, revoked bool, validUntil, now time.Time) {
+	if len(entries) >= revocationCacheSize {
+		for key, entry := range entries {
+			if now.After(entry.validUntil) {
+				delete(entries, key)
+			}
+		}
+	}
+	entries[id] = revocationEntry{revoked: revoked, validUntil: validUntil}
+}
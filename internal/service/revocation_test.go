@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRevocationCache(t *testing.T) {
+	cache := newRevocationCache()
+	now := time.Now()
+
+	tokenID := uuid.New().String()
+
+	_, ok := cache.tokenRevoked(tokenID, now)
+	require.False(t, ok)
+
+	cache.setTokenRevoked(tokenID, true, now.Add(time.Minute), now)
+
+	revoked, ok := cache.tokenRevoked(tokenID, now)
+	require.True(t, ok)
+	require.True(t, revoked)
+
+	_, ok = cache.tokenRevoked(tokenID, now.Add(2*time.Minute))
+	require.False(t, ok)
+
+	sessionID := uuid.New().String()
+
+	_, ok = cache.sessionRevoked(sessionID, now)
+	require.False(t, ok)
+
+	cache.setSessionRevoked(sessionID, true, now.Add(accessTokenTTL), now)
+
+	revoked, ok = cache.sessionRevoked(sessionID, now)
+	require.True(t, ok)
+	require.True(t, revoked)
+
+	userID := uuid.New().String()
+
+	cache.setTokenVersion(userID, 2, now.Add(revocationCacheTTL), now)
+
+	version, ok := cache.tokenVersion(userID, now)
+	require.True(t, ok)
+	require.Equal(t, 2, version)
+
+	_, ok = cache.tokenVersion(userID, now.Add(revocationCacheTTL+time.Second))
+	require.False(t, ok)
+}
@@ -4,42 +4,124 @@ package service
 
 import (
 	"context"
+	"github.com/romandnk/advertisement/configs"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/mailer"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/romandnk/advertisement/internal/storage"
 )
 
 type User interface {
 	SignUp(ctx context.Context, user models.User) (string, error)
-	SignIn(ctx context.Context, email, password string) (string, error)
+	SignIn(ctx context.Context, email, password, userAgent string) (models.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (models.Tokens, error)
+	ListSessions(ctx context.Context) ([]models.Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	CheckAccessToken(ctx context.Context, token models.AccessToken) error
+	Logout(ctx context.Context, token models.AccessToken) error
+	LogoutAll(ctx context.Context) error
+	PurgeExpiredTokens(ctx context.Context) (int, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	GetProfile(ctx context.Context) (models.User, error)
+	GetPublicProfile(ctx context.Context, id string) (models.User, error)
+	UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error)
+	BanUser(ctx context.Context, id string) error
+	UnbanUser(ctx context.Context, id string) error
+	SetUserRole(ctx context.Context, id, role string) error
 }
 
 type Advert interface {
 	CreateAdvert(ctx context.Context, advert models.Advert) (string, error)
+	UpdateAdvert(ctx context.Context, update models.AdvertUpdate) (models.Advert, error)
 	DeleteAdvert(ctx context.Context, id string) error
+	DeleteAnyAdvert(ctx context.Context, id string) error
 	GetAdvertByID(ctx context.Context, id string) (models.Advert, error)
+	ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error)
+	SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error)
+	ChangeAdvertStatus(ctx context.Context, id, status string) error
+	ListModerationQueue(ctx context.Context, params models.AdvertListParams) ([]models.Advert, string, error)
+	ApproveAdvert(ctx context.Context, id string) error
+	RejectAdvert(ctx context.Context, id, reason string) error
+	FindDuplicateAdverts(ctx context.Context, id string) ([]models.DuplicateAdvert, error)
+	RenewAdvert(ctx context.Context, id string) (models.Advert, error)
+	ArchiveExpiredAdverts(ctx context.Context) (int, error)
+}
+
+type Category interface {
+	CreateCategory(ctx context.Context, category models.Category) (string, error)
+	GetCategoryByID(ctx context.Context, id string) (models.Category, error)
+	ListCategories(ctx context.Context) ([]*models.Category, error)
+	UpdateCategory(ctx context.Context, category models.Category) error
+	DeleteCategory(ctx context.Context, id string) error
 }
 
 type Image interface {
-	GetImageByID(ctx context.Context, id string) (models.Image, error)
+	UploadImage(ctx context.Context, image *models.Image) (models.Image, error)
+	GetImageByID(ctx context.Context, id, size, ifNoneMatch string) (models.ImageFile, error)
+	ReshardImages(ctx context.Context) (int, error)
+	CollectOrphanedImages(ctx context.Context, dryRun bool) (models.ImageGCResult, error)
+	ProcessImageJobs(ctx context.Context) (int, error)
+	PurgeUnattachedImages(ctx context.Context) (int, error)
+}
+
+type Upload interface {
+	CreateUpload(ctx context.Context, length int64) (models.Upload, error)
+	GetUpload(ctx context.Context, id string) (models.Upload, error)
+	WriteUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (models.Upload, error)
+	PurgeStaleUploads(ctx context.Context) (int, error)
+}
+
+type Favourite interface {
+	AddFavourite(ctx context.Context, advertID string) error
+	RemoveFavourite(ctx context.Context, advertID string) error
+	ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, string, error)
+}
+
+type Message interface {
+	SendAdvertMessage(ctx context.Context, advertID, body string) (models.Message, error)
+	SendThreadMessage(ctx context.Context, threadID, body string) (models.Message, error)
+	ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, string, error)
+	ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, string, error)
+	CountUnreadMessages(ctx context.Context) (int, error)
+	BlockUser(ctx context.Context, id string) error
+	UnblockUser(ctx context.Context, id string) error
 }
 
 type Services interface {
 	User
 	Advert
+	Category
 	Image
+	Upload
+	Favourite
+	Message
 }
 
 type Service struct {
 	User
 	Advert
+	Category
 	Image
+	Upload
+	Favourite
+	Message
 }
 
-func NewService(storage storage.Storage, logger logger.Logger, secretKey, pathToImages string) *Service {
+func NewService(storage storage.Storage, blob blob.BlobStore, mailer mailer.Mailer, logger logger.Logger, config *configs.Config) *Service {
 	return &Service{
-		NewUserService(storage, logger, secretKey),
-		NewAdvertService(storage, logger, pathToImages),
-		NewImageService(storage, logger, pathToImages),
+		NewUserService(storage, storage, storage, storage, mailer, logger, blob, config.ImageFormats, config.ImageMaxPixels,
+			config.SecretKey, config.PublicURL, config.TokenPurge.BatchSize),
+		NewAdvertService(storage, storage, storage, storage, storage, mailer, logger, blob, config.ImageFormats, config.ImageMaxPixels,
+			config.ImageDuplicates.MaxDistance, config.PublicURL, config.AdvertExpiry.TTL, config.AdvertExpiry.BatchSize),
+		NewCategoryService(storage, logger),
+		NewImageService(storage, logger, blob, config.ImageFormats, config.ImageMaxPixels, config.ImageGC,
+			config.ImageDuplicates, config.ImageJobs, config.UploadPurge),
+		NewUploadService(storage, logger, blob, config.UploadMaxSize, config.UploadPurge.TTL),
+		NewFavouriteService(storage, storage, logger),
+		NewMessageService(storage, storage, logger),
 	}
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/blob"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrUploadServiceInvalidLength  = errors.New("upload length must be positive and not greater than the max upload size")
+	ErrUploadServiceOffsetMismatch = errors.New("offset does not match the received bytes")
+	ErrUploadServiceChunkTooLong   = errors.New("chunk exceeds the upload length")
+	ErrUploadServiceIncomplete     = errors.New("upload is not complete")
+)
+
+// UploadService receives large files in chunks, so a broken connection costs only the current chunk.
+// Complete uploads are attached to adverts by their ids.
+type UploadService struct {
+	upload  storage.UploadStorage
+	logger  logger.Logger
+	blob    blob.BlobStore
+	maxSize int64
+	// uploads not written to within ttl are purged
+	ttl time.Duration
+}
+
+func NewUploadService(upload storage.UploadStorage, logger logger.Logger, blob blob.BlobStore, maxSize int64, ttl time.Duration) *UploadService {
+	return &UploadService{
+		upload:  upload,
+		logger:  logger,
+		blob:    blob,
+		maxSize: maxSize,
+		ttl:     ttl,
+	}
+}
+
+func (u *UploadService) CreateUpload(ctx context.Context, length int64) (models.Upload, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Upload{}, err
+	}
+
+	if length <= 0 || length > u.maxSize {
+		return models.Upload{}, custom_error.CustomError{Field: "Upload-Length", Message: ErrUploadServiceInvalidLength.Error()}
+	}
+
+	now := time.Now()
+	upload := models.Upload{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Length:    length,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := u.upload.CreateUpload(ctx, upload); err != nil {
+		return models.Upload{}, err
+	}
+
+	return upload, nil
+}
+
+// GetUpload reports the progress of an upload of the caller.
+func (u *UploadService) GetUpload(ctx context.Context, id string) (models.Upload, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Upload{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.Upload{}, err
+	}
+
+	return u.upload.GetUpload(ctx, parsedID.String(), userID)
+}
+
+// WriteUploadChunk stores the chunk which must start exactly where the received bytes end.
+// The last chunk assembles the upload.
+func (u *UploadService) WriteUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (models.Upload, error) {
+	upload, err := u.GetUpload(ctx, id)
+	if err != nil {
+		return models.Upload{}, err
+	}
+
+	if offset != upload.Offset {
+		return models.Upload{}, custom_error.CustomError{Field: "Upload-Offset", Message: ErrUploadServiceOffsetMismatch.Error()}
+	}
+
+	end := offset + int64(len(chunk))
+	if end > upload.Length {
+		return models.Upload{}, custom_error.CustomError{Field: "chunk", Message: ErrUploadServiceChunkTooLong.Error()}
+	}
+	if end == offset {
+		return upload, nil
+	}
+
+	// the bytes are claimed before the chunk is stored, of two requests with the same offset
+	// the second one fails here instead of overwriting the chunk of the first
+	now := time.Now()
+	err = u.upload.AdvanceUpload(ctx, upload.ID, upload.UserID, offset, end, now)
+	if err != nil {
+		return models.Upload{}, err
+	}
+
+	err = u.blob.Put(ctx, uploadChunkKey(upload.ID, offset), bytes.NewReader(chunk), int64(len(chunk)), "application/octet-stream")
+	if err != nil {
+		if err := u.upload.AdvanceUpload(ctx, upload.ID, upload.UserID, end, offset, time.Now()); err != nil {
+			u.logger.Error("error releasing upload chunk", zap.String("upload_id", upload.ID), zap.String("error", err.Error()))
+		}
+		return models.Upload{}, err
+	}
+
+	upload.Offset = end
+	upload.UpdatedAt = now
+
+	// if assembling fails here, it is repeated when the upload is attached
+	if upload.Offset == upload.Length {
+		if err := assembleUpload(ctx, u.blob, upload); err != nil {
+			u.logger.Error("error assembling upload", zap.String("upload_id", upload.ID), zap.String("error", err.Error()))
+		}
+	}
+
+	return upload, nil
+}
+
+// PurgeStaleUploads deletes uploads not written to for longer than ttl, both abandoned and complete but never attached.
+// It returns the number of purged uploads.
+func (u *UploadService) PurgeStaleUploads(ctx context.Context) (int, error) {
+	var purged int
+
+	for {
+		uploads, err := u.upload.DeleteStaleUploads(ctx, time.Now().Add(-u.ttl), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, upload := range uploads {
+			if err := deleteUploadFiles(ctx, u.blob, upload); err != nil {
+				u.logger.Error("error deleting stale upload", zap.String("upload_id", upload.ID), zap.String("error", err.Error()))
+			}
+		}
+		purged += len(uploads)
+
+		if len(uploads) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"github.com/romandnk/advertisement/internal/blob"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// testUploadStorage keeps uploads in memory, like the database it advances an offset only from its current value.
+type testUploadStorage struct {
+	storage.UploadStorage
+	uploads map[string]models.Upload
+}
+
+func (s testUploadStorage) CreateUpload(ctx context.Context, upload models.Upload) error {
+	s.uploads[upload.ID] = upload
+	return nil
+}
+
+func (s testUploadStorage) GetUpload(ctx context.Context, id, userID string) (models.Upload, error) {
+	upload, ok := s.uploads[id]
+	if !ok || upload.UserID != userID {
+		return models.Upload{}, custom_error.CustomError{Field: "id", Message: "upload not found"}
+	}
+	return upload, nil
+}
+
+func (s testUploadStorage) AdvanceUpload(ctx context.Context, id, userID string, from, to int64, updatedAt time.Time) error {
+	upload := s.uploads[id]
+	if upload.Offset != from {
+		return custom_error.CustomError{Field: "Upload-Offset", Message: "upload offset does not match the received bytes"}
+	}
+	upload.Offset = to
+	upload.UpdatedAt = updatedAt
+	s.uploads[id] = upload
+	return nil
+}
+
+func (s testUploadStorage) DeleteStaleUploads(ctx context.Context, before time.Time, limit int) ([]models.Upload, error) {
+	var uploads []models.Upload
+	for id, upload := range s.uploads {
+		if len(uploads) < limit && upload.UpdatedAt.Before(before) {
+			uploads = append(uploads, upload)
+			delete(s.uploads, id)
+		}
+	}
+	return uploads, nil
+}
+
+func TestUploadServiceWriteUploadChunk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	store := blob.NewFileSystemStore(t.TempDir())
+	uploads := testUploadStorage{uploads: make(map[string]models.Upload)}
+	service := NewUploadService(uploads, logger, store, 100, time.Hour)
+
+	ctx := context.WithValue(context.Background(), "user_id", "user id")
+
+	data := []byte("chunked upload data")
+
+	upload, err := service.CreateUpload(ctx, int64(len(data)))
+	require.NoError(t, err)
+
+	upload, err = service.WriteUploadChunk(ctx, upload.ID, 0, data[:7])
+	require.NoError(t, err)
+	require.Equal(t, int64(7), upload.Offset)
+
+	// a retried chunk whose response was lost
+	_, err = service.WriteUploadChunk(ctx, upload.ID, 0, data[:7])
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "Upload-Offset", Message: ErrUploadServiceOffsetMismatch.Error()})
+
+	_, err = service.WriteUploadChunk(ctx, upload.ID, 7, append(data[7:], 'x'))
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "chunk", Message: ErrUploadServiceChunkTooLong.Error()})
+
+	_, err = service.GetUpload(context.WithValue(context.Background(), "user_id", "another user id"), upload.ID)
+	require.Error(t, err)
+
+	upload, err = service.WriteUploadChunk(ctx, upload.ID, 7, data[7:])
+	require.NoError(t, err)
+	require.Equal(t, upload.Length, upload.Offset)
+
+	assembled, err := readUpload(ctx, store, upload)
+	require.NoError(t, err)
+	require.Equal(t, data, assembled)
+
+	_, err = store.Get(ctx, uploadChunkKey(upload.ID, 0))
+	require.Error(t, err, "chunks are removed after assembling")
+}
+
+func TestUploadServiceCreateUploadInvalidLength(t *testing.T) {
+	service := NewUploadService(testUploadStorage{uploads: make(map[string]models.Upload)}, nil, nil, 100, time.Hour)
+
+	ctx := context.WithValue(context.Background(), "user_id", "user id")
+
+	for _, length := range []int64{0, -1, 101} {
+		_, err := service.CreateUpload(ctx, length)
+		require.ErrorIs(t, err, custom_error.CustomError{Field: "Upload-Length", Message: ErrUploadServiceInvalidLength.Error()})
+	}
+}
+
+func TestUploadServicePurgeStaleUploads(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	store := blob.NewFileSystemStore(t.TempDir())
+	uploads := testUploadStorage{uploads: make(map[string]models.Upload)}
+	service := NewUploadService(uploads, logger, store, 100, time.Hour)
+
+	ctx := context.WithValue(context.Background(), "user_id", "user id")
+
+	stale, err := service.CreateUpload(ctx, 10)
+	require.NoError(t, err)
+	_, err = service.WriteUploadChunk(ctx, stale.ID, 0, []byte("abc"))
+	require.NoError(t, err)
+	_, err = service.WriteUploadChunk(ctx, stale.ID, 3, []byte("defg"))
+	require.NoError(t, err)
+
+	// the upload was last written to before the ttl
+	upload := uploads.uploads[stale.ID]
+	upload.UpdatedAt = time.Now().Add(-2 * time.Hour)
+	uploads.uploads[stale.ID] = upload
+
+	fresh, err := service.CreateUpload(ctx, 10)
+	require.NoError(t, err)
+	_, err = service.WriteUploadChunk(ctx, fresh.ID, 0, []byte("abc"))
+	require.NoError(t, err)
+
+	purged, err := service.PurgeStaleUploads(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	for _, offset := range []int64{0, 3} {
+		_, err = store.Stat(ctx, uploadChunkKey(stale.ID, offset))
+		require.ErrorIs(t, err, blob.ErrNotFound)
+	}
+
+	_, err = store.Stat(ctx, uploadChunkKey(fresh.ID, 0))
+	require.NoError(t, err)
+	require.Contains(t, uploads.uploads, fresh.ID)
+}
@@ -4,27 +4,77 @@ import (
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/logger"
+	"github.com/romandnk/advertisement/internal/mailer"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/romandnk/advertisement/internal/storage"
+	"go.uber.org/zap"
 	"net/mail"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-var ErrUserServiceInvalidPassword = errors.New("invalid password")
+var (
+	ErrUserServiceInvalidPassword     = errors.New("invalid password")
+	ErrUserServiceInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrUserServiceRevokedToken        = errors.New("token has been revoked")
+	ErrUserServiceEmptyToken          = errors.New("empty token")
+	ErrUserServiceEmailVerified       = errors.New("email is already verified")
+	ErrUserServiceNotFound            = errors.New("user not found")
+	ErrUserServiceLongDisplayName     = errors.New("max display name length is 100")
+	ErrUserServiceInvalidPhone        = errors.New("phone must contain from 10 to 15 digits and may start with +")
+	ErrUserServiceLongCity            = errors.New("max city length is 100")
+	ErrUserServiceBanned              = errors.New("user is banned")
+	ErrUserServiceSelfModeration      = errors.New("you cannot ban or change the role of yourself")
+	ErrUserServiceInvalidRole         = errors.New("role must be user, moderator or admin")
+)
+
+const (
+	accessTokenTTL      = time.Hour
+	refreshTokenTTL     = 30 * 24 * time.Hour
+	verifyEmailTokenTTL = 24 * time.Hour
+
+	resetPasswordTokenTTL = time.Hour
+	sendEmailTimeout      = 30 * time.Second
+)
 
 type UserService struct {
-	user      storage.UserStorage
-	logger    logger.Logger
-	secretKey string
+	user           storage.UserStorage
+	session        storage.SessionStorage
+	token          storage.TokenStorage
+	userToken      storage.UserTokenStorage
+	cache          *revocationCache
+	mailer         mailer.Mailer
+	logger         logger.Logger
+	imageFormats   map[string]struct{}
+	imageMaxPixels int
+	secretKey      string
+	publicURL      string
+	blob           blob.BlobStore
+	purgeBatchSize int
 }
 
-func NewUserService(user storage.UserStorage, logger logger.Logger, secretKey string) *UserService {
+func NewUserService(user storage.UserStorage, session storage.SessionStorage, token storage.TokenStorage,
+	userToken storage.UserTokenStorage, mailer mailer.Mailer, logger logger.Logger, blob blob.BlobStore, imageFormats []string, imageMaxPixels int, secretKey, publicURL string,
+	purgeBatchSize int) *UserService {
 	return &UserService{
-		user:      user,
-		logger:    logger,
-		secretKey: secretKey,
+		user:           user,
+		session:        session,
+		token:          token,
+		userToken:      userToken,
+		cache:          newRevocationCache(),
+		mailer:         mailer,
+		logger:         logger,
+		imageFormats:   newImageFormats(imageFormats),
+		imageMaxPixels: imageMaxPixels,
+		secretKey:      secretKey,
+		publicURL:      publicURL,
+		blob:           blob,
+		purgeBatchSize: purgeBatchSize,
 	}
 }
 
@@ -50,31 +100,667 @@ func (u *UserService) SignUp(ctx context.Context, user models.User) (string, err
 
 	user.Password = hashedPassword
 
-	return u.user.CreateUser(ctx, user)
+	id, err := u.user.CreateUser(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	// the account is already created, the user can ask for another email if this one is lost
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		u.logger.Error("error sending verification email",
+			zap.String("user_id", user.ID),
+			zap.String("error", err.Error()),
+		)
+	}
+
+	return id, nil
+}
+
+// VerifyEmail confirms the email of the user the verification token was sent to.
+func (u *UserService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return custom_error.CustomError{Field: "token", Message: ErrUserServiceEmptyToken.Error()}
+	}
+
+	now := time.Now()
+
+	userToken, err := u.userToken.UseUserToken(ctx, hashToken(token), models.UserTokenPurposeVerifyEmail, now)
+	if err != nil {
+		return err
+	}
+
+	return u.user.SetEmailVerified(ctx, userToken.UserID, now)
 }
 
-func (u *UserService) SignIn(ctx context.Context, email, password string) (string, error) {
+func (u *UserService) ResendVerificationEmail(ctx context.Context) error {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.user.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return custom_error.CustomError{Field: "email", Message: ErrUserServiceEmailVerified.Error()}
+	}
+
+	return u.sendVerificationEmail(ctx, user)
+}
+
+func (u *UserService) SignIn(ctx context.Context, email, password, userAgent string) (models.Tokens, error) {
 	user, err := u.user.GetUserByEmail(ctx, email)
 	if err != nil {
 		var customError custom_error.CustomError
 		if errors.As(err, &customError) {
-			return "", err
+			return models.Tokens{}, err
 		}
-		return "", custom_error.CustomError{Field: "", Message: err.Error()}
+		return models.Tokens{}, custom_error.CustomError{Field: "", Message: err.Error()}
 	}
 
 	if user.Deleted == true {
-		return "", nil
+		return models.Tokens{}, nil
 	}
 
 	if !comparePassword(password, user.Password) {
-		return "", custom_error.CustomError{Field: "password", Message: ErrUserServiceInvalidPassword.Error()}
+		return models.Tokens{}, custom_error.CustomError{Field: "password", Message: ErrUserServiceInvalidPassword.Error()}
+	}
+
+	if user.Banned {
+		return models.Tokens{}, custom_error.CustomError{Field: "email", Message: ErrUserServiceBanned.Error()}
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		UserAgent:  truncateUserAgent(userAgent),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+
+	refreshToken, refreshTokenHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	session.RefreshTokenHash = refreshTokenHash
+
+	if err := u.session.CreateSession(ctx, session); err != nil {
+		return models.Tokens{}, err
+	}
+
+	accessToken, err := createJWT([]byte(u.secretKey), user, session.ID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	return models.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Every refresh token is single-use:
+// presenting an already rotated token means it was stolen, so the whole session is revoked.
+func (u *UserService) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	invalidToken := custom_error.CustomError{Field: "refresh_token", Message: ErrUserServiceInvalidRefreshToken.Error()}
+
+	sessionID, secret, err := parseRefreshToken(refreshToken)
+	if err != nil {
+		return models.Tokens{}, invalidToken
+	}
+
+	session, err := u.session.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		var customError custom_error.CustomError
+		if errors.As(err, &customError) {
+			return models.Tokens{}, invalidToken
+		}
+		return models.Tokens{}, err
+	}
+
+	now := time.Now()
+	if session.Revoked || now.After(session.ExpiresAt) {
+		return models.Tokens{}, invalidToken
+	}
+
+	if hashToken(secret) != session.RefreshTokenHash {
+		u.revokeReusedSession(ctx, session)
+		return models.Tokens{}, invalidToken
+	}
+
+	user, err := u.user.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	if user.Deleted || user.Banned {
+		return models.Tokens{}, invalidToken
+	}
+
+	newToken, newTokenHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	oldHash := session.RefreshTokenHash
+	session.RefreshTokenHash = newTokenHash
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(refreshTokenTTL)
+
+	if err := u.session.RotateSession(ctx, session, oldHash); err != nil {
+		var customError custom_error.CustomError
+		if errors.As(err, &customError) {
+			// the same token has just been used by a concurrent request
+			u.revokeReusedSession(ctx, session)
+			return models.Tokens{}, invalidToken
+		}
+		return models.Tokens{}, err
+	}
+
+	accessToken, err := createJWT([]byte(u.secretKey), user, session.ID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	return models.Tokens{AccessToken: accessToken, RefreshToken: newToken}, nil
+}
+
+func (u *UserService) ListSessions(ctx context.Context) ([]models.Session, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return u.session.ListSessions(ctx, userID, time.Now())
+}
+
+func (u *UserService) RevokeSession(ctx context.Context, id string) error {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = u.session.RevokeSession(ctx, parsedID.String(), userID)
+	if err != nil {
+		return err
+	}
+
+	// access tokens of the session stop working at once, not when they expire
+	now := time.Now()
+	u.cache.setSessionRevoked(parsedID.String(), true, now.Add(accessTokenTTL), now)
+
+	return nil
+}
+
+func (u *UserService) GetProfile(ctx context.Context) (models.User, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return u.user.GetUserByID(ctx, userID)
+}
+
+// GetPublicProfile returns a user which can be shown to anyone, deleted users are not found.
+func (u *UserService) GetPublicProfile(ctx context.Context, id string) (models.User, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.User{}, custom_error.CustomError{Field: "id", Message: err.Error()}
+	}
+
+	user, err := u.user.GetUserByID(ctx, parsedID.String())
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user.Deleted {
+		return models.User{}, custom_error.CustomError{Field: "id", Message: ErrUserServiceNotFound.Error()}
+	}
+
+	return user, nil
+}
+
+func (u *UserService) UpdateProfile(ctx context.Context, update models.UserProfileUpdate) (models.User, error) {
+	var err error
+
+	update.ID, err = getUserID(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if update.DisplayName != nil {
+		displayName := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(displayName) > 100 {
+			return models.User{}, custom_error.CustomError{Field: "display_name", Message: ErrUserServiceLongDisplayName.Error()}
+		}
+		update.DisplayName = &displayName
+	}
+
+	if update.Phone != nil {
+		phone, err := normalizePhone(*update.Phone)
+		if err != nil {
+			return models.User{}, err
+		}
+		update.Phone = &phone
+	}
+
+	if update.City != nil {
+		city := strings.TrimSpace(*update.City)
+		if utf8.RuneCountInString(city) > 100 {
+			return models.User{}, custom_error.CustomError{Field: "city", Message: ErrUserServiceLongCity.Error()}
+		}
+		update.City = &city
+	}
+
+	now := time.Now()
+	update.UpdatedAt = now
+
+	if update.Avatar != nil {
+		if err := validateImageFormat("avatar", update.Avatar, u.imageFormats); err != nil {
+			return models.User{}, err
+		}
+		if err := prepareImage("avatar", update.Avatar, u.imageMaxPixels); err != nil {
+			return models.User{}, err
+		}
+		update.Avatar.ID = uuid.New().String()
+		update.Avatar.CreatedAt = now
+		update.Avatar.Status = models.ImageStatusReady
+		err := saveImage(ctx, u.blob, update.Avatar)
+		if err != nil {
+			return models.User{}, custom_error.CustomError{Field: "avatar", Message: err.Error()}
+		}
+	}
+
+	oldAvatarID, err := u.user.UpdateUserProfile(ctx, update)
+	if err != nil {
+		if update.Avatar != nil {
+			if err := deleteImage(ctx, u.blob, update.Avatar.ID); err != nil {
+				u.logger.Error("error deleting avatar while updating profile", zap.String("error", err.Error()))
+			}
+		}
+		return models.User{}, err
+	}
+
+	if oldAvatarID != "" {
+		if err := deleteImage(ctx, u.blob, oldAvatarID); err != nil {
+			u.logger.Error("error deleting old avatar", zap.String("error", err.Error()))
+		}
+	}
+
+	return u.user.GetUserByID(ctx, update.ID)
+}
+
+// BanUser forbids the user to sign in and invalidates all their sessions and tokens.
+func (u *UserService) BanUser(ctx context.Context, id string) error {
+	parsedID, err := u.parseModeratedUserID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	user, err := u.user.BanUser(ctx, parsedID, now)
+	if err != nil {
+		return err
+	}
+
+	u.cache.setTokenVersion(user.ID, user.TokenVersion, now.Add(revocationCacheTTL), now)
+
+	return nil
+}
+
+func (u *UserService) UnbanUser(ctx context.Context, id string) error {
+	parsedID, err := u.parseModeratedUserID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return u.user.UnbanUser(ctx, parsedID, time.Now())
+}
+
+func (u *UserService) SetUserRole(ctx context.Context, id, role string) error {
+	parsedID, err := u.parseModeratedUserID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch role {
+	case models.RoleUser, models.RoleModerator, models.RoleAdmin:
+	default:
+		return custom_error.CustomError{Field: "role", Message: ErrUserServiceInvalidRole.Error()}
+	}
+
+	now := time.Now()
+
+	version, err := u.user.SetUserRole(ctx, parsedID, role, now)
+	if err != nil {
+		return err
+	}
+
+	u.cache.setTokenVersion(parsedID, version, now.Add(revocationCacheTTL), now)
+
+	return nil
+}
+
+// parseModeratedUserID validates the id of a user an admin acts on, admins cannot act on themselves.
+func (u *UserService) parseModeratedUserID(ctx context.Context, id string) (string, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return "", custom_error.CustomError{Field: "id", Message: err.Error()}
 	}
 
-	token, err := createJWT([]byte(u.secretKey), user.ID)
+	userID, err := getUserID(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return token, nil
+	if parsedID.String() == userID {
+		return "", custom_error.CustomError{Field: "id", Message: ErrUserServiceSelfModeration.Error()}
+	}
+
+	return parsedID.String(), nil
+}
+
+// RequestPasswordReset emails a reset code to the user. To not reveal whether the email is registered
+// the result is the same for unknown emails, and the email is sent in the background so that
+// the response time does not give it away either.
+func (u *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return custom_error.CustomError{Field: "email", Message: err.Error()}
+	}
+
+	user, err := u.user.GetUserByEmail(ctx, email)
+	if err != nil {
+		var customError custom_error.CustomError
+		if !errors.As(err, &customError) || customError.Field != "email" {
+			u.logger.Error("error getting user for password reset", zap.String("error", err.Error()))
+		}
+		return nil
+	}
+
+	if user.Deleted {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendEmailTimeout)
+		defer cancel()
+
+		if err := u.sendPasswordResetEmail(ctx, user); err != nil {
+			u.logger.Error("error sending password reset email",
+				zap.String("user_id", user.ID),
+				zap.String("error", err.Error()),
+			)
+		}
+	}()
+
+	return nil
+}
+
+// ResetPassword sets a new password by the emailed code and signs the user out everywhere.
+func (u *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	if token == "" {
+		return custom_error.CustomError{Field: "token", Message: ErrUserServiceEmptyToken.Error()}
+	}
+
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return custom_error.CustomError{Field: "password", Message: err.Error()}
+	}
+
+	now := time.Now()
+
+	user, err := u.user.ResetPassword(ctx, hashToken(token), hashedPassword, now)
+	if err != nil {
+		return err
+	}
+
+	u.cache.setTokenVersion(user.ID, user.TokenVersion, now.Add(revocationCacheTTL), now)
+
+	return nil
+}
+
+// CheckAccessToken rejects tokens which were logged out, belong to a revoked session
+// or were issued before the user logged out everywhere.
+func (u *UserService) CheckAccessToken(ctx context.Context, token models.AccessToken) error {
+	revokedToken := custom_error.CustomError{Field: "token", Message: ErrUserServiceRevokedToken.Error()}
+
+	if token.ID == "" {
+		return revokedToken
+	}
+
+	now := time.Now()
+
+	revoked, ok := u.cache.tokenRevoked(token.ID, now)
+	if !ok {
+		var err error
+		revoked, err = u.token.IsTokenRevoked(ctx, token.ID)
+		if err != nil {
+			return err
+		}
+
+		validUntil := now.Add(revocationCacheTTL)
+		if revoked {
+			validUntil = token.ExpiresAt
+		}
+		u.cache.setTokenRevoked(token.ID, revoked, validUntil, now)
+	}
+	if revoked {
+		return revokedToken
+	}
+
+	if token.SessionID != "" {
+		revoked, err := u.isSessionRevoked(ctx, token.SessionID, now)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return revokedToken
+		}
+	}
+
+	version, ok := u.cache.tokenVersion(token.UserID, now)
+	if !ok {
+		user, err := u.user.GetUserByID(ctx, token.UserID)
+		if err != nil {
+			var customError custom_error.CustomError
+			if errors.As(err, &customError) {
+				return revokedToken
+			}
+			return err
+		}
+		if user.Deleted || user.Banned {
+			return revokedToken
+		}
+
+		version = user.TokenVersion
+		u.cache.setTokenVersion(token.UserID, version, now.Add(revocationCacheTTL), now)
+	}
+	if token.Version < version {
+		return revokedToken
+	}
+
+	return nil
+}
+
+// isSessionRevoked tells whether access tokens of the session must be rejected.
+// A revoked session is remembered for the lifetime of the access tokens issued for it.
+func (u *UserService) isSessionRevoked(ctx context.Context, id string, now time.Time) (bool, error) {
+	revoked, ok := u.cache.sessionRevoked(id, now)
+	if ok {
+		return revoked, nil
+	}
+
+	session, err := u.session.GetSessionByID(ctx, id)
+	if err != nil {
+		var customError custom_error.CustomError
+		if !errors.As(err, &customError) {
+			return false, err
+		}
+		session.Revoked = true
+	}
+
+	validUntil := now.Add(revocationCacheTTL)
+	if session.Revoked {
+		validUntil = now.Add(accessTokenTTL)
+	}
+	u.cache.setSessionRevoked(id, session.Revoked, validUntil, now)
+
+	return session.Revoked, nil
+}
+
+// Logout revokes the presented access token and the session it belongs to.
+func (u *UserService) Logout(ctx context.Context, token models.AccessToken) error {
+	if token.ID == "" {
+		return custom_error.CustomError{Field: "token", Message: ErrUserServiceRevokedToken.Error()}
+	}
+
+	if err := u.token.RevokeToken(ctx, token.ID, token.ExpiresAt); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	u.cache.setTokenRevoked(token.ID, true, token.ExpiresAt, now)
+
+	if token.SessionID != "" {
+		if err := u.session.RevokeSession(ctx, token.SessionID, token.UserID); err != nil {
+			var customError custom_error.CustomError
+			if !errors.As(err, &customError) {
+				return err
+			}
+		}
+		u.cache.setSessionRevoked(token.SessionID, true, now.Add(accessTokenTTL), now)
+	}
+
+	return nil
+}
+
+// PurgeExpiredTokens deletes expired entries of the revoked tokens list batch by batch.
+// It returns the number of deleted entries.
+func (u *UserService) PurgeExpiredTokens(ctx context.Context) (int, error) {
+	var purged int
+
+	for {
+		deleted, err := u.token.DeleteExpiredTokens(ctx, time.Now(), u.purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		purged += int(deleted)
+
+		if deleted < int64(u.purgeBatchSize) {
+			return purged, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+	}
+}
+
+// LogoutAll bumps the token version of the user, which outdates all issued access tokens,
+// and revokes all sessions so their refresh tokens stop working too.
+func (u *UserService) LogoutAll(ctx context.Context) error {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	version, err := u.user.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	u.cache.setTokenVersion(userID, version, now.Add(revocationCacheTTL), now)
+
+	return u.session.RevokeUserSessions(ctx, userID)
+}
+
+func (u *UserService) sendVerificationEmail(ctx context.Context, user models.User) error {
+	secret, err := u.createUserToken(ctx, user.ID, models.UserTokenPurposeVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := u.publicURL + "/api/v1/users/verify?token=" + url.QueryEscape(secret)
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение электронной почты",
+		Body: "Здравствуйте!\n\n" +
+			"Чтобы подтвердить адрес электронной почты, перейдите по ссылке:\n" +
+			link + "\n\n" +
+			"Ссылка действительна 24 часа. Если вы не регистрировались, просто проигнорируйте это письмо.",
+	})
+}
+
+func (u *UserService) sendPasswordResetEmail(ctx context.Context, user models.User) error {
+	secret, err := u.createUserToken(ctx, user.ID, models.UserTokenPurposeResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Восстановление пароля",
+		Body: "Здравствуйте!\n\n" +
+			"Код для восстановления пароля:\n" +
+			secret + "\n\n" +
+			"Код действителен 1 час и может быть использован один раз. " +
+			"Если вы не запрашивали восстановление пароля, просто проигнорируйте это письмо.",
+	})
+}
+
+// createUserToken stores the hash of a new single-use token and returns the token itself.
+func (u *UserService) createUserToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := models.UserToken{
+		Hash:      hashToken(secret),
+		UserID:    userID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if err := u.userToken.CreateUserToken(ctx, token); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+func (u *UserService) revokeReusedSession(ctx context.Context, session models.Session) {
+	u.logger.Error("refresh token reuse detected, revoking session",
+		zap.String("session_id", session.ID),
+		zap.String("user_id", session.UserID),
+	)
+
+	if err := u.session.RevokeSession(ctx, session.ID, session.UserID); err != nil {
+		u.logger.Error("error revoking session", zap.String("error", err.Error()))
+		return
+	}
+
+	// access tokens of the session may be stolen too, they stop working at once
+	now := time.Now()
+	u.cache.setSessionRevoked(session.ID, true, now.Add(accessTokenTTL), now)
+}
+
+func truncateUserAgent(userAgent string) string {
+	if utf8.RuneCountInString(userAgent) <= 512 {
+		return userAgent
+	}
+	return string([]rune(userAgent)[:512])
 }
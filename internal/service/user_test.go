@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/romandnk/advertisement/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// testUserStorage knows a single user.
+type testUserStorage struct {
+	storage.UserStorage
+	user models.User
+}
+
+func (s testUserStorage) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	return s.user, nil
+}
+
+type testSessionStorage struct {
+	storage.SessionStorage
+	sessions map[string]models.Session
+}
+
+func (s testSessionStorage) GetSessionByID(ctx context.Context, id string) (models.Session, error) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return models.Session{}, custom_error.CustomError{Field: "refresh_token", Message: "session not found"}
+	}
+	return session, nil
+}
+
+func (s testSessionStorage) RevokeSession(ctx context.Context, id, userID string) error {
+	session := s.sessions[id]
+	session.Revoked = true
+	s.sessions[id] = session
+	return nil
+}
+
+// testTokenStorage revokes no tokens and has expired tokens to purge.
+type testTokenStorage struct {
+	storage.TokenStorage
+	expired *int
+}
+
+func (s testTokenStorage) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+func (s testTokenStorage) DeleteExpiredTokens(ctx context.Context, now time.Time, limit int) (int64, error) {
+	deleted := *s.expired
+	if deleted > limit {
+		deleted = limit
+	}
+	*s.expired -= deleted
+	return int64(deleted), nil
+}
+
+func TestUserServiceCheckAccessTokenRevokedSession(t *testing.T) {
+	user := models.User{ID: uuid.New().String()}
+	session := models.Session{ID: uuid.New().String(), UserID: user.ID}
+	sessions := testSessionStorage{sessions: map[string]models.Session{session.ID: session}}
+
+	newService := func() *UserService {
+		return NewUserService(testUserStorage{user: user}, sessions, testTokenStorage{}, nil, nil, nil, nil, nil, 0, "", "", 100)
+	}
+	service := newService()
+
+	token := models.AccessToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		SessionID: session.ID,
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	}
+
+	err := service.CheckAccessToken(context.Background(), token)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), "user_id", user.ID)
+	err = service.RevokeSession(ctx, session.ID)
+	require.NoError(t, err)
+
+	revokedToken := custom_error.CustomError{Field: "token", Message: ErrUserServiceRevokedToken.Error()}
+
+	// the replica which revoked the session rejects its tokens at once
+	err = service.CheckAccessToken(context.Background(), token)
+	require.ErrorIs(t, err, revokedToken)
+
+	// another replica finds the revoked session in the storage
+	err = newService().CheckAccessToken(context.Background(), token)
+	require.ErrorIs(t, err, revokedToken)
+}
+
+func TestUserServiceRefreshReusedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := models.User{ID: uuid.New().String()}
+	session := models.Session{
+		ID:               uuid.New().String(),
+		UserID:           user.ID,
+		RefreshTokenHash: hashToken("current"),
+		ExpiresAt:        time.Now().Add(refreshTokenTTL),
+	}
+	sessions := testSessionStorage{sessions: map[string]models.Session{session.ID: session}}
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error("refresh token reuse detected, revoking session", gomock.Any(), gomock.Any())
+
+	service := NewUserService(testUserStorage{user: user}, sessions, testTokenStorage{}, nil, nil, logger, nil, nil, 0, "", "", 100)
+
+	token := models.AccessToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		SessionID: session.ID,
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	}
+
+	err := service.CheckAccessToken(context.Background(), token)
+	require.NoError(t, err)
+
+	_, err = service.Refresh(context.Background(), session.ID+".rotated")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "refresh_token", Message: ErrUserServiceInvalidRefreshToken.Error()})
+
+	// the access token cached as valid before the reuse is rejected at once
+	err = service.CheckAccessToken(context.Background(), token)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "token", Message: ErrUserServiceRevokedToken.Error()})
+}
+
+func TestUserServicePurgeExpiredTokens(t *testing.T) {
+	expired := 250
+	service := NewUserService(nil, nil, testTokenStorage{expired: &expired}, nil, nil, nil, nil, nil, 0, "", "", 100)
+
+	purged, err := service.PurgeExpiredTokens(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 250, purged)
+	require.Zero(t, expired)
+}
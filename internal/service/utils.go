@@ -1,31 +1,450 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
+	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/bcrypt"
-	"io"
-	"io/fs"
-	"os"
-	"path/filepath"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path"
 	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
 )
 
-func saveImage(image *models.Image, path string) error {
-	err := os.WriteFile(path+image.ID+".jpg", image.Data, 0o644)
-	return err
+func getUserID(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		return "", custom_error.CustomError{Field: "user_id", Message: ErrAdvertServiceNoUserID.Error()}
+	}
+	return userID, nil
+}
+
+// canSeeUnpublishedAdvert reports whether the caller is the owner of the advert or a moderator.
+// The context has no user for anonymous requests.
+func canSeeUnpublishedAdvert(ctx context.Context, advert models.Advert) bool {
+	role, _ := ctx.Value("role").(string)
+	if role == models.RoleModerator || role == models.RoleAdmin {
+		return true
+	}
+	userID, ok := ctx.Value("user_id").(string)
+	return ok && userID == advert.UserID
+}
+
+// advertVisible reports whether the caller can see the advert. Everyone sees published adverts
+// with all images processed, other adverts are seen only by their owner and moderators.
+func advertVisible(ctx context.Context, advert models.Advert) bool {
+	if advert.Status == models.AdvertStatusPublished && imagesReady(advert) {
+		return true
+	}
+	return canSeeUnpublishedAdvert(ctx, advert)
+}
+
+// imageExtensions maps an image format to the extension its file is saved with.
+var imageExtensions = map[string]string{
+	models.ImageFormatJPEG: ".jpg",
+	models.ImageFormatPNG:  ".png",
+	models.ImageFormatGIF:  ".gif",
+	models.ImageFormatWebP: ".webp",
+}
+
+// imageVariants are the max width and height of the resized copies made for every uploaded image.
+var imageVariants = map[string]int{
+	models.ImageSizeThumb:  200,
+	models.ImageSizeMedium: 800,
+}
+
+// saveImage stores the original image and its resized variants.
+func saveImage(ctx context.Context, store blob.BlobStore, image *models.Image) error {
+	sum := sha256.Sum256(image.Data)
+	image.ContentHash = hex.EncodeToString(sum[:])
+
+	key := imageKey(image.ID, models.ImageSizeOriginal, image.Format)
+	err := store.Put(ctx, key, bytes.NewReader(image.Data), int64(len(image.Data)), "image/"+image.Format)
+	if err != nil {
+		return err
+	}
+
+	for size := range imageVariants {
+		if _, err := saveImageVariant(ctx, store, image, size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveImageVariant resizes the original image to fit the size and stores it next to the original.
+func saveImageVariant(ctx context.Context, store blob.BlobStore, image *models.Image, size string) ([]byte, error) {
+	format := variantFormat(image.Format)
+
+	data, err := resizeImage(image.Data, imageVariants[size], format)
+	if err != nil {
+		return nil, err
+	}
+
+	key := imageKey(image.ID, size, image.Format)
+	err = store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/"+format)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// deleteImage removes the image and its variants whatever format they were saved in.
+func deleteImage(ctx context.Context, store blob.BlobStore, imageID string) error {
+	shard := imageShard(imageID)
+	for _, extension := range imageExtensions {
+		keys := []string{path.Join(shard, imageID+extension)}
+		for size := range imageVariants {
+			keys = append(keys, path.Join(shard, imageID+"_"+size+extension))
+		}
+
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// imageKey places the image into two levels of subdirectories made of its id, e.g. ab/cd/abcd...jpg,
+// so that none of them grows too large.
+func imageKey(id, size, format string) string {
+	return path.Join(imageShard(id), imageFileName(id, size, format))
+}
+
+// imageIDFromKey is the reverse of imageKey, it returns an empty string for keys that are not image files.
+func imageIDFromKey(key string) string {
+	name := path.Base(key)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name, _, _ = strings.Cut(name, "_")
+
+	id, err := uuid.Parse(name)
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+// imageUploadKey is where an uploaded advert image waits for its processing job, e.g. uploads/ab/cd/abcd...
+func imageUploadKey(id string) string {
+	return path.Join("uploads", imageShard(id), id)
+}
+
+func isImageUploadKey(key string) bool {
+	return strings.HasPrefix(key, "uploads/")
+}
+
+// saveImageUpload stores the image as uploaded, the processing job makes the stored original and variants of it.
+func saveImageUpload(ctx context.Context, store blob.BlobStore, image *models.Image) error {
+	key := imageUploadKey(image.ID)
+	return store.Put(ctx, key, bytes.NewReader(image.Data), int64(len(image.Data)), "image/"+image.Format)
+}
+
+// uploadKey is where a complete chunked upload is assembled, e.g. tmp/ab/cd/abcd.../data.
+// Neither it nor the chunk keys end with an image id, so the image garbage collector skips them.
+func uploadKey(id string) string {
+	return path.Join("tmp", imageShard(id), id, "data")
+}
+
+func uploadChunkKey(id string, offset int64) string {
+	return path.Join("tmp", imageShard(id), id, fmt.Sprintf("%020d", offset))
+}
+
+// deleteUploadFiles removes the assembled file and the chunks of the upload.
+// Chunks follow each other, so the size of a chunk gives the offset of the next one.
+func deleteUploadFiles(ctx context.Context, store blob.BlobStore, upload models.Upload) error {
+	if err := store.Delete(ctx, uploadKey(upload.ID)); err != nil {
+		return err
+	}
+
+	for offset := int64(0); offset < upload.Offset; {
+		key := uploadChunkKey(upload.ID, offset)
+
+		info, err := store.Stat(ctx, key)
+		if errors.Is(err, blob.ErrNotFound) {
+			// the chunks of an assembled upload are already removed
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Size == 0 {
+			return errors.New("empty upload chunk")
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+		offset += info.Size
+	}
+
+	return nil
+}
+
+// assembleUpload joins the chunks of a complete upload into one file and removes them.
+// Chunks follow each other, so the next one starts where the previous one ends.
+func assembleUpload(ctx context.Context, store blob.BlobStore, upload models.Upload) error {
+	data := make([]byte, 0, upload.Length)
+	var chunkKeys []string
+
+	for offset := int64(0); offset < upload.Length; {
+		key := uploadChunkKey(upload.ID, offset)
+
+		chunk, err := store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return errors.New("empty upload chunk")
+		}
+
+		data = append(data, chunk...)
+		chunkKeys = append(chunkKeys, key)
+		offset += int64(len(chunk))
+	}
+
+	if int64(len(data)) != upload.Length {
+		return errors.New("upload chunks do not match the upload length")
+	}
+
+	err := store.Put(ctx, uploadKey(upload.ID), bytes.NewReader(data), upload.Length, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range chunkKeys {
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readUpload returns the content of a complete upload, the upload is assembled
+// if that failed when its last chunk was received.
+func readUpload(ctx context.Context, store blob.BlobStore, upload models.Upload) ([]byte, error) {
+	data, err := store.Get(ctx, uploadKey(upload.ID))
+	if errors.Is(err, blob.ErrNotFound) {
+		if err := assembleUpload(ctx, store, upload); err != nil {
+			return nil, err
+		}
+		data, err = store.Get(ctx, uploadKey(upload.ID))
+	}
+	return data, err
+}
+
+// imagesReady reports whether all images of the advert are processed.
+func imagesReady(advert models.Advert) bool {
+	for _, image := range advert.Images {
+		if image.Status != models.ImageStatusReady {
+			return false
+		}
+	}
+	return true
+}
+
+func imageShard(id string) string {
+	if len(id) < 4 {
+		return ""
+	}
+	return id[:2] + "/" + id[2:4]
+}
+
+func imageFileName(id, size, format string) string {
+	if size == models.ImageSizeOriginal {
+		return id + imageExtensions[format]
+	}
+	return id + "_" + size + imageExtensions[variantFormat(format)]
+}
+
+// variantFormat keeps jpeg variants in jpeg, other formats may have transparency and are resized to png.
+func variantFormat(format string) string {
+	if format == models.ImageFormatJPEG {
+		return models.ImageFormatJPEG
+	}
+	return models.ImageFormatPNG
+}
+
+// resizeImage scales the image down to fit into maxSide x maxSide keeping the aspect ratio.
+// Smaller images are only re-encoded.
+func resizeImage(data []byte, maxSide int, format string) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			height = height * maxSide / width
+			width = maxSide
+		} else {
+			width = width * maxSide / height
+			height = maxSide
+		}
+	}
+	if width == 0 {
+		width = 1
+	}
+	if height == 0 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	buf := &bytes.Buffer{}
+	if format == models.ImageFormatJPEG {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newImageFormats(formats []string) map[string]struct{} {
+	allowed := make(map[string]struct{}, len(formats))
+	for _, format := range formats {
+		allowed[format] = struct{}{}
+	}
+	return allowed
+}
+
+func validateImageFormat(field string, image *models.Image, allowed map[string]struct{}) error {
+	if _, ok := allowed[image.Format]; !ok {
+		return custom_error.CustomError{Field: field, Message: ErrImageServiceFormatNotAllowed.Error()}
+	}
+	return nil
+}
+
+// checkImagePixels rejects images with more than maxPixels pixels reading only their header,
+// so that a small file declaring huge dimensions is never decoded.
+func checkImagePixels(field string, img *models.Image, maxPixels int) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return custom_error.CustomError{Field: field, Message: ErrImageServiceCannotDecode.Error()}
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return custom_error.CustomError{Field: field, Message: ErrImageServiceTooManyPixels.Error()}
+	}
+	return nil
 }
 
-func deleteImage(imageID string, path string) error {
-	pathImage := path + imageID + ".jpg"
-	if _, err := os.Stat(pathImage); os.IsNotExist(err) {
-		return nil
+// prepareImage checks the pixels of the image, removes the metadata, e.g. GPS coordinates,
+// and turns the image upright according to its EXIF orientation.
+func prepareImage(field string, img *models.Image, maxPixels int) error {
+	if err := checkImagePixels(field, img, maxPixels); err != nil {
+		return err
+	}
+
+	data, format, err := stripImageMetadata(img.Data, img.Format)
+	if err != nil {
+		return custom_error.CustomError{Field: field, Message: ErrImageServiceCannotDecode.Error()}
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return custom_error.CustomError{Field: field, Message: ErrImageServiceCannotDecode.Error()}
+	}
+
+	img.Data = data
+	img.Format = format
+	img.PHash = perceptualHash(decoded)
+
+	return nil
+}
+
+// perceptualHash is a dHash: the image is shrunk to 9x8 grey pixels and every bit tells
+// whether a pixel is brighter than its right neighbour. Recompressed, resized or slightly
+// edited copies of a picture get hashes that differ in a few bits.
+func perceptualHash(img image.Image) int64 {
+	gray := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray.GrayAt(x, y).Y > gray.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+
+	// stored as bigint, only the bits matter
+	return int64(hash)
+}
+
+// stripImageMetadata re-encodes jpeg and png, the encoders write no metadata.
+// Webp has no encoder, its metadata chunks are cut out instead and only a rotated image is re-encoded, to png.
+// Gif has no EXIF and is kept as is, re-encoding would lose the animation.
+func stripImageMetadata(data []byte, format string) ([]byte, string, error) {
+	orientation := 1
+	switch format {
+	case models.ImageFormatJPEG:
+		orientation = jpegOrientation(data)
+	case models.ImageFormatPNG:
+		orientation = pngOrientation(data)
+	case models.ImageFormatWebP:
+		stripped, webpOrientation, err := stripWebPMetadata(data)
+		if err != nil {
+			return nil, "", err
+		}
+		if webpOrientation == 1 {
+			return stripped, format, nil
+		}
+		orientation = webpOrientation
+		format = models.ImageFormatPNG
+	default:
+		return data, format, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
 	}
-	return os.Remove(pathImage)
+
+	dst := orientImage(src, orientation)
+
+	buf := &bytes.Buffer{}
+	if format == models.ImageFormatJPEG {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(buf, dst)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), format, nil
 }
 
 func validatePassword(password string) error {
@@ -82,18 +501,44 @@ func hashPassword(pwd string) (string, error) {
 	return string(hash), nil
 }
 
+// normalizePhone drops spaces, dashes and brackets, an empty phone removes it from the profile.
+func normalizePhone(phone string) (string, error) {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+
+	if normalized == "" {
+		return "", nil
+	}
+
+	digits := strings.TrimPrefix(normalized, "+")
+	if len(digits) < 10 || len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+		return "", custom_error.CustomError{Field: "phone", Message: ErrUserServiceInvalidPhone.Error()}
+	}
+
+	return normalized, nil
+}
+
 func comparePassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
 
-func createJWT(secret []byte, userID string) (string, error) {
+func createJWT(secret []byte, user models.User, sessionID string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
 
-	claims["exp"] = time.Now().Add(time.Hour).Unix()
-	claims["user_id"] = userID
+	claims["jti"] = uuid.New().String()
+	claims["exp"] = time.Now().Add(accessTokenTTL).Unix()
+	claims["user_id"] = user.ID
+	claims["role"] = user.Role
+	claims["sid"] = sessionID
+	claims["ver"] = user.TokenVersion
 
 	tokenStr, err := token.SignedString(secret)
 	if err != nil {
@@ -103,33 +548,207 @@ func createJWT(secret []byte, userID string) (string, error) {
 	return tokenStr, nil
 }
 
-func findImageByID(path string, id string) ([]byte, error) {
-	var data []byte
-	name := id + ".jpg"
+// newRefreshToken returns a token in the form <session id>.<random secret>
+// and the hash of the secret which is stored instead of the token.
+func newRefreshToken(sessionID string) (string, string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID + "." + secret, hashToken(secret), nil
+}
 
-	err := filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
+func parseRefreshToken(token string) (string, string, error) {
+	sessionID, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return "", "", errors.New("malformed refresh token")
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return "", "", err
+	}
+	return sessionID, secret, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func findImageByID(ctx context.Context, store blob.BlobStore, id, size, format string) ([]byte, error) {
+	return store.Get(ctx, imageKey(id, size, format))
+}
+
+// imageETag is a strong validator of the image, variants are derived from the same original
+// and get the size appended. Images without a stored hash have no ETag.
+func imageETag(contentHash, size string) string {
+	if contentHash == "" {
+		return ""
+	}
+	if size == models.ImageSizeOriginal {
+		return `"` + contentHash + `"`
+	}
+	return `"` + contentHash + "-" + size + `"`
+}
+
+// etagMatches reports whether If-None-Match lists the etag, the comparison is weak as RFC 9110 requires.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
 		}
+	}
 
-		if info.Name() == name {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
+	return false
+}
 
-			dataFile, err := io.ReadAll(file)
-			if err != nil {
-				return err
+func openImage(ctx context.Context, store blob.BlobStore, id, size, format string) (models.ImageFile, error) {
+	content, info, err := store.Stream(ctx, imageKey(id, size, format))
+	if err != nil {
+		return models.ImageFile{}, err
+	}
+
+	return models.ImageFile{
+		Format:  format,
+		Size:    info.Size,
+		ModTime: info.ModTime,
+		Content: content,
+	}, nil
+}
+
+// reshardImage moves the image and its variants saved at the root of the store into their shard.
+// It returns the number of moved files, files already moved or never created are skipped.
+func reshardImage(ctx context.Context, store blob.BlobStore, image models.Image) (int, error) {
+	sizes := []string{models.ImageSizeOriginal}
+	for size := range imageVariants {
+		sizes = append(sizes, size)
+	}
+
+	var moved int
+	for _, size := range sizes {
+		oldKey := imageFileName(image.ID, size, image.Format)
+		newKey := imageKey(image.ID, size, image.Format)
+
+		data, err := store.Get(ctx, oldKey)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotFound) {
+				continue
 			}
+			return moved, err
+		}
 
-			data = dataFile
-			return nil
+		format := image.Format
+		if size != models.ImageSizeOriginal {
+			format = variantFormat(format)
+		}
+
+		err = store.Put(ctx, newKey, bytes.NewReader(data), int64(len(data)), "image/"+format)
+		if err != nil {
+			return moved, err
+		}
+
+		err = store.Delete(ctx, oldKey)
+		if err != nil {
+			return moved, err
 		}
 
-		return nil
+		moved++
+	}
+
+	return moved, nil
+}
+
+// advertCursor is serialized into the opaque pagination token.
+// Sort and order are kept to reject a token used with another sorting.
+type advertCursor struct {
+	SortBy string `json:"s"`
+	Order  string `json:"o"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+}
+
+func encodeAdvertCursor(cursor models.AdvertCursor, sortBy, order string) (string, error) {
+	value := cursor.CreatedAt.UTC().Format(time.RFC3339Nano)
+	if sortBy == models.AdvertSortByPrice {
+		value = cursor.Price.String()
+	}
+
+	data, err := json.Marshal(advertCursor{
+		SortBy: sortBy,
+		Order:  order,
+		Value:  value,
+		ID:     cursor.ID,
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return data, err
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeAdvertCursor(token, sortBy, order string) (models.AdvertCursor, error) {
+	var cursor models.AdvertCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, err
+	}
+
+	var decoded advertCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return cursor, err
+	}
+
+	if decoded.SortBy != sortBy || decoded.Order != order {
+		return cursor, errors.New("cursor was issued for another sorting")
+	}
+
+	if _, err := uuid.Parse(decoded.ID); err != nil {
+		return cursor, err
+	}
+	cursor.ID = decoded.ID
+
+	switch sortBy {
+	case models.AdvertSortByPrice:
+		cursor.Price, err = decimal.NewFromString(decoded.Value)
+	default:
+		cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, decoded.Value)
+	}
+	if err != nil {
+		return models.AdvertCursor{}, err
+	}
+
+	return cursor, nil
+}
+
+// parsePage applies the default limit, validates it and decodes the cursor of a list sorted by sortBy, the newest first.
+func parsePage(limit *int, cursor, sortBy string) (*models.AdvertCursor, error) {
+	if *limit == 0 {
+		*limit = defaultAdvertsLimit
+	}
+	if *limit < 0 || *limit > maxAdvertsLimit {
+		return nil, custom_error.CustomError{Field: "limit", Message: ErrAdvertServiceInvalidLimit.Error()}
+	}
+
+	if cursor == "" {
+		return nil, nil
+	}
+
+	after, err := decodeAdvertCursor(cursor, sortBy, models.SortOrderDesc)
+	if err != nil {
+		return nil, custom_error.CustomError{Field: "cursor", Message: ErrAdvertServiceInvalidCursor.Error()}
+	}
+	return &after, nil
 }
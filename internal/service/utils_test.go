@@ -1,62 +1,240 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/google/uuid"
+	"github.com/romandnk/advertisement/internal/blob"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/bits"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
 func TestSaveImage(t *testing.T) {
 	dir := t.TempDir()
+	store := blob.NewFileSystemStore(dir)
 
 	image := &models.Image{
 		ID:        uuid.New().String(),
-		Data:      []byte("test"),
+		Data:      newTestImageData(t, 10, 10),
+		Format:    models.ImageFormatJPEG,
 		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
 	}
 
-	err := saveImage(image, dir)
+	err := saveImage(context.Background(), store, image)
 	require.NoError(t, err)
 
-	_, err = os.Stat(dir + image.ID + ".jpg")
-	require.NoError(t, err)
+	for _, name := range []string{".jpg", "_thumb.jpg", "_medium.jpg"} {
+		_, err = os.Stat(filepath.Join(dir, image.ID[:2], image.ID[2:4], image.ID+name))
+		require.NoError(t, err)
+	}
 }
 
 func TestDeleteImage(t *testing.T) {
 	dir := t.TempDir()
+	store := blob.NewFileSystemStore(dir)
 
 	image := &models.Image{
 		ID:        uuid.New().String(),
-		Data:      []byte("test"),
+		Data:      newTestImageData(t, 10, 10),
+		Format:    models.ImageFormatJPEG,
 		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
 	}
 
-	err := saveImage(image, dir)
+	err := saveImage(context.Background(), store, image)
 	require.NoError(t, err)
 
-	err = deleteImage(image.ID, dir)
+	sum := sha256.Sum256(image.Data)
+	require.Equal(t, hex.EncodeToString(sum[:]), image.ContentHash)
+
+	err = deleteImage(context.Background(), store, image.ID)
 	require.NoError(t, err)
+
+	for _, name := range []string{".jpg", "_thumb.jpg", "_medium.jpg"} {
+		_, err = os.Stat(filepath.Join(dir, image.ID[:2], image.ID[2:4], image.ID+name))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	}
 }
 
 func TestDeleteImageFileIsNotExist(t *testing.T) {
+	store := blob.NewFileSystemStore(t.TempDir())
+
+	err := deleteImage(context.Background(), store, uuid.New().String())
+	require.NoError(t, err)
+}
+
+func TestSaveImageFormats(t *testing.T) {
+	store := blob.NewFileSystemStore(t.TempDir())
+
+	for format := range imageExtensions {
+		image := &models.Image{
+			ID:     uuid.New().String(),
+			Data:   newTestImageData(t, 10, 10),
+			Format: format,
+		}
+
+		err := saveImage(context.Background(), store, image)
+		require.NoError(t, err)
+
+		data, err := findImageByID(context.Background(), store, image.ID, models.ImageSizeOriginal, format)
+		require.NoError(t, err)
+		require.Equal(t, image.Data, data)
+
+		err = deleteImage(context.Background(), store, image.ID)
+		require.NoError(t, err)
+
+		_, err = findImageByID(context.Background(), store, image.ID, models.ImageSizeOriginal, format)
+		require.ErrorIs(t, err, blob.ErrNotFound)
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		width          int
+		height         int
+		format         string
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{
+			name:           "landscape",
+			width:          1000,
+			height:         500,
+			format:         models.ImageFormatJPEG,
+			expectedWidth:  200,
+			expectedHeight: 100,
+		},
+		{
+			name:           "portrait",
+			width:          300,
+			height:         1200,
+			format:         models.ImageFormatPNG,
+			expectedWidth:  50,
+			expectedHeight: 200,
+		},
+		{
+			name:           "smaller than variant",
+			width:          20,
+			height:         10,
+			format:         models.ImageFormatPNG,
+			expectedWidth:  20,
+			expectedHeight: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := resizeImage(newTestImageData(t, tc.width, tc.height), 200, tc.format)
+			require.NoError(t, err)
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, tc.format, format)
+			require.Equal(t, tc.expectedWidth, cfg.Width)
+			require.Equal(t, tc.expectedHeight, cfg.Height)
+		})
+	}
+}
+
+func TestImageFileName(t *testing.T) {
+	require.Equal(t, "id.webp", imageFileName("id", models.ImageSizeOriginal, models.ImageFormatWebP))
+	require.Equal(t, "id_thumb.png", imageFileName("id", models.ImageSizeThumb, models.ImageFormatWebP))
+	require.Equal(t, "id_medium.jpg", imageFileName("id", models.ImageSizeMedium, models.ImageFormatJPEG))
+}
+
+func TestImageKey(t *testing.T) {
+	id := "abcd1234-0000-0000-0000-000000000000"
+
+	require.Equal(t, "ab/cd/"+id+".png", imageKey(id, models.ImageSizeOriginal, models.ImageFormatPNG))
+	require.Equal(t, "ab/cd/"+id+"_thumb.jpg", imageKey(id, models.ImageSizeThumb, models.ImageFormatJPEG))
+	require.Equal(t, "id.jpg", imageKey("id", models.ImageSizeOriginal, models.ImageFormatJPEG))
+}
+
+func TestReshardImage(t *testing.T) {
 	dir := t.TempDir()
+	store := blob.NewFileSystemStore(dir)
 
-	image := &models.Image{
-		ID:        uuid.New().String(),
-		Data:      []byte("test"),
-		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	image := models.Image{ID: uuid.New().String(), Format: models.ImageFormatGIF}
+
+	// the medium variant was never made, e.g. the upload predates variants
+	names := []string{image.ID + ".gif", image.ID + "_thumb.png"}
+	for _, name := range names {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+		require.NoError(t, err)
+	}
+
+	moved, err := reshardImage(context.Background(), store, image)
+	require.NoError(t, err)
+	require.Equal(t, len(names), moved)
+
+	for _, name := range names {
+		_, err = os.Stat(filepath.Join(dir, name))
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		data, err := os.ReadFile(filepath.Join(dir, image.ID[:2], image.ID[2:4], name))
+		require.NoError(t, err)
+		require.Equal(t, []byte(name), data)
 	}
 
-	err := saveImage(image, dir)
+	moved, err = reshardImage(context.Background(), store, image)
 	require.NoError(t, err)
+	require.Zero(t, moved)
+}
+
+func TestImageETag(t *testing.T) {
+	require.Equal(t, `"hash"`, imageETag("hash", models.ImageSizeOriginal))
+	require.Equal(t, `"hash-thumb"`, imageETag("hash", models.ImageSizeThumb))
+	require.Empty(t, imageETag("", models.ImageSizeOriginal))
+}
+
+func TestETagMatches(t *testing.T) {
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		expected    bool
+	}{
+		{name: "equal", ifNoneMatch: `"hash"`, etag: `"hash"`, expected: true},
+		{name: "list", ifNoneMatch: `"other", "hash"`, etag: `"hash"`, expected: true},
+		{name: "weak", ifNoneMatch: `W/"hash"`, etag: `"hash"`, expected: true},
+		{name: "any", ifNoneMatch: `*`, etag: `"hash"`, expected: true},
+		{name: "other", ifNoneMatch: `"other"`, etag: `"hash"`, expected: false},
+		{name: "variant", ifNoneMatch: `"hash"`, etag: `"hash-thumb"`, expected: false},
+		{name: "no header", ifNoneMatch: "", etag: `"hash"`, expected: false},
+		{name: "no etag", ifNoneMatch: `*`, etag: "", expected: false},
+	}
 
-	err = deleteImage(image.ID, dir)
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, etagMatches(tc.ifNoneMatch, tc.etag))
+		})
+	}
+}
+
+func TestValidateImageFormat(t *testing.T) {
+	allowed := newImageFormats([]string{models.ImageFormatJPEG, models.ImageFormatPNG})
+
+	err := validateImageFormat("images", &models.Image{Format: models.ImageFormatPNG}, allowed)
 	require.NoError(t, err)
+
+	err = validateImageFormat("images", &models.Image{Format: models.ImageFormatWebP}, allowed)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "images", Message: ErrImageServiceFormatNotAllowed.Error()})
 }
 
 func TestValidatePassword(t *testing.T) {
@@ -228,28 +406,248 @@ func TestComparePassword(t *testing.T) {
 func TestFindImageByID(t *testing.T) {
 	dir := t.TempDir()
 
-	file, err := os.CreateTemp(dir, "")
+	expectedData := []byte("this is jpg")
+	err := os.WriteFile(filepath.Join(dir, "id_thumb.jpg"), expectedData, 0o644)
 	require.NoError(t, err)
-	defer file.Close()
-	defer os.Remove(file.Name())
 
-	expectedData := []byte("this is jpg")
-	_, err = file.Write(expectedData)
+	data, err := findImageByID(context.Background(), blob.NewFileSystemStore(dir), "id", models.ImageSizeThumb, models.ImageFormatJPEG)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestFindImageByIDError(t *testing.T) {
+	data, err := findImageByID(context.Background(), blob.NewFileSystemStore("random dir"), "random_id", models.ImageSizeOriginal, models.ImageFormatJPEG)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	require.Empty(t, data)
+}
+
+func TestAdvertCursor(t *testing.T) {
+	cursor := models.AdvertCursor{
+		Price:     decimal.New(12345, -2),
+		CreatedAt: time.Date(2023, time.August, 11, 0, 35, 14, 340105000, time.UTC),
+		ID:        uuid.New().String(),
+	}
+
+	token, err := encodeAdvertCursor(cursor, models.AdvertSortByDate, models.SortOrderDesc)
 	require.NoError(t, err)
 
-	info, err := file.Stat()
+	decoded, err := decodeAdvertCursor(token, models.AdvertSortByDate, models.SortOrderDesc)
 	require.NoError(t, err)
+	require.Equal(t, cursor.ID, decoded.ID)
+	require.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
 
-	err = os.Rename(dir+"/"+info.Name(), dir+"/"+"id.jpg")
+	token, err = encodeAdvertCursor(cursor, models.AdvertSortByPrice, models.SortOrderAsc)
 	require.NoError(t, err)
 
-	data, err := findImageByID(dir, "id")
+	decoded, err = decodeAdvertCursor(token, models.AdvertSortByPrice, models.SortOrderAsc)
 	require.NoError(t, err)
-	require.ElementsMatch(t, data, expectedData)
+	require.Equal(t, cursor.ID, decoded.ID)
+	require.True(t, cursor.Price.Equal(decoded.Price))
 }
 
-func TestFindImageByIDError(t *testing.T) {
-	data, err := findImageByID("random dir", "random_id")
-	require.ErrorIs(t, err, os.ErrNotExist)
-	require.ElementsMatch(t, data, []byte{})
+func TestAdvertCursorError(t *testing.T) {
+	token, err := encodeAdvertCursor(models.AdvertCursor{ID: uuid.New().String()}, models.AdvertSortByPrice, models.SortOrderAsc)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		token  string
+		sortBy string
+		order  string
+	}{
+		{
+			name:   "not base64",
+			token:  "!!!",
+			sortBy: models.AdvertSortByPrice,
+			order:  models.SortOrderAsc,
+		},
+		{
+			name:   "another sort",
+			token:  token,
+			sortBy: models.AdvertSortByDate,
+			order:  models.SortOrderAsc,
+		},
+		{
+			name:   "another order",
+			token:  token,
+			sortBy: models.AdvertSortByPrice,
+			order:  models.SortOrderDesc,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeAdvertCursor(tc.token, tc.sortBy, tc.order)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	sessionID := uuid.New().String()
+
+	token, hash, err := newRefreshToken(sessionID)
+	require.NoError(t, err)
+
+	parsedSessionID, secret, err := parseRefreshToken(token)
+	require.NoError(t, err)
+	require.Equal(t, sessionID, parsedSessionID)
+	require.Equal(t, hash, hashToken(secret))
+
+	anotherToken, anotherHash, err := newRefreshToken(sessionID)
+	require.NoError(t, err)
+	require.NotEqual(t, token, anotherToken)
+	require.NotEqual(t, hash, anotherHash)
+}
+
+func TestParseRefreshTokenError(t *testing.T) {
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "empty token",
+			token: "",
+		},
+		{
+			name:  "no secret",
+			token: uuid.New().String() + ".",
+		},
+		{
+			name:  "invalid session id",
+			token: "session.secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := parseRefreshToken(tc.token)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	testCases := []struct {
+		name     string
+		phone    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "formatted phone",
+			phone:    "+7 (999) 123-45-67",
+			expected: "+79991234567",
+		},
+		{
+			name:     "empty phone",
+			phone:    " ",
+			expected: "",
+		},
+		{
+			name:  "short phone",
+			phone: "12345",
+			err:   custom_error.CustomError{Field: "phone", Message: ErrUserServiceInvalidPhone.Error()},
+		},
+		{
+			name:  "letters",
+			phone: "+7999123456a",
+			err:   custom_error.CustomError{Field: "phone", Message: ErrUserServiceInvalidPhone.Error()},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			phone, err := normalizePhone(tc.phone)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, phone)
+		})
+	}
+}
+
+func TestCanSeeUnpublishedAdvert(t *testing.T) {
+	advert := models.Advert{
+		ID:     uuid.New().String(),
+		UserID: uuid.New().String(),
+		Status: models.AdvertStatusPendingReview,
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected bool
+	}{
+		{
+			name:     "anonymous",
+			ctx:      context.Background(),
+			expected: false,
+		},
+		{
+			name:     "owner",
+			ctx:      context.WithValue(context.WithValue(context.Background(), "user_id", advert.UserID), "role", models.RoleUser),
+			expected: true,
+		},
+		{
+			name:     "another user",
+			ctx:      context.WithValue(context.WithValue(context.Background(), "user_id", uuid.New().String()), "role", models.RoleUser),
+			expected: false,
+		},
+		{
+			name:     "moderator",
+			ctx:      context.WithValue(context.WithValue(context.Background(), "user_id", uuid.New().String()), "role", models.RoleModerator),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, canSeeUnpublishedAdvert(tc.ctx, advert))
+		})
+	}
+}
+
+// newTestGradient draws a picture with a diagonal gradient, flipped ones go the other way.
+func newTestGradient(width, height int, flipped bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*x + y*3) * 255 / (width*width + height*3))
+			if flipped {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	original := newTestGradient(400, 300, false)
+
+	// a recompressed and shrunk copy
+	buf := &bytes.Buffer{}
+	require.NoError(t, jpeg.Encode(buf, original, &jpeg.Options{Quality: 50}))
+	data, err := resizeImage(buf.Bytes(), 120, models.ImageFormatJPEG)
+	require.NoError(t, err)
+	copied, _, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	hash := perceptualHash(original)
+	require.LessOrEqual(t, bits.OnesCount64(uint64(hash^perceptualHash(copied))), 5)
+	require.Greater(t, bits.OnesCount64(uint64(hash^perceptualHash(newTestGradient(400, 300, true)))), 20)
+}
+
+func newTestImageData(t *testing.T, width, height int) []byte {
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	require.NoError(t, err)
+	return buf.Bytes()
 }
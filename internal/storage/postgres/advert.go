@@ -7,12 +7,18 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
+	"strings"
+	"time"
 )
 
 var (
-	ErrAdvertNotCreated      = errors.New("advert was not created")
-	ErrAdvertImageNotCreated = errors.New("image was not created")
-	ErrAdvertNotFound        = errors.New("advert not found")
+	ErrAdvertNotCreated       = errors.New("advert was not created")
+	ErrAdvertImageNotCreated  = errors.New("image was not created")
+	ErrAdvertNotFound         = errors.New("advert not found")
+	ErrAdvertImageNotFound    = errors.New("image not found")
+	ErrAdvertStatusNotChanged = errors.New("advert not found or its status does not allow the change")
+	ErrAdvertNotRenewed       = errors.New("advert not found or it is neither published nor archived on expiry")
+	ErrAdvertImageNotAttached = errors.New("image not found, already attached or failed")
 )
 
 func (s *PostgresStorage) CreateAdvert(ctx context.Context, advert models.Advert) (string, error) {
@@ -23,8 +29,8 @@ func (s *PostgresStorage) CreateAdvert(ctx context.Context, advert models.Advert
 	defer tx.Rollback(ctx)
 
 	insertAdvert := fmt.Sprintf(`
-				INSERT INTO %s (id, title, description, price, created_at, updated_at, user_id, deleted)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				INSERT INTO %s (id, title, description, price, created_at, updated_at, user_id, category_id, status, expires_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`, advertsTable)
 
 	ct, err := tx.Exec(ctx, insertAdvert,
@@ -35,6 +41,9 @@ func (s *PostgresStorage) CreateAdvert(ctx context.Context, advert models.Advert
 		advert.CreatedAt,
 		advert.UpdatedAt,
 		advert.UserID,
+		advert.CategoryID,
+		advert.Status,
+		advert.ExpiresAt,
 		advert.Deleted,
 	)
 	if err != nil {
@@ -45,20 +54,14 @@ func (s *PostgresStorage) CreateAdvert(ctx context.Context, advert models.Advert
 		return "", custom_error.CustomError{Field: "", Message: ErrAdvertNotCreated.Error()}
 	}
 
-	insertImage := fmt.Sprintf(`
-				INSERT INTO %s (id, advert_id, created_at, deleted)
-				VALUES ($1, $2, $3, $4)
-	`, imagesTable)
-
-	for _, image := range advert.Images {
-		ct, err := tx.Exec(ctx, insertImage, image.ID, image.AdvertID, image.CreatedAt, image.Deleted)
-		if err != nil {
-			return "", err
-		}
-		if ct.RowsAffected() == 0 {
-			return "", custom_error.CustomError{Field: "images", Message: ErrAdvertImageNotCreated.Error()}
-		}
+	err = insertAdvertImages(ctx, tx, advert.Images)
+	if err != nil {
+		return "", err
+	}
 
+	err = attachAdvertImages(ctx, tx, advert.ID, advert.UserID, advert.ImageIDs)
+	if err != nil {
+		return "", err
 	}
 
 	err = tx.Commit(ctx)
@@ -69,6 +72,72 @@ func (s *PostgresStorage) CreateAdvert(ctx context.Context, advert models.Advert
 	return advert.ID, nil
 }
 
+func (s *PostgresStorage) UpdateAdvert(ctx context.Context, update models.AdvertUpdate) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	updateAdvert := fmt.Sprintf(`
+				UPDATE %s
+				SET title = COALESCE($3, title),
+				    description = COALESCE($4, description),
+				    price = COALESCE($5, price),
+				    category_id = COALESCE($7, category_id),
+				    status = CASE WHEN $8 AND status = ANY($9) THEN $10 ELSE status END,
+				    status_reason = CASE WHEN $8 THEN '' ELSE status_reason END,
+				    updated_at = $6
+				WHERE id = $1 AND user_id = $2 AND deleted = false
+	`, advertsTable)
+
+	// edited content of a reviewed advert is reviewed again before it is shown,
+	// an edited advert archived on expiry loses the reason and is not renewed without review
+	ct, err := tx.Exec(ctx, updateAdvert,
+		update.ID,
+		update.UserID,
+		update.Title,
+		update.Description,
+		update.Price,
+		update.UpdatedAt,
+		update.CategoryID,
+		update.ContentChanged,
+		[]string{models.AdvertStatusPublished, models.AdvertStatusRejected},
+		models.AdvertStatusPendingReview,
+	)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrAdvertNotFound.Error()}
+	}
+
+	if len(update.RemoveImageIDs) > 0 {
+		removeImages := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = TRUE
+				WHERE advert_id = $1 AND id = ANY($2) AND deleted = false
+		`, imagesTable)
+
+		ct, err = tx.Exec(ctx, removeImages, update.ID, update.RemoveImageIDs)
+		if err != nil {
+			return err
+		}
+
+		if ct.RowsAffected() != int64(len(update.RemoveImageIDs)) {
+			return custom_error.CustomError{Field: "remove_images", Message: ErrAdvertImageNotFound.Error()}
+		}
+	}
+
+	err = insertAdvertImages(ctx, tx, update.AddImages)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (s *PostgresStorage) DeleteAdvert(ctx context.Context, advertID, userID string) ([]string, error) {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
@@ -91,6 +160,152 @@ func (s *PostgresStorage) DeleteAdvert(ctx context.Context, advertID, userID str
 		return nil, custom_error.CustomError{Field: "id", Message: ErrAdvertNotFound.Error()}
 	}
 
+	imageIDs, err := deleteAdvertImages(ctx, tx, advertID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageIDs, nil
+}
+
+// DeleteAnyAdvert deletes the advert regardless of its owner, it is used by moderators.
+func (s *PostgresStorage) DeleteAnyAdvert(ctx context.Context, advertID string) ([]string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	updateAdverts := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = TRUE
+				WHERE id = $1 AND deleted = false
+	`, advertsTable)
+
+	ct, err := tx.Exec(ctx, updateAdverts, advertID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return nil, custom_error.CustomError{Field: "id", Message: ErrAdvertNotFound.Error()}
+	}
+
+	imageIDs, err := deleteAdvertImages(ctx, tx, advertID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageIDs, nil
+}
+
+// UpdateAdvertStatus changes the status only if the advert is still in one of the expected statuses,
+// so two moderators cannot both handle the same advert.
+func (s *PostgresStorage) UpdateAdvertStatus(ctx context.Context, change models.AdvertStatusChange) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = $3, updated_at = $4, expires_at = COALESCE($7, expires_at)
+				WHERE id = $1 AND status = ANY($5) AND ($6 = '' OR user_id = $6) AND deleted = false
+	`, advertsTable)
+
+	ct, err := s.db.Exec(ctx, query, change.ID, change.To, change.Reason, change.UpdatedAt, change.From, change.UserID, change.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "status", Message: ErrAdvertStatusNotChanged.Error()}
+	}
+
+	return nil
+}
+
+// ArchiveExpiredAdverts archives up to limit published adverts whose expiry time has passed.
+// SKIP LOCKED lets several application instances archive different batches at the same time.
+func (s *PostgresStorage) ArchiveExpiredAdverts(ctx context.Context, now time.Time, limit int) ([]models.ExpiredAdvert, error) {
+	query := fmt.Sprintf(`
+				UPDATE %s a
+				SET status = $2, status_reason = $3, updated_at = $4
+				FROM %s u
+				WHERE u.id = a.user_id AND a.id IN (
+					SELECT id FROM %s
+					WHERE status = $1 AND deleted = false AND expires_at <= $4
+					ORDER BY expires_at
+					LIMIT $5
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING a.id, a.title, a.user_id, u.email
+	`, advertsTable, usersTable, advertsTable)
+
+	rows, err := s.db.Query(ctx, query,
+		models.AdvertStatusPublished,
+		models.AdvertStatusArchived,
+		models.AdvertStatusReasonExpired,
+		now,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adverts []models.ExpiredAdvert
+	for rows.Next() {
+		var advert models.ExpiredAdvert
+		if err := rows.Scan(&advert.ID, &advert.Title, &advert.UserID, &advert.UserEmail); err != nil {
+			return nil, err
+		}
+		adverts = append(adverts, advert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return adverts, nil
+}
+
+// RenewAdvert extends a published advert or publishes an advert archived on expiry again until expiresAt.
+// An advert archived by its owner is not renewed, it goes through moderation to be published again.
+func (s *PostgresStorage) RenewAdvert(ctx context.Context, id, userID string, expiresAt, updatedAt time.Time) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $3, status_reason = '', expires_at = $4, updated_at = $5
+				WHERE id = $1 AND user_id = $2 AND deleted = false
+				  AND (status = $3 OR (status = $6 AND status_reason = $7))
+	`, advertsTable)
+
+	ct, err := s.db.Exec(ctx, query,
+		id,
+		userID,
+		models.AdvertStatusPublished,
+		expiresAt,
+		updatedAt,
+		models.AdvertStatusArchived,
+		models.AdvertStatusReasonExpired,
+	)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrAdvertNotRenewed.Error()}
+	}
+
+	return nil
+}
+
+func deleteAdvertImages(ctx context.Context, tx pgx.Tx, advertID string) ([]string, error) {
 	updateImages := fmt.Sprintf(`
 				UPDATE %s
 				SET deleted = TRUE
@@ -101,6 +316,7 @@ func (s *PostgresStorage) DeleteAdvert(ctx context.Context, advertID, userID str
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var imageIDs []string
 
@@ -115,17 +331,77 @@ func (s *PostgresStorage) DeleteAdvert(ctx context.Context, advertID, userID str
 		imageIDs = append(imageIDs, imageID)
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
 	return imageIDs, nil
 }
 
+// insertAdvertImages saves the images of an advert. An image which is still processing gets its job
+// in the same transaction, so an upload is never left without a job to process it.
+func insertAdvertImages(ctx context.Context, tx pgx.Tx, images []*models.Image) error {
+	insertImage := fmt.Sprintf(`
+				INSERT INTO %s (id, advert_id, format, content_hash, phash, status, created_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, imagesTable)
+
+	insertJob := fmt.Sprintf(`
+				INSERT INTO %s (image_id, run_at, created_at)
+				VALUES ($1, $2, $2)
+	`, imageJobsTable)
+
+	for _, image := range images {
+		ct, err := tx.Exec(ctx, insertImage, image.ID, image.AdvertID, image.Format, image.ContentHash, image.PHash,
+			image.Status, image.CreatedAt, image.Deleted)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return custom_error.CustomError{Field: "images", Message: ErrAdvertImageNotCreated.Error()}
+		}
+
+		if image.Status != models.ImageStatusProcessing {
+			continue
+		}
+
+		_, err = tx.Exec(ctx, insertJob, image.ID, image.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachAdvertImages moves images uploaded beforehand by the user to the advert.
+// Every image must belong to the user and must not be attached yet, otherwise nothing is attached.
+func attachAdvertImages(ctx context.Context, tx pgx.Tx, advertID, userID string, imageIDs []string) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET advert_id = $1
+				WHERE id = ANY($2) AND user_id = $3 AND advert_id IS NULL AND deleted = false AND status <> $4
+	`, imagesTable)
+
+	ct, err := tx.Exec(ctx, query, advertID, imageIDs, userID, models.ImageStatusFailed)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() != int64(len(imageIDs)) {
+		return custom_error.CustomError{Field: "image_ids", Message: ErrAdvertImageNotAttached.Error()}
+	}
+
+	return nil
+}
+
 func (s *PostgresStorage) GetAdvertByID(ctx context.Context, id string) (models.Advert, error) {
 	var advert models.Advert
-	var imageIDs []string
+	var imageIDs, imageStatuses []string
 
 	query := fmt.Sprintf(`
 				SELECT
@@ -136,12 +412,18 @@ func (s *PostgresStorage) GetAdvertByID(ctx context.Context, id string) (models.
     			a.created_at,
     			a.updated_at,
     			a.user_id,
-    			ARRAY_AGG(i.id) as images
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id ORDER BY i.id) as images,
+    			ARRAY_AGG(i.status ORDER BY i.id) as image_statuses,
+    			(SELECT COUNT(*) FROM %s f WHERE f.advert_id = a.id) as favourites_count
 				FROM %s a
 				JOIN %s i ON a.id = i.advert_id
 				WHERE a.id = $1 AND a.deleted = false AND i.deleted = false
 				GROUP BY a.id
-	`, advertsTable, imagesTable)
+	`, favouritesTable, advertsTable, imagesTable)
 
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&advert.ID,
@@ -151,10 +433,16 @@ func (s *PostgresStorage) GetAdvertByID(ctx context.Context, id string) (models.
 		&advert.CreatedAt,
 		&advert.UpdatedAt,
 		&advert.UserID,
-		&imageIDs)
+		&advert.CategoryID,
+		&advert.Status,
+		&advert.StatusReason,
+		&advert.ExpiresAt,
+		&imageIDs,
+		&imageStatuses,
+		&advert.FavouritesCount)
 
-	for _, imageID := range imageIDs {
-		advert.Images = append(advert.Images, &models.Image{ID: imageID})
+	for i, imageID := range imageIDs {
+		advert.Images = append(advert.Images, &models.Image{ID: imageID, Status: imageStatuses[i]})
 	}
 
 	if err != nil {
@@ -166,3 +454,211 @@ func (s *PostgresStorage) GetAdvertByID(ctx context.Context, id string) (models.
 
 	return advert, nil
 }
+
+// ListAdverts never returns adverts with images which are still processing or have failed.
+func (s *PostgresStorage) ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, error) {
+	conditions := []string{"a.deleted = false", "i.deleted = false"}
+	var args []interface{}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if params.MinPrice != nil {
+		conditions = append(conditions, "a.price >= "+addArg(*params.MinPrice))
+	}
+	if params.MaxPrice != nil {
+		conditions = append(conditions, "a.price <= "+addArg(*params.MaxPrice))
+	}
+	if params.CreatedFrom != nil {
+		conditions = append(conditions, "a.created_at >= "+addArg(*params.CreatedFrom))
+	}
+	if params.CreatedTo != nil {
+		conditions = append(conditions, "a.created_at <= "+addArg(*params.CreatedTo))
+	}
+	if params.UserID != "" {
+		conditions = append(conditions, "a.user_id = "+addArg(params.UserID))
+	}
+	if params.Status != "" {
+		conditions = append(conditions, "a.status = "+addArg(params.Status))
+	}
+	if params.CategoryID != "" {
+		conditions = append(conditions, fmt.Sprintf(`a.category_id IN (
+					WITH RECURSIVE tree AS (
+						SELECT id FROM %s WHERE id = %s
+						UNION ALL
+						SELECT c.id FROM %s c JOIN tree t ON c.parent_id = t.id
+					)
+					SELECT id FROM tree
+				)`, categoriesTable, addArg(params.CategoryID), categoriesTable))
+	}
+
+	sortColumn := "a.created_at"
+	if params.SortBy == models.AdvertSortByPrice {
+		sortColumn = "a.price"
+	}
+
+	order, comparison := "ASC", ">"
+	if params.Order == models.SortOrderDesc {
+		order, comparison = "DESC", "<"
+	}
+
+	// keyset pagination: the id breaks ties between adverts with the same sort value,
+	// so new adverts never shift the pages which were already returned
+	if params.After != nil {
+		var value interface{} = params.After.CreatedAt
+		if params.SortBy == models.AdvertSortByPrice {
+			value = params.After.Price
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, a.id) %s (%s, %s)",
+			sortColumn, comparison, addArg(value), addArg(params.After.ID)))
+	}
+
+	query := fmt.Sprintf(`
+				SELECT
+    			a.id,
+    			a.title,
+    			a.description,
+    			a.price,
+    			a.created_at,
+    			a.updated_at,
+    			a.user_id,
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id) as images
+				FROM %s a
+				JOIN %s i ON a.id = i.advert_id
+				WHERE %s
+				GROUP BY a.id
+				HAVING BOOL_AND(i.status = %s)
+				ORDER BY %s %s, a.id %s
+				LIMIT %s
+	`, advertsTable, imagesTable, strings.Join(conditions, " AND "), addArg(models.ImageStatusReady),
+		sortColumn, order, order, addArg(params.Limit))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adverts []models.Advert
+
+	for rows.Next() {
+		var advert models.Advert
+		var imageIDs []string
+
+		err = rows.Scan(
+			&advert.ID,
+			&advert.Title,
+			&advert.Description,
+			&advert.Price,
+			&advert.CreatedAt,
+			&advert.UpdatedAt,
+			&advert.UserID,
+			&advert.CategoryID,
+			&advert.Status,
+			&advert.StatusReason,
+			&advert.ExpiresAt,
+			&imageIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imageID := range imageIDs {
+			advert.Images = append(advert.Images, &models.Image{ID: imageID})
+		}
+
+		adverts = append(adverts, advert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return adverts, nil
+}
+
+// SearchAdverts finds published adverts whose images are all ready.
+func (s *PostgresStorage) SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error) {
+	query := fmt.Sprintf(`
+				SELECT
+    			a.id,
+    			a.title,
+    			a.description,
+    			a.price,
+    			a.created_at,
+    			a.updated_at,
+    			a.user_id,
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id) as images,
+    			ts_rank(a.search_vector, q.query) as rank,
+    			ts_headline('russian', %s, q.query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+    			ts_headline('russian', %s, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')
+				FROM %s a
+				CROSS JOIN websearch_to_tsquery('russian', $1) as q(query)
+				JOIN %s i ON a.id = i.advert_id
+				WHERE a.search_vector @@ q.query AND a.status = $4 AND a.deleted = false AND i.deleted = false
+				GROUP BY a.id, q.query
+				HAVING BOOL_AND(i.status = $5)
+				ORDER BY rank DESC, a.id
+				LIMIT $2 OFFSET $3
+	`, escapeHTML("a.title"), escapeHTML("coalesce(a.description, '')"), advertsTable, imagesTable)
+
+	rows, err := s.db.Query(ctx, query, params.Query, params.Limit, params.Offset, models.AdvertStatusPublished, models.ImageStatusReady)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.AdvertSearchResult
+
+	for rows.Next() {
+		var result models.AdvertSearchResult
+		var imageIDs []string
+
+		err = rows.Scan(
+			&result.Advert.ID,
+			&result.Advert.Title,
+			&result.Advert.Description,
+			&result.Advert.Price,
+			&result.Advert.CreatedAt,
+			&result.Advert.UpdatedAt,
+			&result.Advert.UserID,
+			&result.Advert.CategoryID,
+			&result.Advert.Status,
+			&result.Advert.StatusReason,
+			&result.Advert.ExpiresAt,
+			&imageIDs,
+			&result.Rank,
+			&result.TitleHighlight,
+			&result.DescriptionHighlight)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imageID := range imageIDs {
+			result.Advert.Images = append(result.Advert.Images, &models.Image{ID: imageID})
+		}
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// escapeHTML wraps a text expression so that its result can be embedded in HTML.
+// The search highlights are built from the escaped text, only the <mark> tags are markup.
+func escapeHTML(expr string) string {
+	return fmt.Sprintf(`replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`, expr)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
@@ -27,26 +28,35 @@ func TestPostgresStorageCreateAdvert(t *testing.T) {
 		CreatedAt:   time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
 		UpdatedAt:   time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
 		UserID:      uuid.New().String(),
+		CategoryID:  uuid.New().String(),
+		Status:      models.AdvertStatusPendingReview,
 		Deleted:     false,
 		Images: []*models.Image{{
 			ID:        uuid.New().String(),
 			Data:      []byte("test data"),
+			Format:    models.ImageFormatJPEG,
 			AdvertID:  advertID,
+			Status:    models.ImageStatusProcessing,
 			CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
 			Deleted:   false,
 		}},
 	}
 
 	insertAdvert := fmt.Sprintf(`
-				INSERT INTO %s (id, title, description, price, created_at, updated_at, user_id, deleted)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				INSERT INTO %s (id, title, description, price, created_at, updated_at, user_id, category_id, status, expires_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`, advertsTable)
 
 	insertImage := fmt.Sprintf(`
-				INSERT INTO %s (id, advert_id, created_at, deleted)
-				VALUES ($1, $2, $3, $4)
+				INSERT INTO %s (id, advert_id, format, content_hash, phash, status, created_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`, imagesTable)
 
+	insertJob := fmt.Sprintf(`
+				INSERT INTO %s (image_id, run_at, created_at)
+				VALUES ($1, $2, $2)
+	`, imageJobsTable)
+
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(insertAdvert)).WithArgs(
 		advert.ID,
@@ -56,14 +66,23 @@ func TestPostgresStorageCreateAdvert(t *testing.T) {
 		advert.CreatedAt,
 		advert.UpdatedAt,
 		advert.UserID,
+		advert.CategoryID,
+		advert.Status,
+		advert.ExpiresAt,
 		advert.Deleted,
 	).WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mock.ExpectExec(regexp.QuoteMeta(insertImage)).WithArgs(
 		advert.Images[0].ID,
 		advert.Images[0].AdvertID,
+		advert.Images[0].Format,
+		advert.Images[0].ContentHash,
+		advert.Images[0].PHash,
+		advert.Images[0].Status,
 		advert.Images[0].CreatedAt,
 		advert.Images[0].Deleted,
 	).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertJob)).WithArgs(advert.Images[0].ID, advert.Images[0].CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mock.ExpectCommit()
 
 	storage := NewPostgresStorage(mock)
@@ -75,6 +94,222 @@ func TestPostgresStorageCreateAdvert(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
+func TestPostgresStorageCreateAdvertImageNotAttached(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	advert := models.Advert{
+		ID:       uuid.New().String(),
+		Title:    "test title",
+		Price:    decimal.New(1200, 0),
+		UserID:   uuid.New().String(),
+		Status:   models.AdvertStatusDraft,
+		ImageIDs: []string{uuid.New().String(), uuid.New().String()},
+	}
+
+	insertAdvert := fmt.Sprintf(`
+				INSERT INTO %s (id, title, description, price, created_at, updated_at, user_id, category_id, status, expires_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, advertsTable)
+
+	attachImages := fmt.Sprintf(`
+				UPDATE %s
+				SET advert_id = $1
+				WHERE id = ANY($2) AND user_id = $3 AND advert_id IS NULL AND deleted = false AND status <> $4
+	`, imagesTable)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(insertAdvert)).WithArgs(
+		advert.ID,
+		advert.Title,
+		advert.Description,
+		advert.Price,
+		advert.CreatedAt,
+		advert.UpdatedAt,
+		advert.UserID,
+		advert.CategoryID,
+		advert.Status,
+		advert.ExpiresAt,
+		advert.Deleted,
+	).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	// the second image belongs to another user
+	mock.ExpectExec(regexp.QuoteMeta(attachImages)).
+		WithArgs(advert.ID, advert.ImageIDs, advert.UserID, models.ImageStatusFailed).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectRollback()
+
+	storage := NewPostgresStorage(mock)
+
+	_, err = storage.CreateAdvert(context.Background(), advert)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "image_ids", Message: ErrAdvertImageNotAttached.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorageUpdateAdvert(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	advertID := uuid.New().String()
+	title := "new title"
+	update := models.AdvertUpdate{
+		ID:        advertID,
+		UserID:    uuid.New().String(),
+		Title:     &title,
+		UpdatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		AddImages: []*models.Image{{
+			ID:        uuid.New().String(),
+			Format:    models.ImageFormatPNG,
+			AdvertID:  advertID,
+			Status:    models.ImageStatusProcessing,
+			CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		RemoveImageIDs: []string{uuid.New().String()},
+		ContentChanged: true,
+	}
+
+	updateAdvert := fmt.Sprintf(`
+				UPDATE %s
+				SET title = COALESCE($3, title),
+				    description = COALESCE($4, description),
+				    price = COALESCE($5, price),
+				    category_id = COALESCE($7, category_id),
+				    status = CASE WHEN $8 AND status = ANY($9) THEN $10 ELSE status END,
+				    status_reason = CASE WHEN $8 THEN '' ELSE status_reason END,
+				    updated_at = $6
+				WHERE id = $1 AND user_id = $2 AND deleted = false
+	`, advertsTable)
+
+	removeImages := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = TRUE
+				WHERE advert_id = $1 AND id = ANY($2) AND deleted = false
+		`, imagesTable)
+
+	insertImage := fmt.Sprintf(`
+				INSERT INTO %s (id, advert_id, format, content_hash, phash, status, created_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, imagesTable)
+
+	insertJob := fmt.Sprintf(`
+				INSERT INTO %s (image_id, run_at, created_at)
+				VALUES ($1, $2, $2)
+	`, imageJobsTable)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(updateAdvert)).WithArgs(
+		update.ID,
+		update.UserID,
+		update.Title,
+		update.Description,
+		update.Price,
+		update.UpdatedAt,
+		update.CategoryID,
+		true,
+		[]string{models.AdvertStatusPublished, models.AdvertStatusRejected},
+		models.AdvertStatusPendingReview,
+	).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(removeImages)).WithArgs(advertID, update.RemoveImageIDs).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertImage)).WithArgs(
+		update.AddImages[0].ID,
+		update.AddImages[0].AdvertID,
+		update.AddImages[0].Format,
+		update.AddImages[0].ContentHash,
+		update.AddImages[0].PHash,
+		update.AddImages[0].Status,
+		update.AddImages[0].CreatedAt,
+		update.AddImages[0].Deleted,
+	).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertJob)).WithArgs(update.AddImages[0].ID, update.AddImages[0].CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.UpdateAdvert(context.Background(), update)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageUpdateAdvertNotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	update := models.AdvertUpdate{
+		ID:     uuid.New().String(),
+		UserID: uuid.New().String(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE adverts").
+		WithArgs(update.ID, update.UserID, update.Title, update.Description, update.Price, update.UpdatedAt, update.CategoryID,
+			false, []string{models.AdvertStatusPublished, models.AdvertStatusRejected}, models.AdvertStatusPendingReview).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectRollback()
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.UpdateAdvert(context.Background(), update)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrAdvertNotFound.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageUpdateAdvertContentChanged(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	price := decimal.New(150, 0)
+	update := models.AdvertUpdate{
+		ID:             uuid.New().String(),
+		UserID:         uuid.New().String(),
+		Price:          &price,
+		UpdatedAt:      time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		ContentChanged: true,
+	}
+
+	// a published or rejected advert goes back to moderation and loses the rejection reason
+	updateAdvert := fmt.Sprintf(`
+				UPDATE %s
+				SET title = COALESCE($3, title),
+				    description = COALESCE($4, description),
+				    price = COALESCE($5, price),
+				    category_id = COALESCE($7, category_id),
+				    status = CASE WHEN $8 AND status = ANY($9) THEN $10 ELSE status END,
+				    status_reason = CASE WHEN $8 THEN '' ELSE status_reason END,
+				    updated_at = $6
+				WHERE id = $1 AND user_id = $2 AND deleted = false
+	`, advertsTable)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(updateAdvert)).WithArgs(
+		update.ID,
+		update.UserID,
+		update.Title,
+		update.Description,
+		update.Price,
+		update.UpdatedAt,
+		update.CategoryID,
+		true,
+		[]string{models.AdvertStatusPublished, models.AdvertStatusRejected},
+		models.AdvertStatusPendingReview,
+	).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.UpdateAdvert(context.Background(), update)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
 func TestPostgresStorageDeleteAdvert(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -144,6 +379,173 @@ func TestPostgresStorageDeleteAdvertError(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
+func TestPostgresStorageDeleteAnyAdvert(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	advertID := uuid.New().String()
+
+	updateAdverts := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = TRUE
+				WHERE id = $1 AND deleted = false
+	`, advertsTable)
+
+	updateImages := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = TRUE
+				WHERE advert_id = $1 RETURNING id
+	`, imagesTable)
+
+	rows := pgxmock.NewRows([]string{"id"}).AddRow("test id 1")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(updateAdverts)).WithArgs(advertID).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(regexp.QuoteMeta(updateImages)).WithArgs(advertID).WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	images, err := storage.DeleteAnyAdvert(context.Background(), advertID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test id 1"}, images)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageUpdateAdvertStatus(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	change := models.AdvertStatusChange{
+		ID:        uuid.New().String(),
+		From:      []string{models.AdvertStatusPendingReview},
+		To:        models.AdvertStatusRejected,
+		Reason:    "prohibited goods",
+		UpdatedAt: time.Now(),
+	}
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = $3, updated_at = $4, expires_at = COALESCE($7, expires_at)
+				WHERE id = $1 AND status = ANY($5) AND ($6 = '' OR user_id = $6) AND deleted = false
+	`, advertsTable)
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(change.ID, change.To, change.Reason, change.UpdatedAt, change.From, change.UserID, change.ExpiresAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.UpdateAdvertStatus(context.Background(), change)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageUpdateAdvertStatusNotChanged(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	change := models.AdvertStatusChange{
+		ID:        uuid.New().String(),
+		UserID:    uuid.New().String(),
+		From:      []string{models.AdvertStatusPublished},
+		To:        models.AdvertStatusSold,
+		UpdatedAt: time.Now(),
+	}
+
+	mock.ExpectExec("UPDATE adverts").
+		WithArgs(change.ID, change.To, change.Reason, change.UpdatedAt, change.From, change.UserID, change.ExpiresAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.UpdateAdvertStatus(context.Background(), change)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "status", Message: ErrAdvertStatusNotChanged.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageArchiveExpiredAdverts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	expected := []models.ExpiredAdvert{
+		{
+			ID:        uuid.New().String(),
+			Title:     "bike",
+			UserID:    uuid.New().String(),
+			UserEmail: "seller@mail.com",
+		},
+	}
+
+	query := fmt.Sprintf(`
+				UPDATE %s a
+				SET status = $2, status_reason = $3, updated_at = $4
+				FROM %s u
+				WHERE u.id = a.user_id AND a.id IN (
+					SELECT id FROM %s
+					WHERE status = $1 AND deleted = false AND expires_at <= $4
+					ORDER BY expires_at
+					LIMIT $5
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING a.id, a.title, a.user_id, u.email
+	`, advertsTable, usersTable, advertsTable)
+
+	rows := pgxmock.NewRows([]string{"id", "title", "user_id", "email"}).
+		AddRow(expected[0].ID, expected[0].Title, expected[0].UserID, expected[0].UserEmail)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(models.AdvertStatusPublished, models.AdvertStatusArchived, models.AdvertStatusReasonExpired, now, 100).
+		WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	adverts, err := storage.ArchiveExpiredAdverts(context.Background(), now, 100)
+	require.NoError(t, err)
+	require.Equal(t, expected, adverts)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageRenewAdvertNotRenewed(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New().String()
+	userID := uuid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(30 * 24 * time.Hour)
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $3, status_reason = '', expires_at = $4, updated_at = $5
+				WHERE id = $1 AND user_id = $2 AND deleted = false
+				  AND (status = $3 OR (status = $6 AND status_reason = $7))
+	`, advertsTable)
+
+	// an advert archived by its owner is not renewed
+	mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, userID, models.AdvertStatusPublished, expiresAt, now,
+			models.AdvertStatusArchived, models.AdvertStatusReasonExpired).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.RenewAdvert(context.Background(), id, userID, expiresAt, now)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrAdvertNotRenewed.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
 func TestPostgresStorageGetAdvertByID(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -158,12 +560,18 @@ func TestPostgresStorageGetAdvertByID(t *testing.T) {
     			a.created_at,
     			a.updated_at,
     			a.user_id,
-    			ARRAY_AGG(i.id) as images
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id ORDER BY i.id) as images,
+    			ARRAY_AGG(i.status ORDER BY i.id) as image_statuses,
+    			(SELECT COUNT(*) FROM %s f WHERE f.advert_id = a.id) as favourites_count
 				FROM %s a
 				JOIN %s i ON a.id = i.advert_id
 				WHERE a.id = $1 AND a.deleted = false AND i.deleted = false
 				GROUP BY a.id
-	`, advertsTable, imagesTable)
+	`, favouritesTable, advertsTable, imagesTable)
 
 	expectedID := uuid.New().String()
 	expectedImageIDs := []string{"id1", "id2"}
@@ -178,15 +586,19 @@ func TestPostgresStorageGetAdvertByID(t *testing.T) {
 		UserID:      uuid.New().String(),
 		Images: []*models.Image{
 			{
-				ID: "id1",
+				ID:     "id1",
+				Status: models.ImageStatusReady,
 			},
 			{
-				ID: "id2",
+				ID:     "id2",
+				Status: models.ImageStatusProcessing,
 			},
 		},
+		FavouritesCount: 3,
 	}
 
-	columns := []string{"id", "title", "desctiption", "price", "created_at", "updated_at", "user_id", "images"}
+	columns := []string{"id", "title", "desctiption", "price", "created_at", "updated_at", "user_id", "category_id", "status", "status_reason", "expires_at",
+		"images", "image_statuses", "favourites_count"}
 	rows := pgxmock.NewRows(columns).
 		AddRow(expectedID,
 			expectedAdvert.Title,
@@ -195,7 +607,13 @@ func TestPostgresStorageGetAdvertByID(t *testing.T) {
 			expectedAdvert.CreatedAt,
 			expectedAdvert.UpdatedAt,
 			expectedAdvert.UserID,
-			expectedImageIDs)
+			expectedAdvert.CategoryID,
+			expectedAdvert.Status,
+			expectedAdvert.StatusReason,
+			expectedAdvert.ExpiresAt,
+			expectedImageIDs,
+			[]string{models.ImageStatusReady, models.ImageStatusProcessing},
+			3)
 
 	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedID).WillReturnRows(rows)
 
@@ -225,12 +643,18 @@ func TestPostgresStorageGetAdvertByIDError(t *testing.T) {
     			a.created_at,
     			a.updated_at,
     			a.user_id,
-    			ARRAY_AGG(i.id) as images
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id ORDER BY i.id) as images,
+    			ARRAY_AGG(i.status ORDER BY i.id) as image_statuses,
+    			(SELECT COUNT(*) FROM %s f WHERE f.advert_id = a.id) as favourites_count
 				FROM %s a
 				JOIN %s i ON a.id = i.advert_id
 				WHERE a.id = $1 AND a.deleted = false AND i.deleted = false
 				GROUP BY a.id
-	`, advertsTable, imagesTable)
+	`, favouritesTable, advertsTable, imagesTable)
 
 	expectedID := uuid.New().String()
 
@@ -246,3 +670,127 @@ func TestPostgresStorageGetAdvertByIDError(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
+
+func TestPostgresStorageListAdverts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	minPrice := decimal.New(100, 0)
+	after := models.AdvertCursor{
+		Price: decimal.New(500, 0),
+		ID:    uuid.New().String(),
+	}
+	params := models.AdvertListParams{
+		Limit:    3,
+		SortBy:   models.AdvertSortByPrice,
+		Order:    models.SortOrderAsc,
+		MinPrice: &minPrice,
+		After:    &after,
+	}
+
+	query := fmt.Sprintf(`
+				FROM %s a
+				JOIN %s i ON a.id = i.advert_id
+				WHERE a.deleted = false AND i.deleted = false AND a.price >= $1 AND (a.price, a.id) > ($2, $3)
+				GROUP BY a.id
+				HAVING BOOL_AND(i.status = $4)
+				ORDER BY a.price ASC, a.id ASC
+				LIMIT $5
+	`, advertsTable, imagesTable)
+
+	expectedAdvert := models.Advert{
+		ID:          uuid.New().String(),
+		Title:       "test",
+		Description: "test",
+		Price:       decimal.New(700, 0),
+		UserID:      uuid.New().String(),
+		Images:      []*models.Image{{ID: "id1"}},
+	}
+
+	columns := []string{"id", "title", "desctiption", "price", "created_at", "updated_at", "user_id", "category_id", "status", "status_reason", "expires_at", "images"}
+	rows := pgxmock.NewRows(columns).
+		AddRow(expectedAdvert.ID,
+			expectedAdvert.Title,
+			expectedAdvert.Description,
+			expectedAdvert.Price,
+			expectedAdvert.CreatedAt,
+			expectedAdvert.UpdatedAt,
+			expectedAdvert.UserID,
+			expectedAdvert.CategoryID,
+			expectedAdvert.Status,
+			expectedAdvert.StatusReason,
+			expectedAdvert.ExpiresAt,
+			[]string{"id1"})
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(minPrice, after.Price, after.ID, models.ImageStatusReady, params.Limit).
+		WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	adverts, err := storage.ListAdverts(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, []models.Advert{expectedAdvert}, adverts)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageSearchAdverts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	params := models.AdvertSearchParams{
+		Query:  "велосипед",
+		Limit:  20,
+		Offset: 0,
+	}
+
+	expectedResult := models.AdvertSearchResult{
+		Advert: models.Advert{
+			ID:          uuid.New().String(),
+			Title:       "Велосипед",
+			Description: "test <script>",
+			Price:       decimal.New(700, 0),
+			UserID:      uuid.New().String(),
+			Images:      []*models.Image{{ID: "id1"}},
+		},
+		Rank:                 0.6,
+		TitleHighlight:       "<mark>Велосипед</mark>",
+		DescriptionHighlight: "test &lt;script&gt;",
+	}
+
+	columns := []string{"id", "title", "desctiption", "price", "created_at", "updated_at", "user_id", "category_id", "status", "status_reason", "expires_at", "images",
+		"rank", "title_highlight", "description_highlight"}
+	rows := pgxmock.NewRows(columns).
+		AddRow(expectedResult.Advert.ID,
+			expectedResult.Advert.Title,
+			expectedResult.Advert.Description,
+			expectedResult.Advert.Price,
+			expectedResult.Advert.CreatedAt,
+			expectedResult.Advert.UpdatedAt,
+			expectedResult.Advert.UserID,
+			expectedResult.Advert.CategoryID,
+			expectedResult.Advert.Status,
+			expectedResult.Advert.StatusReason,
+			expectedResult.Advert.ExpiresAt,
+			[]string{"id1"},
+			expectedResult.Rank,
+			expectedResult.TitleHighlight,
+			expectedResult.DescriptionHighlight)
+
+	// the highlights are built from the escaped text of the advert
+	headline := `ts_headline('russian', replace(replace(replace(replace(coalesce(a.description, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), q.query`
+	mock.ExpectQuery(regexp.QuoteMeta(headline)).
+		WithArgs(params.Query, params.Limit, params.Offset, models.AdvertStatusPublished, models.ImageStatusReady).
+		WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	results, err := storage.SearchAdverts(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, []models.AdvertSearchResult{expectedResult}, results)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
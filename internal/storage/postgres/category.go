@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+)
+
+var (
+	ErrCategoryNotCreated = errors.New("category was not created")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrCategoryInUse      = errors.New("category has subcategories or adverts")
+)
+
+// foreignKeyViolation is the postgres error code of a broken foreign key.
+const foreignKeyViolation = "23503"
+
+func (s *PostgresStorage) CreateCategory(ctx context.Context, category models.Category) (string, error) {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (id, name, parent_id, created_at, updated_at)
+				VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+	`, categoriesTable)
+
+	ct, err := s.db.Exec(ctx, query,
+		category.ID,
+		category.Name,
+		category.ParentID,
+		category.CreatedAt,
+		category.UpdatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return "", custom_error.CustomError{Field: "", Message: ErrCategoryNotCreated.Error()}
+	}
+
+	return category.ID, nil
+}
+
+func (s *PostgresStorage) GetCategoryByID(ctx context.Context, id string) (models.Category, error) {
+	var category models.Category
+
+	query := fmt.Sprintf(`
+				SELECT id, name, COALESCE(parent_id, ''), created_at, updated_at
+				FROM %s
+				WHERE id = $1
+	`, categoriesTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&category.ID,
+		&category.Name,
+		&category.ParentID,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return category, custom_error.CustomError{Field: "id", Message: ErrCategoryNotFound.Error()}
+		}
+		return category, err
+	}
+
+	childrenQuery := fmt.Sprintf(`
+				SELECT id, name, COALESCE(parent_id, ''), created_at, updated_at
+				FROM %s
+				WHERE parent_id = $1
+				ORDER BY name
+	`, categoriesTable)
+
+	children, err := s.queryCategories(ctx, childrenQuery, id)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	for i := range children {
+		category.Children = append(category.Children, &children[i])
+	}
+
+	return category, nil
+}
+
+func (s *PostgresStorage) ListCategories(ctx context.Context) ([]models.Category, error) {
+	query := fmt.Sprintf(`
+				SELECT id, name, COALESCE(parent_id, ''), created_at, updated_at
+				FROM %s
+				ORDER BY name
+	`, categoriesTable)
+
+	return s.queryCategories(ctx, query)
+}
+
+// CategoryHasAdverts reports whether not deleted adverts are placed directly into the category.
+func (s *PostgresStorage) CategoryHasAdverts(ctx context.Context, id string) (bool, error) {
+	var exists bool
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (SELECT 1 FROM %s WHERE category_id = $1 AND deleted = false)
+	`, advertsTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *PostgresStorage) UpdateCategory(ctx context.Context, category models.Category) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET name = $2, parent_id = NULLIF($3, ''), updated_at = $4
+				WHERE id = $1
+	`, categoriesTable)
+
+	ct, err := s.db.Exec(ctx, query, category.ID, category.Name, category.ParentID, category.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrCategoryNotFound.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) DeleteCategory(ctx context.Context, id string) error {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE id = $1
+	`, categoriesTable)
+
+	ct, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return custom_error.CustomError{Field: "id", Message: ErrCategoryInUse.Error()}
+		}
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrCategoryNotFound.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) queryCategories(ctx context.Context, query string, args ...interface{}) ([]models.Category, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+
+	for rows.Next() {
+		var category models.Category
+
+		err = rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.ParentID,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
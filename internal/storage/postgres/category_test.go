@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageCreateCategory(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				INSERT INTO %s (id, name, parent_id, created_at, updated_at)
+				VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+	`, categoriesTable)
+
+	category := models.Category{
+		ID:        uuid.New().String(),
+		Name:      "Велосипеды",
+		ParentID:  uuid.New().String(),
+		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
+		category.ID,
+		category.Name,
+		category.ParentID,
+		category.CreatedAt,
+		category.UpdatedAt,
+	).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	storage := NewPostgresStorage(mock)
+
+	id, err := storage.CreateCategory(context.Background(), category)
+	require.NoError(t, err)
+	require.Equal(t, category.ID, id)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageGetCategoryByID(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT id, name, COALESCE(parent_id, ''), created_at, updated_at
+				FROM %s
+				WHERE id = $1
+	`, categoriesTable)
+
+	childrenQuery := fmt.Sprintf(`
+				SELECT id, name, COALESCE(parent_id, ''), created_at, updated_at
+				FROM %s
+				WHERE parent_id = $1
+				ORDER BY name
+	`, categoriesTable)
+
+	tm := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	expectedCategory := models.Category{
+		ID:        uuid.New().String(),
+		Name:      "Транспорт",
+		CreatedAt: tm,
+		UpdatedAt: tm,
+	}
+	child := models.Category{
+		ID:        uuid.New().String(),
+		Name:      "Велосипеды",
+		ParentID:  expectedCategory.ID,
+		CreatedAt: tm,
+		UpdatedAt: tm,
+	}
+	expectedCategory.Children = []*models.Category{&child}
+
+	columns := []string{"id", "name", "parent_id", "created_at", "updated_at"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedCategory.ID).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(expectedCategory.ID, expectedCategory.Name, "", tm, tm))
+	mock.ExpectQuery(regexp.QuoteMeta(childrenQuery)).WithArgs(expectedCategory.ID).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(child.ID, child.Name, child.ParentID, tm, tm))
+
+	storage := NewPostgresStorage(mock)
+
+	category, err := storage.GetCategoryByID(context.Background(), expectedCategory.ID)
+	require.NoError(t, err)
+	require.Equal(t, expectedCategory, category)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageDeleteCategoryInUse(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE id = $1
+	`, categoriesTable)
+
+	id := uuid.New().String()
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id).
+		WillReturnError(&pgconn.PgError{Code: foreignKeyViolation})
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.DeleteCategory(context.Background(), id)
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrCategoryInUse.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageCategoryHasAdverts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (SELECT 1 FROM %s WHERE category_id = $1 AND deleted = false)
+	`, advertsTable)
+
+	id := uuid.New().String()
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id).WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	storage := NewPostgresStorage(mock)
+
+	hasAdverts, err := storage.CategoryHasAdverts(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, hasAdverts)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/romandnk/advertisement/internal/models"
+)
+
+// The phash_bands column splits the 64-bit perceptual hash into phashBands bands of phashBandBits bits.
+const (
+	phashBands    = 4
+	phashBandBits = 16
+)
+
+// FindDuplicateAdverts returns other not deleted adverts whose images differ from the images
+// of the advert in at most maxDistance bits of the perceptual hash, closest first.
+func (s *PostgresStorage) FindDuplicateAdverts(ctx context.Context, advertID string, maxDistance, limit int) ([]models.DuplicateAdvert, error) {
+	phashes, err := s.advertImageHashes(ctx, advertID)
+	if err != nil {
+		return nil, err
+	}
+	if len(phashes) == 0 {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+				SELECT a.id, a.title, a.user_id, a.status, i.id, d.id, bit_count((i.phash # d.phash)::bit(64)) AS distance
+				FROM %s i
+				JOIN %s d ON d.advert_id <> i.advert_id AND d.deleted = false AND d.phash IS NOT NULL
+					AND d.phash_bands && $4 AND bit_count((i.phash # d.phash)::bit(64)) <= $2
+				JOIN %s a ON a.id = d.advert_id AND a.deleted = false
+				WHERE i.advert_id = $1 AND i.deleted = false AND i.phash IS NOT NULL
+				ORDER BY distance, a.id, d.id
+				LIMIT $3
+	`, imagesTable, imagesTable, advertsTable)
+
+	rows, err := s.db.Query(ctx, query, advertID, maxDistance, limit, phashBandKeys(phashes, maxDistance))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []models.DuplicateAdvert
+	for rows.Next() {
+		var duplicate models.DuplicateAdvert
+
+		err = rows.Scan(
+			&duplicate.ID,
+			&duplicate.Title,
+			&duplicate.UserID,
+			&duplicate.Status,
+			&duplicate.ImageID,
+			&duplicate.DuplicateImageID,
+			&duplicate.Distance,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicates = append(duplicates, duplicate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
+
+// HasBannedUserImage reports whether a banned user has ever uploaded an advert image similar to phash.
+// Deleted images count too: moderators usually delete the adverts of the users they ban.
+func (s *PostgresStorage) HasBannedUserImage(ctx context.Context, phash int64, maxDistance int) (bool, error) {
+	query := fmt.Sprintf(`
+				SELECT EXISTS (
+					SELECT 1
+					FROM %s i
+					JOIN %s a ON a.id = i.advert_id
+					JOIN %s u ON u.id = a.user_id
+					WHERE u.banned = true AND i.phash IS NOT NULL
+						AND i.phash_bands && $3 AND bit_count((i.phash # $1)::bit(64)) <= $2
+				)
+	`, imagesTable, advertsTable, usersTable)
+
+	var exists bool
+
+	err := s.db.QueryRow(ctx, query, phash, maxDistance, phashBandKeys([]int64{phash}, maxDistance)).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *PostgresStorage) advertImageHashes(ctx context.Context, advertID string) ([]int64, error) {
+	query := fmt.Sprintf(`
+				SELECT phash
+				FROM %s
+				WHERE advert_id = $1 AND deleted = false AND phash IS NOT NULL
+	`, imagesTable)
+
+	rows, err := s.db.Query(ctx, query, advertID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var phashes []int64
+	for rows.Next() {
+		var phash int64
+		if err := rows.Scan(&phash); err != nil {
+			return nil, err
+		}
+		phashes = append(phashes, phash)
+	}
+
+	return phashes, rows.Err()
+}
+
+// phashBandKeys returns the values of the phash_bands column which hashes within maxDistance bits
+// of one of phashes may have. At least one of the four bands of such a hash differs from the band
+// of the phash in at most maxDistance/4 bits, so it is enough to look for these band values.
+func phashBandKeys(phashes []int64, maxDistance int) []int32 {
+	radius := maxDistance / phashBands
+
+	var keys []int32
+	seen := make(map[int32]struct{})
+	for _, phash := range phashes {
+		for band := 0; band < phashBands; band++ {
+			value := uint16(uint64(phash) >> (phashBandBits * (phashBands - 1 - band)))
+			for _, near := range flipBits(value, 0, radius, nil) {
+				key := int32(band<<phashBandBits | int(near))
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+// flipBits appends value and every value made of it by flipping at most radius of its bits starting from bit from.
+func flipBits(value uint16, from, radius int, values []uint16) []uint16 {
+	values = append(values, value)
+	if radius == 0 {
+		return values
+	}
+	for bit := from; bit < phashBandBits; bit++ {
+		values = flipBits(value^1<<bit, bit+1, radius-1, values)
+	}
+	return values
+}
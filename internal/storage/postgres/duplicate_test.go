@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestPostgresStorageFindDuplicateAdverts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	hashesQuery := fmt.Sprintf(`
+				SELECT phash
+				FROM %s
+				WHERE advert_id = $1 AND deleted = false AND phash IS NOT NULL
+	`, imagesTable)
+
+	query := fmt.Sprintf(`
+				SELECT a.id, a.title, a.user_id, a.status, i.id, d.id, bit_count((i.phash # d.phash)::bit(64)) AS distance
+				FROM %s i
+				JOIN %s d ON d.advert_id <> i.advert_id AND d.deleted = false AND d.phash IS NOT NULL
+					AND d.phash_bands && $4 AND bit_count((i.phash # d.phash)::bit(64)) <= $2
+				JOIN %s a ON a.id = d.advert_id AND a.deleted = false
+				WHERE i.advert_id = $1 AND i.deleted = false AND i.phash IS NOT NULL
+				ORDER BY distance, a.id, d.id
+				LIMIT $3
+	`, imagesTable, imagesTable, advertsTable)
+
+	columns := []string{"id", "title", "user_id", "status", "image_id", "duplicate_image_id", "distance"}
+	rows := pgxmock.NewRows(columns).
+		AddRow("advert id 2", "title 2", "user id 2", models.AdvertStatusPublished, "image id 1", "image id 2", 0).
+		AddRow("advert id 3", "title 3", "user id 3", models.AdvertStatusArchived, "image id 1", "image id 3", 4)
+
+	mock.ExpectQuery(regexp.QuoteMeta(hashesQuery)).WithArgs("advert id 1").
+		WillReturnRows(pgxmock.NewRows([]string{"phash"}).AddRow(int64(42)))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("advert id 1", 5, 100, phashBandKeys([]int64{42}, 5)).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	duplicates, err := storage.FindDuplicateAdverts(context.Background(), "advert id 1", 5, 100)
+	require.NoError(t, err)
+
+	expectedDuplicates := []models.DuplicateAdvert{
+		{
+			ID:               "advert id 2",
+			Title:            "title 2",
+			UserID:           "user id 2",
+			Status:           models.AdvertStatusPublished,
+			ImageID:          "image id 1",
+			DuplicateImageID: "image id 2",
+			Distance:         0,
+		},
+		{
+			ID:               "advert id 3",
+			Title:            "title 3",
+			UserID:           "user id 3",
+			Status:           models.AdvertStatusArchived,
+			ImageID:          "image id 1",
+			DuplicateImageID: "image id 3",
+			Distance:         4,
+		},
+	}
+
+	require.Equal(t, expectedDuplicates, duplicates)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageFindDuplicateAdvertsNoHashes(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	// images uploaded before perceptual hashes have nothing to compare
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT phash")).WithArgs("advert id 1").
+		WillReturnRows(pgxmock.NewRows([]string{"phash"}))
+
+	storage := NewPostgresStorage(mock)
+
+	duplicates, err := storage.FindDuplicateAdverts(context.Background(), "advert id 1", 5, 100)
+	require.NoError(t, err)
+	require.Empty(t, duplicates)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPhashBandKeys(t *testing.T) {
+	// the same values as the generated phash_bands column
+	bands := func(phash int64) []int32 {
+		return []int32{
+			int32(phash >> 48 & 65535),
+			int32(65536 + (phash >> 32 & 65535)),
+			int32(131072 + (phash >> 16 & 65535)),
+			int32(196608 + (phash & 65535)),
+		}
+	}
+
+	phash := int64(-0x0123456789abcdef)
+
+	require.Equal(t, bands(phash), phashBandKeys([]int64{phash}, 3))
+	require.Len(t, phashBandKeys([]int64{phash}, 5), 4*(1+16))
+	require.Len(t, phashBandKeys([]int64{phash, phash}, 5), 4*(1+16))
+
+	keys := make(map[int32]bool)
+	for _, key := range phashBandKeys([]int64{phash}, 7) {
+		keys[key] = true
+	}
+
+	// 7 differing bits spread over all bands, one band differs in a single bit
+	near := phash ^ (1 | 1<<17 | 1<<18 | 1<<33 | 1<<34 | 1<<49 | 1<<50)
+	var found bool
+	for _, band := range bands(near) {
+		found = found || keys[band]
+	}
+	require.True(t, found)
+}
+
+func TestPostgresStorageHasBannedUserImage(t *testing.T) {
+	query := fmt.Sprintf(`
+				SELECT EXISTS (
+					SELECT 1
+					FROM %s i
+					JOIN %s a ON a.id = i.advert_id
+					JOIN %s u ON u.id = a.user_id
+					WHERE u.banned = true AND i.phash IS NOT NULL
+						AND i.phash_bands && $3 AND bit_count((i.phash # $1)::bit(64)) <= $2
+				)
+	`, imagesTable, advertsTable, usersTable)
+
+	testCases := []struct {
+		name           string
+		exists         bool
+		err            error
+		expectedExists bool
+	}{
+		{name: "exists", exists: true, expectedExists: true},
+		{name: "not exists", exists: false, expectedExists: false},
+		{name: "error", err: errors.New("db is down")},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			expected := mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(-42), 5, phashBandKeys([]int64{-42}, 5))
+			if tc.err != nil {
+				expected.WillReturnError(tc.err)
+			} else {
+				expected.WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+			}
+
+			storage := NewPostgresStorage(mock)
+
+			exists, err := storage.HasBannedUserImage(context.Background(), -42, 5)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expectedExists, exists)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/romandnk/advertisement/internal/models"
+	"strings"
+)
+
+// AddFavourite adds the advert to the favourites of the user, adding it twice keeps the first time.
+func (s *PostgresStorage) AddFavourite(ctx context.Context, favourite models.Favourite) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (user_id, advert_id, created_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, advert_id) DO NOTHING
+	`, favouritesTable)
+
+	_, err := s.db.Exec(ctx, query, favourite.UserID, favourite.AdvertID, favourite.CreatedAt)
+	return err
+}
+
+// RemoveFavourite removes the advert from the favourites of the user, removing a missing one is not an error.
+func (s *PostgresStorage) RemoveFavourite(ctx context.Context, userID, advertID string) error {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE user_id = $1 AND advert_id = $2
+	`, favouritesTable)
+
+	_, err := s.db.Exec(ctx, query, userID, advertID)
+	return err
+}
+
+func (s *PostgresStorage) IsFavourite(ctx context.Context, userID, advertID string) (bool, error) {
+	var found bool
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (SELECT 1 FROM %s WHERE user_id = $1 AND advert_id = $2)
+	`, favouritesTable)
+
+	err := s.db.QueryRow(ctx, query, userID, advertID).Scan(&found)
+	return found, err
+}
+
+// ListFavourites never returns deleted adverts and adverts with images which are still processing or have failed.
+func (s *PostgresStorage) ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, error) {
+	conditions := []string{"f.user_id = $1", "a.deleted = false", "i.deleted = false", "a.status = ANY($2)"}
+	args := []interface{}{params.UserID, params.Statuses}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// keyset pagination like in ListAdverts, an advert is added to favourites only once
+	if params.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(f.created_at, a.id) < (%s, %s)",
+			addArg(params.After.CreatedAt), addArg(params.After.ID)))
+	}
+
+	query := fmt.Sprintf(`
+				SELECT
+    			a.id,
+    			a.title,
+    			a.description,
+    			a.price,
+    			a.created_at,
+    			a.updated_at,
+    			a.user_id,
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id ORDER BY i.id) as images,
+    			f.created_at
+				FROM %s f
+				JOIN %s a ON a.id = f.advert_id
+				JOIN %s i ON a.id = i.advert_id
+				WHERE %s
+				GROUP BY a.id, f.created_at
+				HAVING BOOL_AND(i.status = %s)
+				ORDER BY f.created_at DESC, a.id DESC
+				LIMIT %s
+	`, favouritesTable, advertsTable, imagesTable, strings.Join(conditions, " AND "),
+		addArg(models.ImageStatusReady), addArg(params.Limit))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var favourites []models.FavouriteAdvert
+
+	for rows.Next() {
+		var favourite models.FavouriteAdvert
+		var imageIDs []string
+
+		advert := &favourite.Advert
+		err = rows.Scan(
+			&advert.ID,
+			&advert.Title,
+			&advert.Description,
+			&advert.Price,
+			&advert.CreatedAt,
+			&advert.UpdatedAt,
+			&advert.UserID,
+			&advert.CategoryID,
+			&advert.Status,
+			&advert.StatusReason,
+			&advert.ExpiresAt,
+			&imageIDs,
+			&favourite.FavouritedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imageID := range imageIDs {
+			advert.Images = append(advert.Images, &models.Image{ID: imageID})
+		}
+
+		favourites = append(favourites, favourite)
+	}
+
+	return favourites, rows.Err()
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageListFavourites(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	after := models.AdvertCursor{
+		CreatedAt: time.Date(2023, time.August, 11, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.New().String(),
+	}
+	params := models.FavouriteListParams{
+		UserID:   uuid.New().String(),
+		Statuses: []string{models.AdvertStatusPublished, models.AdvertStatusSold},
+		Limit:    3,
+		After:    &after,
+	}
+
+	query := fmt.Sprintf(`
+				SELECT
+    			a.id,
+    			a.title,
+    			a.description,
+    			a.price,
+    			a.created_at,
+    			a.updated_at,
+    			a.user_id,
+    			COALESCE(a.category_id, ''),
+    			a.status,
+    			a.status_reason,
+    			a.expires_at,
+    			ARRAY_AGG(i.id ORDER BY i.id) as images,
+    			f.created_at
+				FROM %s f
+				JOIN %s a ON a.id = f.advert_id
+				JOIN %s i ON a.id = i.advert_id
+				WHERE f.user_id = $1 AND a.deleted = false AND i.deleted = false AND a.status = ANY($2) AND (f.created_at, a.id) < ($3, $4)
+				GROUP BY a.id, f.created_at
+				HAVING BOOL_AND(i.status = $5)
+				ORDER BY f.created_at DESC, a.id DESC
+				LIMIT $6
+	`, favouritesTable, advertsTable, imagesTable)
+
+	favouritedAt := after.CreatedAt.Add(-time.Hour)
+	expectedAdvert := models.Advert{
+		ID:     uuid.New().String(),
+		Title:  "test",
+		Price:  decimal.New(1200, 0),
+		UserID: uuid.New().String(),
+		Status: models.AdvertStatusSold,
+		Images: []*models.Image{{ID: "image id"}},
+	}
+
+	columns := []string{"id", "title", "description", "price", "created_at", "updated_at", "user_id", "category_id", "status", "status_reason", "expires_at",
+		"images", "favourited_at"}
+	rows := pgxmock.NewRows(columns).
+		AddRow(expectedAdvert.ID,
+			expectedAdvert.Title,
+			expectedAdvert.Description,
+			expectedAdvert.Price,
+			expectedAdvert.CreatedAt,
+			expectedAdvert.UpdatedAt,
+			expectedAdvert.UserID,
+			expectedAdvert.CategoryID,
+			expectedAdvert.Status,
+			expectedAdvert.StatusReason,
+			expectedAdvert.ExpiresAt,
+			[]string{"image id"},
+			favouritedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(params.UserID, params.Statuses, after.CreatedAt, after.ID, models.ImageStatusReady, params.Limit).
+		WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	favourites, err := storage.ListFavourites(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, []models.FavouriteAdvert{{Advert: expectedAdvert, FavouritedAt: favouritedAt}}, favourites)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorageAddFavouriteTwice(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				INSERT INTO %s (user_id, advert_id, created_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, advert_id) DO NOTHING
+	`, favouritesTable)
+
+	favourite := models.Favourite{
+		UserID:    uuid.New().String(),
+		AdvertID:  uuid.New().String(),
+		CreatedAt: time.Now(),
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(favourite.UserID, favourite.AdvertID, favourite.CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	storage := NewPostgresStorage(mock)
+
+	require.NoError(t, storage.AddFavourite(context.Background(), favourite))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
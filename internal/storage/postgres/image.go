@@ -7,15 +7,19 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
+	"time"
 )
 
-var ErrImageNotFound = errors.New("image not found")
+var (
+	ErrImageNotFound    = errors.New("image not found")
+	ErrImageJobNotFound = errors.New("image job not found")
+)
 
 func (s *PostgresStorage) GetImageByID(ctx context.Context, id string) (models.Image, error) {
 	var image models.Image
 
 	query := fmt.Sprintf(`
-				SELECT id, advert_id, created_at, deleted
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), status, status_reason, created_at, deleted
 				FROM %s
 				WHERE id = $1
 	`, imagesTable)
@@ -23,6 +27,10 @@ func (s *PostgresStorage) GetImageByID(ctx context.Context, id string) (models.I
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&image.ID,
 		&image.AdvertID,
+		&image.Format,
+		&image.ContentHash,
+		&image.Status,
+		&image.StatusReason,
 		&image.CreatedAt,
 		&image.Deleted,
 	)
@@ -39,3 +47,269 @@ func (s *PostgresStorage) GetImageByID(ctx context.Context, id string) (models.I
 
 	return image, nil
 }
+
+// ListImages returns images ordered by id starting after afterID, deleted ones included.
+func (s *PostgresStorage) ListImages(ctx context.Context, afterID string, limit int) ([]models.Image, error) {
+	query := fmt.Sprintf(`
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), created_at, deleted
+				FROM %s
+				WHERE id > $1
+				ORDER BY id
+				LIMIT $2
+	`, imagesTable)
+
+	rows, err := s.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.Image
+	for rows.Next() {
+		var image models.Image
+
+		err = rows.Scan(
+			&image.ID,
+			&image.AdvertID,
+			&image.Format,
+			&image.ContentHash,
+			&image.CreatedAt,
+			&image.Deleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// GetImagesByIDs returns the images found among ids, deleted ones included.
+func (s *PostgresStorage) GetImagesByIDs(ctx context.Context, ids []string) ([]models.Image, error) {
+	query := fmt.Sprintf(`
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), status, created_at, deleted
+				FROM %s
+				WHERE id = ANY($1)
+	`, imagesTable)
+
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.Image
+	for rows.Next() {
+		var image models.Image
+
+		err = rows.Scan(
+			&image.ID,
+			&image.AdvertID,
+			&image.Format,
+			&image.ContentHash,
+			&image.Status,
+			&image.CreatedAt,
+			&image.Deleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// ClaimImageJobs locks up to limit jobs which are due until lockedUntil and returns them.
+// SKIP LOCKED lets several workers claim different jobs at the same time,
+// a job whose lock expired, e.g. because its worker died, is claimed again.
+func (s *PostgresStorage) ClaimImageJobs(ctx context.Context, now, lockedUntil time.Time, limit int) ([]models.ImageJob, error) {
+	query := fmt.Sprintf(`
+				UPDATE %s j
+				SET attempts = j.attempts + 1, locked_until = $2
+				FROM %s i
+				WHERE i.id = j.image_id AND j.image_id IN (
+					SELECT image_id FROM %s
+					WHERE run_at <= $1 AND (locked_until IS NULL OR locked_until <= $1)
+					ORDER BY run_at
+					LIMIT $3
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING j.image_id, COALESCE(i.advert_id, ''), i.format, j.attempts
+	`, imageJobsTable, imagesTable, imageJobsTable)
+
+	rows, err := s.db.Query(ctx, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.ImageJob
+	for rows.Next() {
+		var job models.ImageJob
+		if err := rows.Scan(&job.ImageID, &job.AdvertID, &job.Format, &job.Attempts); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// CompleteImageJob marks the processed image as ready and removes its job.
+func (s *PostgresStorage) CompleteImageJob(ctx context.Context, image models.Image) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	updateImage := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = '', format = $3, content_hash = $4, phash = $5
+				WHERE id = $1
+	`, imagesTable)
+
+	_, err = tx.Exec(ctx, updateImage, image.ID, models.ImageStatusReady, image.Format, image.ContentHash, image.PHash)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteImageJob(ctx, tx, image.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetryImageJob unlocks the job and postpones it until runAt.
+func (s *PostgresStorage) RetryImageJob(ctx context.Context, imageID string, runAt time.Time, lastError string) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET run_at = $2, locked_until = NULL, last_error = $3
+				WHERE image_id = $1
+	`, imageJobsTable)
+
+	ct, err := s.db.Exec(ctx, query, imageID, runAt, lastError)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "image_id", Message: ErrImageJobNotFound.Error()}
+	}
+
+	return nil
+}
+
+// FailImageJob marks the image as failed with the reason and removes its job.
+func (s *PostgresStorage) FailImageJob(ctx context.Context, imageID, reason string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	updateImage := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = $3
+				WHERE id = $1
+	`, imagesTable)
+
+	_, err = tx.Exec(ctx, updateImage, imageID, models.ImageStatusFailed, reason)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteImageJob(ctx, tx, imageID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func deleteImageJob(ctx context.Context, tx pgx.Tx, imageID string) error {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE image_id = $1
+	`, imageJobsTable)
+
+	ct, err := tx.Exec(ctx, query, imageID)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "image_id", Message: ErrImageJobNotFound.Error()}
+	}
+
+	return nil
+}
+
+// CreateImage saves an image uploaded before its advert together with its processing job.
+func (s *PostgresStorage) CreateImage(ctx context.Context, image models.Image) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	insertImage := fmt.Sprintf(`
+				INSERT INTO %s (id, user_id, format, status, created_at, deleted)
+				VALUES ($1, $2, $3, $4, $5, $6)
+	`, imagesTable)
+
+	_, err = tx.Exec(ctx, insertImage, image.ID, image.UserID, image.Format, image.Status, image.CreatedAt, image.Deleted)
+	if err != nil {
+		return err
+	}
+
+	insertJob := fmt.Sprintf(`
+				INSERT INTO %s (image_id, run_at, created_at)
+				VALUES ($1, $2, $2)
+	`, imageJobsTable)
+
+	_, err = tx.Exec(ctx, insertJob, image.ID, image.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteUnattachedImages marks as deleted up to limit images uploaded before before and never attached to an advert
+// and returns their ids. Images still being processed are left to their job.
+func (s *PostgresStorage) DeleteUnattachedImages(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET deleted = true
+				WHERE id IN (
+					SELECT id FROM %s
+					WHERE advert_id IS NULL AND user_id IS NOT NULL AND deleted = false AND status <> $2 AND created_at < $1
+					ORDER BY created_at
+					LIMIT $3
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING id
+	`, imagesTable, imagesTable)
+
+	rows, err := s.db.Query(ctx, query, before, models.ImageStatusProcessing, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
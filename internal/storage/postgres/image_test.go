@@ -19,7 +19,7 @@ func TestPostgresStorageGetImageByID(t *testing.T) {
 	defer mock.Close()
 
 	query := fmt.Sprintf(`
-				SELECT id, advert_id, created_at, deleted
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), status, status_reason, created_at, deleted
 				FROM %s
 				WHERE id = $1
 	`, imagesTable)
@@ -27,9 +27,9 @@ func TestPostgresStorageGetImageByID(t *testing.T) {
 	expectedID := "test id 1"
 	createdAt := time.Now()
 
-	columns := []string{"id", "advert_id", "created_at", "deleted"}
+	columns := []string{"id", "advert_id", "format", "content_hash", "status", "status_reason", "created_at", "deleted"}
 	rows := pgxmock.NewRows(columns).
-		AddRow("test id 1", "advert id 1", createdAt, false)
+		AddRow("test id 1", "advert id 1", models.ImageFormatPNG, "hash 1", models.ImageStatusReady, "", createdAt, false)
 
 	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedID).WillReturnRows(rows)
 
@@ -39,11 +39,14 @@ func TestPostgresStorageGetImageByID(t *testing.T) {
 	require.NoError(t, err)
 
 	expectedImage := models.Image{
-		ID:        expectedID,
-		Data:      nil,
-		AdvertID:  "advert id 1",
-		CreatedAt: createdAt,
-		Deleted:   false,
+		ID:          expectedID,
+		Data:        nil,
+		Format:      models.ImageFormatPNG,
+		ContentHash: "hash 1",
+		AdvertID:    "advert id 1",
+		Status:      models.ImageStatusReady,
+		CreatedAt:   createdAt,
+		Deleted:     false,
 	}
 
 	require.Equal(t, expectedImage, image)
@@ -57,7 +60,7 @@ func TestPostgresStorageGetImageByIDError(t *testing.T) {
 	defer mock.Close()
 
 	query := fmt.Sprintf(`
-				SELECT id, advert_id, created_at, deleted
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), status, status_reason, created_at, deleted
 				FROM %s
 				WHERE id = $1
 	`, imagesTable)
@@ -78,3 +81,214 @@ func TestPostgresStorageGetImageByIDError(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
+
+func TestPostgresStorageListImages(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), created_at, deleted
+				FROM %s
+				WHERE id > $1
+				ORDER BY id
+				LIMIT $2
+	`, imagesTable)
+
+	createdAt := time.Now()
+
+	columns := []string{"id", "advert_id", "format", "content_hash", "created_at", "deleted"}
+	rows := pgxmock.NewRows(columns).
+		AddRow("test id 2", "advert id 1", models.ImageFormatJPEG, "hash 2", createdAt, false).
+		AddRow("test id 3", "", models.ImageFormatPNG, "", createdAt, true)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("test id 1", 2).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	images, err := storage.ListImages(context.Background(), "test id 1", 2)
+	require.NoError(t, err)
+
+	expectedImages := []models.Image{
+		{ID: "test id 2", Format: models.ImageFormatJPEG, ContentHash: "hash 2", AdvertID: "advert id 1", CreatedAt: createdAt},
+		{ID: "test id 3", Format: models.ImageFormatPNG, CreatedAt: createdAt, Deleted: true},
+	}
+
+	require.Equal(t, expectedImages, images)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageGetImagesByIDs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT id, COALESCE(advert_id, ''), format, COALESCE(content_hash, ''), status, created_at, deleted
+				FROM %s
+				WHERE id = ANY($1)
+	`, imagesTable)
+
+	ids := []string{"test id 1", "test id 2", "test id 3"}
+	createdAt := time.Now()
+
+	columns := []string{"id", "advert_id", "format", "content_hash", "status", "created_at", "deleted"}
+	rows := pgxmock.NewRows(columns).
+		AddRow("test id 1", "advert id 1", models.ImageFormatJPEG, "hash 1", models.ImageStatusReady, createdAt, false).
+		AddRow("test id 3", "", models.ImageFormatPNG, "", models.ImageStatusProcessing, createdAt, true)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(ids).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	images, err := storage.GetImagesByIDs(context.Background(), ids)
+	require.NoError(t, err)
+
+	expectedImages := []models.Image{
+		{ID: "test id 1", Format: models.ImageFormatJPEG, ContentHash: "hash 1", AdvertID: "advert id 1", Status: models.ImageStatusReady, CreatedAt: createdAt},
+		{ID: "test id 3", Format: models.ImageFormatPNG, Status: models.ImageStatusProcessing, CreatedAt: createdAt, Deleted: true},
+	}
+
+	require.Equal(t, expectedImages, images)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageClaimImageJobs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				UPDATE %s j
+				SET attempts = j.attempts + 1, locked_until = $2
+				FROM %s i
+				WHERE i.id = j.image_id AND j.image_id IN (
+					SELECT image_id FROM %s
+					WHERE run_at <= $1 AND (locked_until IS NULL OR locked_until <= $1)
+					ORDER BY run_at
+					LIMIT $3
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING j.image_id, COALESCE(i.advert_id, ''), i.format, j.attempts
+	`, imageJobsTable, imagesTable, imageJobsTable)
+
+	now := time.Now()
+	lockedUntil := now.Add(time.Minute)
+
+	expected := []models.ImageJob{
+		{ImageID: "image id 1", AdvertID: "advert id 1", Format: models.ImageFormatJPEG, Attempts: 1},
+		{ImageID: "image id 2", AdvertID: "advert id 1", Format: models.ImageFormatWebP, Attempts: 3},
+	}
+
+	rows := pgxmock.NewRows([]string{"image_id", "advert_id", "format", "attempts"}).
+		AddRow(expected[0].ImageID, expected[0].AdvertID, expected[0].Format, expected[0].Attempts).
+		AddRow(expected[1].ImageID, expected[1].AdvertID, expected[1].Format, expected[1].Attempts)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(now, lockedUntil, 10).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	jobs, err := storage.ClaimImageJobs(context.Background(), now, lockedUntil, 10)
+	require.NoError(t, err)
+	require.Equal(t, expected, jobs)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageCompleteImageJob(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	updateImage := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = '', format = $3, content_hash = $4, phash = $5
+				WHERE id = $1
+	`, imagesTable)
+
+	deleteJob := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE image_id = $1
+	`, imageJobsTable)
+
+	image := models.Image{
+		ID:          uuid.New().String(),
+		Format:      models.ImageFormatPNG,
+		ContentHash: "hash 1",
+		PHash:       -42,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(updateImage)).
+		WithArgs(image.ID, models.ImageStatusReady, image.Format, image.ContentHash, image.PHash).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(deleteJob)).WithArgs(image.ID).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.CompleteImageJob(context.Background(), image)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageRetryImageJobNotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET run_at = $2, locked_until = NULL, last_error = $3
+				WHERE image_id = $1
+	`, imageJobsTable)
+
+	imageID := uuid.New().String()
+	runAt := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(imageID, runAt, "store is down").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.RetryImageJob(context.Background(), imageID, runAt, "store is down")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "image_id", Message: ErrImageJobNotFound.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageFailImageJob(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	updateImage := fmt.Sprintf(`
+				UPDATE %s
+				SET status = $2, status_reason = $3
+				WHERE id = $1
+	`, imagesTable)
+
+	deleteJob := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE image_id = $1
+	`, imageJobsTable)
+
+	imageID := uuid.New().String()
+	reason := "image cannot be decoded"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(updateImage)).WithArgs(imageID, models.ImageStatusFailed, reason).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(deleteJob)).WithArgs(imageID).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.FailImageJob(context.Background(), imageID, reason)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+)
+
+var ErrThreadNotFound = errors.New("thread not found")
+
+// SendMessage saves the message and counts it as unread for the recipient.
+// The thread is created with the first message, a buyer has only one thread per advert,
+// so a new thread for the same advert and buyer continues the existing one. It returns the thread id.
+func (s *PostgresStorage) SendMessage(ctx context.Context, thread models.Thread, message models.Message) (string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var sellerUnread, buyerUnread int
+	if message.SenderID == thread.BuyerID {
+		sellerUnread = 1
+	} else {
+		buyerUnread = 1
+	}
+
+	upsertThread := fmt.Sprintf(`
+				INSERT INTO %s AS t (id, advert_id, seller_id, buyer_id, seller_unread, buyer_unread, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+				ON CONFLICT (advert_id, buyer_id) DO UPDATE
+				SET seller_unread = t.seller_unread + EXCLUDED.seller_unread,
+					buyer_unread = t.buyer_unread + EXCLUDED.buyer_unread,
+					updated_at = EXCLUDED.updated_at
+				RETURNING t.id
+	`, threadsTable)
+
+	var threadID string
+	err = tx.QueryRow(ctx, upsertThread, thread.ID, thread.AdvertID, thread.SellerID, thread.BuyerID,
+		sellerUnread, buyerUnread, message.CreatedAt).Scan(&threadID)
+	if err != nil {
+		return "", err
+	}
+
+	insertMessage := fmt.Sprintf(`
+				INSERT INTO %s (id, thread_id, sender_id, body, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+	`, messagesTable)
+
+	_, err = tx.Exec(ctx, insertMessage, message.ID, threadID, message.SenderID, message.Body, message.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return threadID, nil
+}
+
+// GetThreadByID returns the thread only to its seller and buyer.
+func (s *PostgresStorage) GetThreadByID(ctx context.Context, id, userID string) (models.Thread, error) {
+	var thread models.Thread
+
+	query := fmt.Sprintf(`
+				SELECT
+    			t.id,
+    			t.advert_id,
+    			a.title,
+    			t.seller_id,
+    			t.buyer_id,
+    			CASE WHEN t.seller_id = $2 THEN t.seller_unread ELSE t.buyer_unread END,
+    			t.created_at,
+    			t.updated_at
+				FROM %s t
+				JOIN %s a ON a.id = t.advert_id
+				WHERE t.id = $1 AND (t.seller_id = $2 OR t.buyer_id = $2)
+	`, threadsTable, advertsTable)
+
+	err := scanThread(s.db.QueryRow(ctx, query, id, userID), &thread)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return thread, custom_error.CustomError{Field: "id", Message: ErrThreadNotFound.Error()}
+		}
+		return thread, err
+	}
+
+	return thread, nil
+}
+
+func (s *PostgresStorage) ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, error) {
+	args := []interface{}{params.UserID}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	condition := ""
+	if params.After != nil {
+		condition = fmt.Sprintf(" AND (t.updated_at, t.id) < (%s, %s)", addArg(params.After.CreatedAt), addArg(params.After.ID))
+	}
+
+	query := fmt.Sprintf(`
+				SELECT
+    			t.id,
+    			t.advert_id,
+    			a.title,
+    			t.seller_id,
+    			t.buyer_id,
+    			CASE WHEN t.seller_id = $1 THEN t.seller_unread ELSE t.buyer_unread END,
+    			t.created_at,
+    			t.updated_at
+				FROM %s t
+				JOIN %s a ON a.id = t.advert_id
+				WHERE (t.seller_id = $1 OR t.buyer_id = $1)%s
+				ORDER BY t.updated_at DESC, t.id DESC
+				LIMIT %s
+	`, threadsTable, advertsTable, condition, addArg(params.Limit))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []models.Thread
+	for rows.Next() {
+		var thread models.Thread
+		if err := scanThread(rows, &thread); err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+
+	return threads, rows.Err()
+}
+
+func scanThread(row pgx.Row, thread *models.Thread) error {
+	return row.Scan(
+		&thread.ID,
+		&thread.AdvertID,
+		&thread.AdvertTitle,
+		&thread.SellerID,
+		&thread.BuyerID,
+		&thread.Unread,
+		&thread.CreatedAt,
+		&thread.UpdatedAt,
+	)
+}
+
+func (s *PostgresStorage) ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, error) {
+	args := []interface{}{params.ThreadID}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	condition := ""
+	if params.After != nil {
+		condition = fmt.Sprintf(" AND (created_at, id) < (%s, %s)", addArg(params.After.CreatedAt), addArg(params.After.ID))
+	}
+
+	query := fmt.Sprintf(`
+				SELECT id, thread_id, sender_id, body, created_at
+				FROM %s
+				WHERE thread_id = $1%s
+				ORDER BY created_at DESC, id DESC
+				LIMIT %s
+	`, messagesTable, condition, addArg(params.Limit))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var message models.Message
+		err := rows.Scan(&message.ID, &message.ThreadID, &message.SenderID, &message.Body, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// MarkThreadRead marks messages of the other user up to readUntil as read by the user.
+// Only messages sent after readUntil are left in the unread counter of the user.
+func (s *PostgresStorage) MarkThreadRead(ctx context.Context, id, userID string, readUntil models.AdvertCursor) error {
+	query := fmt.Sprintf(`
+				UPDATE %s t
+				SET seller_unread = CASE WHEN t.seller_id = $2 THEN LEAST(t.seller_unread, n.unread) ELSE t.seller_unread END,
+					buyer_unread = CASE WHEN t.buyer_id = $2 THEN LEAST(t.buyer_unread, n.unread) ELSE t.buyer_unread END
+				FROM (
+					SELECT COUNT(*) AS unread
+					FROM %s
+					WHERE thread_id = $1 AND sender_id <> $2 AND (created_at, id) > ($3, $4)
+				) n
+				WHERE t.id = $1 AND CASE WHEN t.seller_id = $2 THEN t.seller_unread ELSE t.buyer_unread END > n.unread
+	`, threadsTable, messagesTable)
+
+	_, err := s.db.Exec(ctx, query, id, userID, readUntil.CreatedAt, readUntil.ID)
+	return err
+}
+
+// CountUnreadMessages sums unread messages of the user over all threads.
+func (s *PostgresStorage) CountUnreadMessages(ctx context.Context, userID string) (int, error) {
+	var unread int
+
+	query := fmt.Sprintf(`
+				SELECT COALESCE(SUM(CASE WHEN seller_id = $1 THEN seller_unread ELSE buyer_unread END), 0)
+				FROM %s
+				WHERE seller_id = $1 OR buyer_id = $1
+	`, threadsTable)
+
+	err := s.db.QueryRow(ctx, query, userID).Scan(&unread)
+	return unread, err
+}
+
+// BlockUser blocks the user, blocking them twice keeps the first time.
+func (s *PostgresStorage) BlockUser(ctx context.Context, block models.UserBlock) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (user_id, blocked_id, created_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, blocked_id) DO NOTHING
+	`, userBlocksTable)
+
+	_, err := s.db.Exec(ctx, query, block.UserID, block.BlockedID, block.CreatedAt)
+	return err
+}
+
+func (s *PostgresStorage) UnblockUser(ctx context.Context, userID, blockedID string) error {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE user_id = $1 AND blocked_id = $2
+	`, userBlocksTable)
+
+	_, err := s.db.Exec(ctx, query, userID, blockedID)
+	return err
+}
+
+// IsBlocked reports whether either of the users has blocked the other one.
+func (s *PostgresStorage) IsBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+	var blocked bool
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (
+					SELECT 1 FROM %s
+					WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+				)
+	`, userBlocksTable)
+
+	err := s.db.QueryRow(ctx, query, userID, otherID).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageSendMessage(t *testing.T) {
+	upsertThread := fmt.Sprintf(`
+				INSERT INTO %s AS t (id, advert_id, seller_id, buyer_id, seller_unread, buyer_unread, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+				ON CONFLICT (advert_id, buyer_id) DO UPDATE
+				SET seller_unread = t.seller_unread + EXCLUDED.seller_unread,
+					buyer_unread = t.buyer_unread + EXCLUDED.buyer_unread,
+					updated_at = EXCLUDED.updated_at
+				RETURNING t.id
+	`, threadsTable)
+
+	insertMessage := fmt.Sprintf(`
+				INSERT INTO %s (id, thread_id, sender_id, body, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+	`, messagesTable)
+
+	thread := models.Thread{
+		ID:       uuid.New().String(),
+		AdvertID: uuid.New().String(),
+		SellerID: "seller id",
+		BuyerID:  "buyer id",
+	}
+
+	testCases := []struct {
+		name         string
+		senderID     string
+		sellerUnread int
+		buyerUnread  int
+	}{
+		{
+			name:         "buyer writes to seller",
+			senderID:     thread.BuyerID,
+			sellerUnread: 1,
+		},
+		{
+			name:        "seller replies",
+			senderID:    thread.SellerID,
+			buyerUnread: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			message := models.Message{
+				ID:        uuid.New().String(),
+				SenderID:  tc.senderID,
+				Body:      "is it still available?",
+				CreatedAt: time.Now(),
+			}
+
+			// the buyer already has a thread about the advert, the message goes there
+			existingThreadID := uuid.New().String()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(upsertThread)).
+				WithArgs(thread.ID, thread.AdvertID, thread.SellerID, thread.BuyerID, tc.sellerUnread, tc.buyerUnread, message.CreatedAt).
+				WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(existingThreadID))
+			mock.ExpectExec(regexp.QuoteMeta(insertMessage)).
+				WithArgs(message.ID, existingThreadID, message.SenderID, message.Body, message.CreatedAt).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mock.ExpectCommit()
+
+			storage := NewPostgresStorage(mock)
+
+			threadID, err := storage.SendMessage(context.Background(), thread, message)
+			require.NoError(t, err)
+			require.Equal(t, existingThreadID, threadID)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorageListMessages(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	after := models.AdvertCursor{
+		CreatedAt: time.Date(2023, time.August, 11, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.New().String(),
+	}
+	params := models.MessageListParams{
+		ThreadID: uuid.New().String(),
+		Limit:    2,
+		After:    &after,
+	}
+
+	query := fmt.Sprintf(`
+				SELECT id, thread_id, sender_id, body, created_at
+				FROM %s
+				WHERE thread_id = $1 AND (created_at, id) < ($2, $3)
+				ORDER BY created_at DESC, id DESC
+				LIMIT $4
+	`, messagesTable)
+
+	expectedMessage := models.Message{
+		ID:        uuid.New().String(),
+		ThreadID:  params.ThreadID,
+		SenderID:  "buyer id",
+		Body:      "hello",
+		CreatedAt: after.CreatedAt.Add(-time.Minute),
+	}
+
+	rows := pgxmock.NewRows([]string{"id", "thread_id", "sender_id", "body", "created_at"}).
+		AddRow(expectedMessage.ID, expectedMessage.ThreadID, expectedMessage.SenderID, expectedMessage.Body, expectedMessage.CreatedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(params.ThreadID, after.CreatedAt, after.ID, params.Limit).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	messages, err := storage.ListMessages(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, []models.Message{expectedMessage}, messages)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorageMarkThreadRead(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New().String()
+	userID := uuid.New().String()
+	readUntil := models.AdvertCursor{CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), ID: uuid.New().String()}
+
+	// messages which arrived after the page was read stay unread
+	query := fmt.Sprintf(`
+				UPDATE %s t
+				SET seller_unread = CASE WHEN t.seller_id = $2 THEN LEAST(t.seller_unread, n.unread) ELSE t.seller_unread END,
+					buyer_unread = CASE WHEN t.buyer_id = $2 THEN LEAST(t.buyer_unread, n.unread) ELSE t.buyer_unread END
+				FROM (
+					SELECT COUNT(*) AS unread
+					FROM %s
+					WHERE thread_id = $1 AND sender_id <> $2 AND (created_at, id) > ($3, $4)
+				) n
+				WHERE t.id = $1 AND CASE WHEN t.seller_id = $2 THEN t.seller_unread ELSE t.buyer_unread END > n.unread
+	`, threadsTable, messagesTable)
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id, userID, readUntil.CreatedAt, readUntil.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.MarkThreadRead(context.Background(), id, userID, readUntil)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
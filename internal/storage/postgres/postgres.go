@@ -8,9 +8,19 @@ import (
 )
 
 var (
-	usersTable   = "users"
-	advertsTable = "adverts"
-	imagesTable  = "images"
+	usersTable         = "users"
+	advertsTable       = "adverts"
+	imagesTable        = "images"
+	categoriesTable    = "categories"
+	sessionsTable      = "sessions"
+	revokedTokensTable = "revoked_tokens"
+	userTokensTable    = "user_tokens"
+	imageJobsTable     = "image_jobs"
+	uploadsTable       = "uploads"
+	favouritesTable    = "favourites"
+	threadsTable       = "threads"
+	messagesTable      = "messages"
+	userBlocksTable    = "user_blocks"
 )
 
 func NewPostgresDB(ctx context.Context, cfg configs.PostgresConf) (*pgxpool.Pool, error) {
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"time"
+)
+
+var (
+	ErrSessionNotCreated = errors.New("session was not created")
+	ErrSessionNotFound   = errors.New("session not found")
+)
+
+func (s *PostgresStorage) CreateSession(ctx context.Context, session models.Session) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (id, user_id, refresh_token_hash, user_agent, created_at, last_used_at, expires_at, revoked)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, sessionsTable)
+
+	ct, err := s.db.Exec(ctx, query,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastUsedAt,
+		session.ExpiresAt,
+		session.Revoked,
+	)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "", Message: ErrSessionNotCreated.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) GetSessionByID(ctx context.Context, id string) (models.Session, error) {
+	var session models.Session
+
+	query := fmt.Sprintf(`
+				SELECT id, user_id, refresh_token_hash, user_agent, created_at, last_used_at, expires_at, revoked
+				FROM %s
+				WHERE id = $1
+	`, sessionsTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.Revoked,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return session, custom_error.CustomError{Field: "refresh_token", Message: ErrSessionNotFound.Error()}
+		}
+		return session, err
+	}
+
+	return session, nil
+}
+
+// RotateSession replaces the refresh token hash only if the session still holds oldHash,
+// so two concurrent refreshes with the same token cannot both succeed.
+func (s *PostgresStorage) RotateSession(ctx context.Context, session models.Session, oldHash string) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET refresh_token_hash = $3, last_used_at = $4, expires_at = $5
+				WHERE id = $1 AND refresh_token_hash = $2 AND revoked = false
+	`, sessionsTable)
+
+	ct, err := s.db.Exec(ctx, query, session.ID, oldHash, session.RefreshTokenHash, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "refresh_token", Message: ErrSessionNotFound.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) RevokeSession(ctx context.Context, id, userID string) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET revoked = TRUE
+				WHERE id = $1 AND user_id = $2
+	`, sessionsTable)
+
+	ct, err := s.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrSessionNotFound.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) ListSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	query := fmt.Sprintf(`
+				SELECT id, user_id, user_agent, created_at, last_used_at, expires_at, revoked
+				FROM %s
+				WHERE user_id = $1 AND revoked = false AND expires_at > $2
+				ORDER BY last_used_at DESC
+	`, sessionsTable)
+
+	rows, err := s.db.Query(ctx, query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+
+	for rows.Next() {
+		var session models.Session
+
+		err = rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.Revoked,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *PostgresStorage) RevokeUserSessions(ctx context.Context, userID string) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET revoked = TRUE
+				WHERE user_id = $1 AND revoked = false
+	`, sessionsTable)
+
+	_, err := s.db.Exec(ctx, query, userID)
+
+	return err
+}
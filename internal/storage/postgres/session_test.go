@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageGetSessionByID(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT id, user_id, refresh_token_hash, user_agent, created_at, last_used_at, expires_at, revoked
+				FROM %s
+				WHERE id = $1
+	`, sessionsTable)
+
+	tm := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	expectedSession := models.Session{
+		ID:               uuid.New().String(),
+		UserID:           uuid.New().String(),
+		RefreshTokenHash: "hash",
+		UserAgent:        "agent",
+		CreatedAt:        tm,
+		LastUsedAt:       tm,
+		ExpiresAt:        tm.Add(time.Hour),
+		Revoked:          false,
+	}
+
+	columns := []string{"id", "user_id", "refresh_token_hash", "user_agent", "created_at", "last_used_at", "expires_at", "revoked"}
+	rows := pgxmock.NewRows(columns).AddRow(
+		expectedSession.ID,
+		expectedSession.UserID,
+		expectedSession.RefreshTokenHash,
+		expectedSession.UserAgent,
+		expectedSession.CreatedAt,
+		expectedSession.LastUsedAt,
+		expectedSession.ExpiresAt,
+		expectedSession.Revoked,
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedSession.ID).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	session, err := storage.GetSessionByID(context.Background(), expectedSession.ID)
+	require.NoError(t, err)
+	require.Equal(t, expectedSession, session)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageRotateSessionReused(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET refresh_token_hash = $3, last_used_at = $4, expires_at = $5
+				WHERE id = $1 AND refresh_token_hash = $2 AND revoked = false
+	`, sessionsTable)
+
+	tm := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	session := models.Session{
+		ID:               uuid.New().String(),
+		RefreshTokenHash: "new hash",
+		LastUsedAt:       tm,
+		ExpiresAt:        tm.Add(time.Hour),
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(session.ID, "old hash", session.RefreshTokenHash, session.LastUsedAt, session.ExpiresAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.RotateSession(context.Background(), session, "old hash")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "refresh_token", Message: ErrSessionNotFound.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RevokeToken puts the token id into the denylist until the token expires by itself.
+func (s *PostgresStorage) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (jti, expires_at)
+				VALUES ($1, $2)
+				ON CONFLICT (jti) DO NOTHING
+	`, revokedTokensTable)
+
+	_, err := s.db.Exec(ctx, query, id, expiresAt)
+
+	return err
+}
+
+func (s *PostgresStorage) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var revoked bool
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (SELECT 1 FROM %s WHERE jti = $1)
+	`, revokedTokensTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpiredTokens removes up to limit denylist entries of tokens that cannot be used anyway.
+func (s *PostgresStorage) DeleteExpiredTokens(ctx context.Context, now time.Time, limit int) (int64, error) {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE jti IN (
+					SELECT jti FROM %s
+					WHERE expires_at <= $1
+					LIMIT $2
+				)
+	`, revokedTokensTable, revokedTokensTable)
+
+	ct, err := s.db.Exec(ctx, query, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageRevokeToken(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				INSERT INTO %s (jti, expires_at)
+				VALUES ($1, $2)
+				ON CONFLICT (jti) DO NOTHING
+	`, revokedTokensTable)
+
+	id := uuid.New().String()
+	expiresAt := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id, expiresAt).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	storage := NewPostgresStorage(mock)
+
+	err = storage.RevokeToken(context.Background(), id, expiresAt)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageIsTokenRevoked(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT EXISTS (SELECT 1 FROM %s WHERE jti = $1)
+	`, revokedTokensTable)
+
+	id := uuid.New().String()
+
+	rows := pgxmock.NewRows([]string{"exists"}).AddRow(true)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id).WillReturnRows(rows)
+
+	storage := NewPostgresStorage(mock)
+
+	revoked, err := storage.IsTokenRevoked(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageDeleteExpiredTokens(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE jti IN (
+					SELECT jti FROM %s
+					WHERE expires_at <= $1
+					LIMIT $2
+				)
+	`, revokedTokensTable, revokedTokensTable)
+
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(now, 100).WillReturnResult(pgxmock.NewResult("DELETE", 7))
+
+	storage := NewPostgresStorage(mock)
+
+	deleted, err := storage.DeleteExpiredTokens(context.Background(), now, 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), deleted)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
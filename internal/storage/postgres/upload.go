@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"time"
+)
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match the received bytes")
+)
+
+func (s *PostgresStorage) CreateUpload(ctx context.Context, upload models.Upload) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (id, user_id, length, received, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+	`, uploadsTable)
+
+	_, err := s.db.Exec(ctx, query, upload.ID, upload.UserID, upload.Length, upload.Offset, upload.CreatedAt, upload.UpdatedAt)
+	return err
+}
+
+// GetUpload returns the upload only to its owner.
+func (s *PostgresStorage) GetUpload(ctx context.Context, id, userID string) (models.Upload, error) {
+	var upload models.Upload
+
+	query := fmt.Sprintf(`
+				SELECT id, user_id, length, received, created_at, updated_at
+				FROM %s
+				WHERE id = $1 AND user_id = $2
+	`, uploadsTable)
+
+	err := s.db.QueryRow(ctx, query, id, userID).Scan(
+		&upload.ID,
+		&upload.UserID,
+		&upload.Length,
+		&upload.Offset,
+		&upload.CreatedAt,
+		&upload.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return upload, custom_error.CustomError{Field: "id", Message: ErrUploadNotFound.Error()}
+		}
+		return upload, err
+	}
+
+	return upload, nil
+}
+
+// AdvanceUpload moves the offset of the upload from one position to another only if it is still at from,
+// so of two requests writing at the same offset only one succeeds.
+func (s *PostgresStorage) AdvanceUpload(ctx context.Context, id, userID string, from, to int64, updatedAt time.Time) error {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET received = $4, updated_at = $5
+				WHERE id = $1 AND user_id = $2 AND received = $3
+	`, uploadsTable)
+
+	ct, err := s.db.Exec(ctx, query, id, userID, from, to, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "Upload-Offset", Message: ErrUploadOffsetMismatch.Error()}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) DeleteUpload(ctx context.Context, id, userID string) error {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE id = $1 AND user_id = $2
+	`, uploadsTable)
+
+	ct, err := s.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrUploadNotFound.Error()}
+	}
+
+	return nil
+}
+
+// DeleteStaleUploads removes up to limit uploads last written before before and returns them.
+func (s *PostgresStorage) DeleteStaleUploads(ctx context.Context, before time.Time, limit int) ([]models.Upload, error) {
+	query := fmt.Sprintf(`
+				DELETE FROM %s
+				WHERE id IN (
+					SELECT id FROM %s
+					WHERE updated_at < $1
+					ORDER BY updated_at
+					LIMIT $2
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING id, user_id, length, received, created_at, updated_at
+	`, uploadsTable, uploadsTable)
+
+	rows, err := s.db.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []models.Upload
+	for rows.Next() {
+		var upload models.Upload
+		err := rows.Scan(
+			&upload.ID,
+			&upload.UserID,
+			&upload.Length,
+			&upload.Offset,
+			&upload.CreatedAt,
+			&upload.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
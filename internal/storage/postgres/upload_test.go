@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageAdvanceUpload(t *testing.T) {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET received = $4, updated_at = $5
+				WHERE id = $1 AND user_id = $2 AND received = $3
+	`, uploadsTable)
+
+	testCases := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{
+			name:         "offset is advanced",
+			rowsAffected: 1,
+		},
+		{
+			name:          "another chunk was written at the offset",
+			rowsAffected:  0,
+			expectedError: custom_error.CustomError{Field: "Upload-Offset", Message: ErrUploadOffsetMismatch.Error()},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			updatedAt := time.Now()
+
+			mock.ExpectExec(regexp.QuoteMeta(query)).
+				WithArgs("upload id", "user id", int64(0), int64(100), updatedAt).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.rowsAffected))
+
+			storage := NewPostgresStorage(mock)
+
+			err = storage.AdvanceUpload(context.Background(), "upload id", "user id", 0, 100, updatedAt)
+			if tc.expectedError != nil {
+				require.True(t, errors.Is(err, tc.expectedError))
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorageGetUploadOfAnotherUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	query := fmt.Sprintf(`
+				SELECT id, user_id, length, received, created_at, updated_at
+				FROM %s
+				WHERE id = $1 AND user_id = $2
+	`, uploadsTable)
+
+	columns := []string{"id", "user_id", "length", "received", "created_at", "updated_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("upload id", "user id").WillReturnRows(pgxmock.NewRows(columns))
+
+	storage := NewPostgresStorage(mock)
+
+	_, err = storage.GetUpload(context.Background(), "upload id", "user id")
+	require.ErrorIs(t, err, custom_error.CustomError{Field: "id", Message: ErrUploadNotFound.Error()})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
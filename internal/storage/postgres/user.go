@@ -7,20 +7,22 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
+	"time"
 )
 
 var (
 	ErrUserNotCreated   = errors.New("user was not created")
 	ErrUserInvalidEmail = errors.New("invalid email")
+	ErrUserNotFound     = errors.New("user not found")
 )
 
 func (s *PostgresStorage) CreateUser(ctx context.Context, user models.User) (string, error) {
 	query := fmt.Sprintf(`
-			INSERT INTO %s (id, email, password, created_at, updated_at, deleted)
-			VALUES ($1, $2, $3, $4, $5, $6)
+			INSERT INTO %s (id, email, email_verified, password, created_at, updated_at, deleted)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`, usersTable)
 
-	ct, err := s.db.Exec(ctx, query, user.ID, user.Email, user.Password, user.CreatedAt, user.UpdatedAt, user.Deleted)
+	ct, err := s.db.Exec(ctx, query, user.ID, user.Email, user.EmailVerified, user.Password, user.CreatedAt, user.UpdatedAt, user.Deleted)
 	if err != nil {
 		return "", custom_error.CustomError{Field: "", Message: err.Error()}
 	}
@@ -36,7 +38,7 @@ func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (mod
 	var user models.User
 
 	query := fmt.Sprintf(`
-			SELECT id, email, password, created_at, updated_at, deleted
+			SELECT id, email, email_verified, password, role, token_version, display_name, phone, city, COALESCE(avatar_id, ''), banned, created_at, updated_at, deleted
 			FROM %s 
 			WHERE email = $1
 	`, usersTable)
@@ -44,7 +46,15 @@ func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (mod
 	err := s.db.QueryRow(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
+		&user.EmailVerified,
 		&user.Password,
+		&user.Role,
+		&user.TokenVersion,
+		&user.DisplayName,
+		&user.Phone,
+		&user.City,
+		&user.AvatarID,
+		&user.Banned,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Deleted)
@@ -57,3 +67,313 @@ func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (mod
 
 	return user, nil
 }
+
+func (s *PostgresStorage) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	var user models.User
+
+	query := fmt.Sprintf(`
+			SELECT id, email, email_verified, password, role, token_version, display_name, phone, city, COALESCE(avatar_id, ''), banned, created_at, updated_at, deleted
+			FROM %s
+			WHERE id = $1
+	`, usersTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&user.ID,
+		&user.Email,
+		&user.EmailVerified,
+		&user.Password,
+		&user.Role,
+		&user.TokenVersion,
+		&user.DisplayName,
+		&user.Phone,
+		&user.City,
+		&user.AvatarID,
+		&user.Banned,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Deleted)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+		}
+		return user, err
+	}
+
+	return user, nil
+}
+
+// IncrementTokenVersion makes every access token issued to the user before the call outdated.
+func (s *PostgresStorage) IncrementTokenVersion(ctx context.Context, id string) (int, error) {
+	var version int
+
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET token_version = token_version + 1
+			WHERE id = $1
+			RETURNING token_version
+	`, usersTable)
+
+	err := s.db.QueryRow(ctx, query, id).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+		}
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func (s *PostgresStorage) SetEmailVerified(ctx context.Context, id string, updatedAt time.Time) error {
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET email_verified = true, updated_at = $2
+			WHERE id = $1
+	`, usersTable)
+
+	ct, err := s.db.Exec(ctx, query, id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+	}
+
+	return nil
+}
+
+// ResetPassword consumes the reset token and sets the new password. All sessions, access tokens
+// and other reset tokens of the user are invalidated in the same transaction.
+// The returned user has only ID and TokenVersion filled.
+func (s *PostgresStorage) ResetPassword(ctx context.Context, tokenHash, password string, now time.Time) (models.User, error) {
+	var user models.User
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback(ctx)
+
+	useToken := fmt.Sprintf(`
+			UPDATE %s
+			SET used_at = $3
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+			RETURNING user_id
+	`, userTokensTable)
+
+	err = tx.QueryRow(ctx, useToken, tokenHash, models.UserTokenPurposeResetPassword, now).Scan(&user.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, custom_error.CustomError{Field: "token", Message: ErrUserTokenInvalid.Error()}
+		}
+		return user, err
+	}
+
+	updatePassword := fmt.Sprintf(`
+			UPDATE %s
+			SET password = $2, token_version = token_version + 1, updated_at = $3
+			WHERE id = $1
+			RETURNING token_version
+	`, usersTable)
+
+	err = tx.QueryRow(ctx, updatePassword, user.ID, password, now).Scan(&user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, custom_error.CustomError{Field: "token", Message: ErrUserNotFound.Error()}
+		}
+		return user, err
+	}
+
+	revokeSessions := fmt.Sprintf(`
+			UPDATE %s
+			SET revoked = TRUE
+			WHERE user_id = $1 AND revoked = false
+	`, sessionsTable)
+
+	_, err = tx.Exec(ctx, revokeSessions, user.ID)
+	if err != nil {
+		return user, err
+	}
+
+	useOtherTokens := fmt.Sprintf(`
+			UPDATE %s
+			SET used_at = $3
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userTokensTable)
+
+	_, err = tx.Exec(ctx, useOtherTokens, user.ID, models.UserTokenPurposeResetPassword, now)
+	if err != nil {
+		return user, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile applies the update and returns the id of the replaced or removed avatar,
+// the image row of which is marked as deleted.
+func (s *PostgresStorage) UpdateUserProfile(ctx context.Context, update models.UserProfileUpdate) (string, error) {
+	var oldAvatarID string
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if update.Avatar != nil || update.RemoveAvatar {
+		deleteAvatar := fmt.Sprintf(`
+			UPDATE %s
+			SET deleted = TRUE
+			WHERE id = (SELECT avatar_id FROM %s WHERE id = $1)
+			RETURNING id
+		`, imagesTable, usersTable)
+
+		err = tx.QueryRow(ctx, deleteAvatar, update.ID).Scan(&oldAvatarID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	var avatarID string
+	if update.Avatar != nil {
+		avatarID = update.Avatar.ID
+
+		insertAvatar := fmt.Sprintf(`
+			INSERT INTO %s (id, advert_id, format, content_hash, phash, status, created_at, deleted)
+			VALUES ($1, NULL, $2, $3, $4, $5, $6, $7)
+		`, imagesTable)
+
+		_, err = tx.Exec(ctx, insertAvatar, update.Avatar.ID, update.Avatar.Format, update.Avatar.ContentHash, update.Avatar.PHash,
+			update.Avatar.Status, update.Avatar.CreatedAt, update.Avatar.Deleted)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	updateUser := fmt.Sprintf(`
+			UPDATE %s
+			SET display_name = COALESCE($2, display_name),
+			    phone = COALESCE($3, phone),
+			    city = COALESCE($4, city),
+			    avatar_id = CASE WHEN $5 THEN NULLIF($6, '') ELSE avatar_id END,
+			    updated_at = $7
+			WHERE id = $1
+	`, usersTable)
+
+	ct, err := tx.Exec(ctx, updateUser,
+		update.ID,
+		update.DisplayName,
+		update.Phone,
+		update.City,
+		update.Avatar != nil || update.RemoveAvatar,
+		avatarID,
+		update.UpdatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return "", custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oldAvatarID, nil
+}
+
+// BanUser marks the user as banned and signs them out everywhere.
+// The returned user has only ID and TokenVersion filled.
+func (s *PostgresStorage) BanUser(ctx context.Context, id string, updatedAt time.Time) (models.User, error) {
+	var user models.User
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback(ctx)
+
+	banUser := fmt.Sprintf(`
+			UPDATE %s
+			SET banned = true, token_version = token_version + 1, updated_at = $2
+			WHERE id = $1
+			RETURNING id, token_version
+	`, usersTable)
+
+	err = tx.QueryRow(ctx, banUser, id, updatedAt).Scan(&user.ID, &user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+		}
+		return user, err
+	}
+
+	revokeSessions := fmt.Sprintf(`
+			UPDATE %s
+			SET revoked = TRUE
+			WHERE user_id = $1 AND revoked = false
+	`, sessionsTable)
+
+	_, err = tx.Exec(ctx, revokeSessions, id)
+	if err != nil {
+		return user, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (s *PostgresStorage) UnbanUser(ctx context.Context, id string, updatedAt time.Time) error {
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET banned = false, updated_at = $2
+			WHERE id = $1
+	`, usersTable)
+
+	ct, err := s.db.Exec(ctx, query, id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+	}
+
+	return nil
+}
+
+// SetUserRole changes the role and outdates issued access tokens, so the new role
+// is applied to the next token which the user gets with a refresh token.
+func (s *PostgresStorage) SetUserRole(ctx context.Context, id, role string, updatedAt time.Time) (int, error) {
+	var version int
+
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET role = $2, token_version = token_version + 1, updated_at = $3
+			WHERE id = $1
+			RETURNING token_version
+	`, usersTable)
+
+	err := s.db.QueryRow(ctx, query, id, role, updatedAt).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()}
+		}
+		return 0, err
+	}
+
+	return version, nil
+}
@@ -2,8 +2,10 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v2"
 	"github.com/romandnk/advertisement/internal/custom_error"
 	"github.com/romandnk/advertisement/internal/models"
@@ -19,8 +21,8 @@ func TestPostgresStorageCreateUser(t *testing.T) {
 	defer mock.Close()
 
 	query := fmt.Sprintf(`
-			INSERT INTO %s (id, email, password, created_at, updated_at, deleted)
-			VALUES ($1, $2, $3, $4, $5, $6)
+			INSERT INTO %s (id, email, email_verified, password, created_at, updated_at, deleted)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`, usersTable)
 
 	expectedUser := models.User{
@@ -35,6 +37,7 @@ func TestPostgresStorageCreateUser(t *testing.T) {
 	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		expectedUser.ID,
 		expectedUser.Email,
+		expectedUser.EmailVerified,
 		expectedUser.Password,
 		expectedUser.CreatedAt,
 		expectedUser.UpdatedAt,
@@ -57,23 +60,30 @@ func TestPostgresStorageGetUserByEmail(t *testing.T) {
 	defer mock.Close()
 
 	query := fmt.Sprintf(`
-			SELECT id, email, password, created_at, updated_at, deleted
+			SELECT id, email, email_verified, password, role, token_version, display_name, phone, city, COALESCE(avatar_id, ''), banned, created_at, updated_at, deleted
 			FROM %s 
 			WHERE email = $1
 	`, usersTable)
 
 	expectedUser := models.User{
-		ID:        uuid.New().String(),
-		Email:     "test@mail.ru",
-		Password:  "test_password",
-		CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
-		UpdatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
-		Deleted:   false,
+		ID:            uuid.New().String(),
+		Email:         "test@mail.ru",
+		EmailVerified: true,
+		Password:      "test_password",
+		Role:          models.RoleUser,
+		TokenVersion:  2,
+		DisplayName:   "Test",
+		Phone:         "+79991234567",
+		City:          "Moscow",
+		AvatarID:      uuid.New().String(),
+		CreatedAt:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Deleted:       false,
 	}
 
-	columns := []string{"id", "email", "password", "created_at", "updated_at", "deleted"}
+	columns := []string{"id", "email", "email_verified", "password", "role", "token_version", "display_name", "phone", "city", "avatar_id", "banned", "created_at", "updated_at", "deleted"}
 	rows := pgxmock.NewRows(columns).
-		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UpdatedAt, expectedUser.Deleted)
+		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.EmailVerified, expectedUser.Password, expectedUser.Role, expectedUser.TokenVersion, expectedUser.DisplayName, expectedUser.Phone, expectedUser.City, expectedUser.AvatarID, expectedUser.Banned, expectedUser.CreatedAt, expectedUser.UpdatedAt, expectedUser.Deleted)
 
 	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedUser.Email).WillReturnRows(rows)
 
@@ -93,7 +103,7 @@ func TestPostgresStorageGetUserByEmailError(t *testing.T) {
 	defer mock.Close()
 
 	query := fmt.Sprintf(`
-			SELECT id, email, password, created_at, updated_at, deleted
+			SELECT id, email, email_verified, password, role, token_version, display_name, phone, city, COALESCE(avatar_id, ''), banned, created_at, updated_at, deleted
 			FROM %s 
 			WHERE email = $1
 	`, usersTable)
@@ -113,3 +123,146 @@ func TestPostgresStorageGetUserByEmailError(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
+
+func TestPostgresStorageResetPassword(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	useToken := fmt.Sprintf(`
+			UPDATE %s
+			SET used_at = $3
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+			RETURNING user_id
+	`, userTokensTable)
+
+	updatePassword := fmt.Sprintf(`
+			UPDATE %s
+			SET password = $2, token_version = token_version + 1, updated_at = $3
+			WHERE id = $1
+			RETURNING token_version
+	`, usersTable)
+
+	revokeSessions := fmt.Sprintf(`
+			UPDATE %s
+			SET revoked = TRUE
+			WHERE user_id = $1 AND revoked = false
+	`, sessionsTable)
+
+	useOtherTokens := fmt.Sprintf(`
+			UPDATE %s
+			SET used_at = $3
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userTokensTable)
+
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	expectedUser := models.User{
+		ID:           uuid.New().String(),
+		TokenVersion: 3,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(useToken)).
+		WithArgs("hash", models.UserTokenPurposeResetPassword, now).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(expectedUser.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(updatePassword)).
+		WithArgs(expectedUser.ID, "password hash", now).
+		WillReturnRows(pgxmock.NewRows([]string{"token_version"}).AddRow(expectedUser.TokenVersion))
+	mock.ExpectExec(regexp.QuoteMeta(revokeSessions)).
+		WithArgs(expectedUser.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectExec(regexp.QuoteMeta(useOtherTokens)).
+		WithArgs(expectedUser.ID, models.UserTokenPurposeResetPassword, now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	user, err := storage.ResetPassword(context.Background(), "hash", "password hash", now)
+	require.NoError(t, err)
+	require.Equal(t, expectedUser, user)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageBanUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	banUser := fmt.Sprintf(`
+			UPDATE %s
+			SET banned = true, token_version = token_version + 1, updated_at = $2
+			WHERE id = $1
+			RETURNING id, token_version
+	`, usersTable)
+
+	revokeSessions := fmt.Sprintf(`
+			UPDATE %s
+			SET revoked = TRUE
+			WHERE user_id = $1 AND revoked = false
+	`, sessionsTable)
+
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	expectedUser := models.User{
+		ID:           uuid.New().String(),
+		TokenVersion: 1,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(banUser)).
+		WithArgs(expectedUser.ID, now).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "token_version"}).AddRow(expectedUser.ID, expectedUser.TokenVersion))
+	mock.ExpectExec(regexp.QuoteMeta(revokeSessions)).
+		WithArgs(expectedUser.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	storage := NewPostgresStorage(mock)
+
+	user, err := storage.BanUser(context.Background(), expectedUser.ID, now)
+	require.NoError(t, err)
+	require.Equal(t, expectedUser, user)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestPostgresStorageGetUserByIDError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		expectedError error
+	}{
+		{
+			name:          "not found",
+			err:           pgx.ErrNoRows,
+			expectedError: custom_error.CustomError{Field: "id", Message: ErrUserNotFound.Error()},
+		},
+		{
+			// a failure of the database is not a client error
+			name:          "db is down",
+			err:           errors.New("db is down"),
+			expectedError: errors.New("db is down"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			id := uuid.New().String()
+
+			mock.ExpectQuery("SELECT id, email").WithArgs(id).WillReturnError(tc.err)
+
+			storage := NewPostgresStorage(mock)
+
+			_, err = storage.GetUserByID(context.Background(), id)
+			require.Equal(t, tc.expectedError, err)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"time"
+)
+
+var (
+	ErrUserTokenNotCreated = errors.New("token was not created")
+	ErrUserTokenInvalid    = errors.New("token is invalid, expired or already used")
+)
+
+func (s *PostgresStorage) CreateUserToken(ctx context.Context, token models.UserToken) error {
+	query := fmt.Sprintf(`
+				INSERT INTO %s (token_hash, user_id, purpose, created_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5)
+	`, userTokensTable)
+
+	ct, err := s.db.Exec(ctx, query, token.Hash, token.UserID, token.Purpose, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return custom_error.CustomError{Field: "", Message: ErrUserTokenNotCreated.Error()}
+	}
+
+	return nil
+}
+
+// UseUserToken marks the token as used and returns it. The token is accepted only once
+// and only before it expires, so a concurrent request with the same token gets an error.
+func (s *PostgresStorage) UseUserToken(ctx context.Context, hash, purpose string, now time.Time) (models.UserToken, error) {
+	var token models.UserToken
+
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET used_at = $3
+				WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+				RETURNING token_hash, user_id, purpose, created_at, expires_at, used_at
+	`, userTokensTable)
+
+	err := s.db.QueryRow(ctx, query, hash, purpose, now).Scan(
+		&token.Hash,
+		&token.UserID,
+		&token.Purpose,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return token, custom_error.CustomError{Field: "token", Message: ErrUserTokenInvalid.Error()}
+		}
+		return token, err
+	}
+
+	return token, nil
+}
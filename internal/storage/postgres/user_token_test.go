@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/advertisement/internal/custom_error"
+	"github.com/romandnk/advertisement/internal/models"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPostgresStorageUseUserToken(t *testing.T) {
+	query := fmt.Sprintf(`
+				UPDATE %s
+				SET used_at = $3
+				WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+				RETURNING token_hash, user_id, purpose, created_at, expires_at, used_at
+	`, userTokensTable)
+
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	expectedToken := models.UserToken{
+		Hash:      "hash",
+		UserID:    uuid.New().String(),
+		Purpose:   models.UserTokenPurposeVerifyEmail,
+		CreatedAt: now.Add(-time.Hour),
+		ExpiresAt: now.Add(time.Hour),
+		UsedAt:    &now,
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		columns := []string{"token_hash", "user_id", "purpose", "created_at", "expires_at", "used_at"}
+		rows := pgxmock.NewRows(columns).AddRow(
+			expectedToken.Hash,
+			expectedToken.UserID,
+			expectedToken.Purpose,
+			expectedToken.CreatedAt,
+			expectedToken.ExpiresAt,
+			expectedToken.UsedAt,
+		)
+
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedToken.Hash, expectedToken.Purpose, now).WillReturnRows(rows)
+
+		storage := NewPostgresStorage(mock)
+
+		token, err := storage.UseUserToken(context.Background(), expectedToken.Hash, expectedToken.Purpose, now)
+		require.NoError(t, err)
+		require.Equal(t, expectedToken, token)
+
+		require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+	})
+
+	t.Run("used token", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(expectedToken.Hash, expectedToken.Purpose, now).WillReturnError(pgx.ErrNoRows)
+
+		storage := NewPostgresStorage(mock)
+
+		_, err = storage.UseUserToken(context.Background(), expectedToken.Hash, expectedToken.Purpose, now)
+		require.ErrorIs(t, err, custom_error.CustomError{Field: "token", Message: ErrUserTokenInvalid.Error()})
+
+		require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+	})
+}
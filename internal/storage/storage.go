@@ -3,25 +3,114 @@ package storage
 import (
 	"context"
 	"github.com/romandnk/advertisement/internal/models"
+	"time"
 )
 
 type ImageStorage interface {
 	GetImageByID(ctx context.Context, id string) (models.Image, error)
+	ListImages(ctx context.Context, afterID string, limit int) ([]models.Image, error)
+	GetImagesByIDs(ctx context.Context, ids []string) ([]models.Image, error)
+	HasBannedUserImage(ctx context.Context, phash int64, maxDistance int) (bool, error)
+	ClaimImageJobs(ctx context.Context, now, lockedUntil time.Time, limit int) ([]models.ImageJob, error)
+	CompleteImageJob(ctx context.Context, image models.Image) error
+	RetryImageJob(ctx context.Context, imageID string, runAt time.Time, lastError string) error
+	FailImageJob(ctx context.Context, imageID, reason string) error
+	CreateImage(ctx context.Context, image models.Image) error
+	DeleteUnattachedImages(ctx context.Context, before time.Time, limit int) ([]string, error)
 }
 
 type UserStorage interface {
 	CreateUser(ctx context.Context, user models.User) (string, error)
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, id string) (models.User, error)
+	IncrementTokenVersion(ctx context.Context, id string) (int, error)
+	SetEmailVerified(ctx context.Context, id string, updatedAt time.Time) error
+	ResetPassword(ctx context.Context, tokenHash, password string, now time.Time) (models.User, error)
+	UpdateUserProfile(ctx context.Context, update models.UserProfileUpdate) (string, error)
+	BanUser(ctx context.Context, id string, updatedAt time.Time) (models.User, error)
+	UnbanUser(ctx context.Context, id string, updatedAt time.Time) error
+	SetUserRole(ctx context.Context, id, role string, updatedAt time.Time) (int, error)
+}
+
+type UserTokenStorage interface {
+	CreateUserToken(ctx context.Context, token models.UserToken) error
+	UseUserToken(ctx context.Context, hash, purpose string, now time.Time) (models.UserToken, error)
+}
+
+type SessionStorage interface {
+	CreateSession(ctx context.Context, session models.Session) error
+	GetSessionByID(ctx context.Context, id string) (models.Session, error)
+	RotateSession(ctx context.Context, session models.Session, oldHash string) error
+	RevokeSession(ctx context.Context, id, userID string) error
+	ListSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error)
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+type TokenStorage interface {
+	RevokeToken(ctx context.Context, id string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context, now time.Time, limit int) (int64, error)
 }
 
 type AdvertStorage interface {
 	CreateAdvert(ctx context.Context, advert models.Advert) (string, error)
 	GetAdvertByID(ctx context.Context, id string) (models.Advert, error)
+	UpdateAdvert(ctx context.Context, update models.AdvertUpdate) error
 	DeleteAdvert(ctx context.Context, advertID, userID string) ([]string, error)
+	DeleteAnyAdvert(ctx context.Context, advertID string) ([]string, error)
+	UpdateAdvertStatus(ctx context.Context, change models.AdvertStatusChange) error
+	ArchiveExpiredAdverts(ctx context.Context, now time.Time, limit int) ([]models.ExpiredAdvert, error)
+	RenewAdvert(ctx context.Context, id, userID string, expiresAt, updatedAt time.Time) error
+	ListAdverts(ctx context.Context, params models.AdvertListParams) ([]models.Advert, error)
+	SearchAdverts(ctx context.Context, params models.AdvertSearchParams) ([]models.AdvertSearchResult, error)
+	FindDuplicateAdverts(ctx context.Context, advertID string, maxDistance, limit int) ([]models.DuplicateAdvert, error)
+}
+
+type UploadStorage interface {
+	CreateUpload(ctx context.Context, upload models.Upload) error
+	GetUpload(ctx context.Context, id, userID string) (models.Upload, error)
+	AdvanceUpload(ctx context.Context, id, userID string, from, to int64, updatedAt time.Time) error
+	DeleteUpload(ctx context.Context, id, userID string) error
+	DeleteStaleUploads(ctx context.Context, before time.Time, limit int) ([]models.Upload, error)
+}
+
+type FavouriteStorage interface {
+	AddFavourite(ctx context.Context, favourite models.Favourite) error
+	RemoveFavourite(ctx context.Context, userID, advertID string) error
+	IsFavourite(ctx context.Context, userID, advertID string) (bool, error)
+	ListFavourites(ctx context.Context, params models.FavouriteListParams) ([]models.FavouriteAdvert, error)
+}
+
+type MessageStorage interface {
+	SendMessage(ctx context.Context, thread models.Thread, message models.Message) (string, error)
+	GetThreadByID(ctx context.Context, id, userID string) (models.Thread, error)
+	ListThreads(ctx context.Context, params models.ThreadListParams) ([]models.Thread, error)
+	ListMessages(ctx context.Context, params models.MessageListParams) ([]models.Message, error)
+	MarkThreadRead(ctx context.Context, id, userID string, readUntil models.AdvertCursor) error
+	CountUnreadMessages(ctx context.Context, userID string) (int, error)
+	BlockUser(ctx context.Context, block models.UserBlock) error
+	UnblockUser(ctx context.Context, userID, blockedID string) error
+	IsBlocked(ctx context.Context, userID, otherID string) (bool, error)
+}
+
+type CategoryStorage interface {
+	CreateCategory(ctx context.Context, category models.Category) (string, error)
+	GetCategoryByID(ctx context.Context, id string) (models.Category, error)
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	CategoryHasAdverts(ctx context.Context, id string) (bool, error)
+	UpdateCategory(ctx context.Context, category models.Category) error
+	DeleteCategory(ctx context.Context, id string) error
 }
 
 type Storage interface {
 	AdvertStorage
+	CategoryStorage
 	UserStorage
+	SessionStorage
+	TokenStorage
+	UserTokenStorage
 	ImageStorage
+	UploadStorage
+	FavouriteStorage
+	MessageStorage
 }
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/romandnk/advertisement/internal/logger"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Task does one round of background work and returns the number of items it handled.
+type Task func(ctx context.Context) (int, error)
+
+// Periodic runs a task right away and then every interval, e.g. archiving expired adverts
+// or purging expired tokens.
+type Periodic struct {
+	name     string
+	task     Task
+	logger   logger.Logger
+	interval time.Duration
+}
+
+func NewPeriodic(name string, logger logger.Logger, interval time.Duration, task Task) *Periodic {
+	return &Periodic{
+		name:     name,
+		task:     task,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Run runs the task right away and then every interval until ctx is done.
+func (w *Periodic) Run(ctx context.Context) {
+	w.logger.Info(w.name+" worker started", zap.String("interval", w.interval.String()))
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info(w.name + " worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Periodic) run(ctx context.Context) {
+	count, err := w.task(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		w.logger.Error("error running "+w.name+" worker", zap.String("error", err.Error()))
+	}
+
+	if count > 0 {
+		w.logger.Info(w.name+" worker handled items", zap.Int("count", count))
+	}
+}
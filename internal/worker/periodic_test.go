@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	mock_logger "github.com/romandnk/advertisement/internal/logger/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeriodicRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Info("token purge worker started", gomock.Any())
+	logger.EXPECT().Info("token purge worker handled items", zap.Int("count", 2)).MinTimes(2)
+	logger.EXPECT().Info("token purge worker stopped")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	task := func(ctx context.Context) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			cancel()
+		}
+		return 2, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		NewPeriodic("token purge", logger, time.Millisecond, task).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after context cancellation")
+	}
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestPeriodicError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error("error running image gc worker", zap.String("error", "db is down"))
+
+	task := func(ctx context.Context) (int, error) {
+		return 0, errors.New("db is down")
+	}
+	NewPeriodic("image gc", logger, time.Minute, task).run(context.Background())
+
+	// a task stopped by the shutdown is not an error
+	task = func(ctx context.Context) (int, error) {
+		return 0, context.Canceled
+	}
+	NewPeriodic("image gc", logger, time.Minute, task).run(context.Background())
+}
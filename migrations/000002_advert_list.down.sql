@@ -0,0 +1,4 @@
+DROP INDEX images_advert_id_idx;
+DROP INDEX adverts_user_id_idx;
+DROP INDEX adverts_price_id_idx;
+DROP INDEX adverts_created_at_id_idx;
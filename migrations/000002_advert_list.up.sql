@@ -0,0 +1,4 @@
+CREATE INDEX adverts_created_at_id_idx ON adverts (created_at, id) WHERE deleted = false;
+CREATE INDEX adverts_price_id_idx ON adverts (price, id) WHERE deleted = false;
+CREATE INDEX adverts_user_id_idx ON adverts (user_id);
+CREATE INDEX images_advert_id_idx ON images (advert_id);
@@ -0,0 +1,2 @@
+DROP INDEX adverts_search_vector_idx;
+ALTER TABLE adverts DROP COLUMN search_vector;
@@ -0,0 +1,8 @@
+-- the russian configuration stems cyrillic words with the russian snowball stemmer
+-- and latin words with the english one, so one vector serves both languages
+ALTER TABLE adverts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX adverts_search_vector_idx ON adverts USING GIN (search_vector);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN role;
+DROP INDEX adverts_category_id_idx;
+ALTER TABLE adverts DROP COLUMN category_id;
+DROP INDEX categories_parent_id_idx;
+DROP TABLE categories;
@@ -0,0 +1,16 @@
+CREATE TABLE categories (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    parent_id VARCHAR(36) REFERENCES categories(id),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+
+ALTER TABLE adverts ADD COLUMN category_id VARCHAR(36) REFERENCES categories(id);
+
+CREATE INDEX adverts_category_id_idx ON adverts (category_id);
+
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user'
+    CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,12 @@
+CREATE TABLE sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    refresh_token_hash VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked BOOLEAN NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
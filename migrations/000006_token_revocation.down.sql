@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN token_version;
+DROP INDEX revoked_tokens_expires_at_idx;
+DROP TABLE revoked_tokens;
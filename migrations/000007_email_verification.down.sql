@@ -0,0 +1,3 @@
+DROP INDEX user_tokens_user_id_idx;
+DROP TABLE user_tokens;
+ALTER TABLE users DROP COLUMN email_verified;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN avatar_id,
+    DROP COLUMN city,
+    DROP COLUMN phone,
+    DROP COLUMN display_name;
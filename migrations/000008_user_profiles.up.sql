@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN phone VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN city VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_id VARCHAR(36) REFERENCES images(id);
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN banned;
+UPDATE users SET role = 'user' WHERE role = 'moderator';
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,3 @@
+DROP INDEX adverts_status_updated_at_idx;
+ALTER TABLE adverts DROP COLUMN status_reason;
+ALTER TABLE adverts DROP COLUMN status;
@@ -0,0 +1,9 @@
+ALTER TABLE adverts
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published'
+        CONSTRAINT adverts_status_check CHECK (status IN ('draft', 'pending_review', 'published', 'rejected', 'archived', 'sold')),
+    ADD COLUMN status_reason TEXT NOT NULL DEFAULT '';
+
+-- adverts created before moderation are already public, new ones always get an explicit status
+ALTER TABLE adverts ALTER COLUMN status DROP DEFAULT;
+
+CREATE INDEX adverts_status_updated_at_idx ON adverts (status, updated_at);
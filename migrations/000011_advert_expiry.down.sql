@@ -0,0 +1,2 @@
+DROP INDEX adverts_expires_at_idx;
+ALTER TABLE adverts DROP COLUMN expires_at;
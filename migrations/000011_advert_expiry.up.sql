@@ -0,0 +1,8 @@
+ALTER TABLE adverts ADD COLUMN expires_at TIMESTAMP;
+
+-- existing adverts get a full period from the deploy, otherwise old listings would be archived at once
+UPDATE adverts SET expires_at = GREATEST(updated_at, now()::timestamp) + INTERVAL '30 days';
+
+ALTER TABLE adverts ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX adverts_expires_at_idx ON adverts (expires_at) WHERE status = 'published' AND deleted = false;
@@ -0,0 +1,3 @@
+-- jpeg was the only accepted format so far, the default fills in the existing images only
+ALTER TABLE images ADD COLUMN format VARCHAR(8) NOT NULL DEFAULT 'jpeg';
+ALTER TABLE images ALTER COLUMN format DROP DEFAULT;
@@ -0,0 +1,2 @@
+-- images uploaded before this migration have no hash and are served without an ETag
+ALTER TABLE images ADD COLUMN content_hash VARCHAR(64);
@@ -0,0 +1,2 @@
+DROP INDEX images_phash_idx;
+ALTER TABLE images DROP COLUMN phash;
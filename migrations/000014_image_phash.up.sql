@@ -0,0 +1,5 @@
+-- 64-bit dHash, images uploaded before this migration have none.
+-- The index serves exact matches, near ones are compared with bit_count over the xor of two hashes
+ALTER TABLE images ADD COLUMN phash BIGINT;
+
+CREATE INDEX images_phash_idx ON images (phash) WHERE phash IS NOT NULL;
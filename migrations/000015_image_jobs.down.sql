@@ -0,0 +1,2 @@
+DROP TABLE image_jobs;
+ALTER TABLE images DROP COLUMN status, DROP COLUMN status_reason;
@@ -0,0 +1,20 @@
+ALTER TABLE images
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'ready'
+        CONSTRAINT images_status_check CHECK (status IN ('processing', 'ready', 'failed')),
+    ADD COLUMN status_reason TEXT NOT NULL DEFAULT '';
+
+-- existing images were resized within the upload request, they are ready and need no job
+ALTER TABLE images ALTER COLUMN status DROP DEFAULT;
+
+-- a job is claimed by setting locked_until, a worker which dies while processing
+-- leaves the lock to expire and the job is picked up again
+CREATE TABLE image_jobs (
+    image_id VARCHAR(36) PRIMARY KEY REFERENCES images(id),
+    attempts INT NOT NULL DEFAULT 0,
+    run_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX image_jobs_run_at_idx ON image_jobs (run_at);
@@ -0,0 +1,11 @@
+-- files uploaded in chunks, the chunks are kept in the blob store until the upload is attached to an advert
+CREATE TABLE uploads (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    length BIGINT NOT NULL,
+    received BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX uploads_user_id_idx ON uploads (user_id);
@@ -0,0 +1,2 @@
+DROP INDEX images_unattached_idx;
+ALTER TABLE images DROP COLUMN user_id;
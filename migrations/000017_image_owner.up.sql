@@ -0,0 +1,5 @@
+-- user_id is set only for images uploaded before their advert, such an image stays
+-- without advert_id until it is attached and is purged if it is never attached
+ALTER TABLE images ADD COLUMN user_id VARCHAR(36) REFERENCES users(id);
+
+CREATE INDEX images_unattached_idx ON images (created_at) WHERE advert_id IS NULL AND user_id IS NOT NULL AND deleted = false;
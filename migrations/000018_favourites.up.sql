@@ -0,0 +1,9 @@
+CREATE TABLE favourites (
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    advert_id VARCHAR(36) NOT NULL REFERENCES adverts(id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, advert_id)
+);
+
+-- the primary key serves the list of a user, this index counts the favourites of an advert
+CREATE INDEX favourites_advert_id_idx ON favourites (advert_id);
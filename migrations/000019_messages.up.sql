@@ -0,0 +1,33 @@
+-- a thread is the conversation of the seller of an advert with one buyer,
+-- the unread counters are kept for both sides and reset when the side reads the thread
+CREATE TABLE threads (
+    id VARCHAR(36) PRIMARY KEY,
+    advert_id VARCHAR(36) NOT NULL REFERENCES adverts(id),
+    seller_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    buyer_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    seller_unread INT NOT NULL DEFAULT 0,
+    buyer_unread INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (advert_id, buyer_id)
+);
+
+CREATE INDEX threads_seller_id_updated_at_idx ON threads (seller_id, updated_at);
+CREATE INDEX threads_buyer_id_updated_at_idx ON threads (buyer_id, updated_at);
+
+CREATE TABLE messages (
+    id VARCHAR(36) PRIMARY KEY,
+    thread_id VARCHAR(36) NOT NULL REFERENCES threads(id),
+    sender_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX messages_thread_id_created_at_idx ON messages (thread_id, created_at, id);
+
+CREATE TABLE user_blocks (
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    blocked_id VARCHAR(36) NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, blocked_id)
+);
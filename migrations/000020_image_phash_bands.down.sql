@@ -0,0 +1,4 @@
+CREATE INDEX images_phash_idx ON images (phash) WHERE phash IS NOT NULL;
+
+DROP INDEX images_phash_bands_idx;
+ALTER TABLE images DROP COLUMN phash_bands;
@@ -0,0 +1,13 @@
+-- The perceptual hash split into four 16-bit bands, each tagged with its number (band * 65536 + value).
+-- Hashes differing in at most d bits share a band differing in at most d / 4 bits, so near duplicates
+-- are looked up in the GIN index by the band values within that distance and then compared with bit_count.
+ALTER TABLE images ADD COLUMN phash_bands INTEGER[] GENERATED ALWAYS AS (ARRAY[
+    ((phash >> 48) & 65535)::integer,
+    (65536 + ((phash >> 32) & 65535))::integer,
+    (131072 + ((phash >> 16) & 65535))::integer,
+    (196608 + (phash & 65535))::integer
+]) STORED;
+
+CREATE INDEX images_phash_bands_idx ON images USING GIN (phash_bands) WHERE phash IS NOT NULL;
+
+DROP INDEX images_phash_idx;